@@ -128,6 +128,8 @@ func (c *Compressor) SetTrace(trace bool) {
 
 func (c *Compressor) Count() int { return int(c.wordsCount) }
 
+var _ etl.WordAdder = &Compressor{} // see etl.Collector.LoadToCompressor
+
 func (c *Compressor) AddWord(word []byte) error {
 	c.wordsCount++
 	l := 2*len(word) + 2
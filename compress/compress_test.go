@@ -23,8 +23,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 
+	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/stretchr/testify/require"
 )
@@ -166,3 +168,36 @@ func TestCompressDict1(t *testing.T) {
 		t.Errorf("result file hash changed, %d", cs)
 	}
 }
+
+func TestLoadToCompressor(t *testing.T) {
+	require := require.New(t)
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "compressed")
+	c, err := NewCompressor(context.Background(), t.Name(), file, tmpDir, 100, 1, log.LvlDebug)
+	require.NoError(err)
+	defer c.Close()
+
+	collector := etl.NewCollector(t.Name(), tmpDir, etl.NewSortableBuffer(1024))
+	for i := 99; i >= 0; i-- {
+		require.NoError(collector.Collect([]byte(fmt.Sprintf("key %d", i%50)), []byte(fmt.Sprintf("value %d", i))))
+	}
+	require.NoError(collector.LoadToCompressor(c, etl.DedupFirst, etl.TransformArgs{}))
+	require.NoError(c.Compress())
+
+	d, err := NewDecompressor(file)
+	require.NoError(err)
+	defer d.Close()
+	require.Equal(100, d.Count())
+	var keys []string
+	g := d.MakeGetter()
+	for g.HasNext() {
+		k, _ := g.Next(nil)
+		v, _ := g.Next(nil)
+		keys = append(keys, string(k))
+		var i int
+		_, err = fmt.Sscanf(string(k), "key %d", &i)
+		require.NoError(err)
+		require.Equal(fmt.Sprintf("value %d", i+50), string(v)) // first collected
+	}
+	require.True(sort.StringsAreSorted(keys))
+}
@@ -0,0 +1,167 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package etl
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/c2h5oh/datasize"
+)
+
+// MemoryBudget - RAM limit shared by buffers of many collectors (see NewCollectorWithBudget).
+//
+// Collector joins budget on first Collect and leaves it on Close/Load: only active collectors take part.
+// Every member is guaranteed share `limit / members`. Collector may grow over its share while budget has
+// free memory (borrows memory of idle collectors), but when sum of buffers exceeds limit - budget reclaims
+// memory: collectors above their share are forced to flush. Busy ones flush on their next Collect, idle ones
+// are flushed immediately by collector which needs memory (see Collector.tryForceFlush).
+//
+// Limit is best-effort: buffer grows by budgetStep between reports, and a member which is loading keeps its
+// buffer until Load is done.
+type MemoryBudget struct {
+	limit int
+
+	lock    sync.Mutex
+	used    int
+	members map[*Collector]*budgetMember
+
+	usedBytes     *metrics.Counter
+	limitBytes    *metrics.Counter
+	collectors    *metrics.Counter
+	flushes       *metrics.Counter
+	forcedFlushes *metrics.Counter
+}
+
+type budgetMember struct {
+	size      int  // size of buffer on last Collect
+	mustFlush bool // set under memory pressure, reset by flush
+}
+
+// NewMemoryBudget - `name` is used as label of metrics, budgets with same name share metrics
+func NewMemoryBudget(name string, limit datasize.ByteSize) *MemoryBudget {
+	b := &MemoryBudget{
+		limit:         int(limit.Bytes()),
+		members:       map[*Collector]*budgetMember{},
+		usedBytes:     metrics.GetOrCreateCounter(fmt.Sprintf(`etl_budget_bytes{kind="used",name="%s"}`, name)),
+		limitBytes:    metrics.GetOrCreateCounter(fmt.Sprintf(`etl_budget_bytes{kind="limit",name="%s"}`, name)),
+		collectors:    metrics.GetOrCreateCounter(fmt.Sprintf(`etl_budget_collectors{name="%s"}`, name)),
+		flushes:       metrics.GetOrCreateCounter(fmt.Sprintf(`etl_budget_flushes_total{forced="false",name="%s"}`, name)),
+		forcedFlushes: metrics.GetOrCreateCounter(fmt.Sprintf(`etl_budget_flushes_total{forced="true",name="%s"}`, name)),
+	}
+	b.limitBytes.Set(uint64(b.limit))
+	return b
+}
+
+func (b *MemoryBudget) Limit() datasize.ByteSize { return datasize.ByteSize(b.limit) }
+
+// Used - sum of buffers of all members
+func (b *MemoryBudget) Used() datasize.ByteSize {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return datasize.ByteSize(b.used)
+}
+
+// Share - guaranteed buffer size of every member
+func (b *MemoryBudget) Share() datasize.ByteSize {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return datasize.ByteSize(b.share())
+}
+
+func (b *MemoryBudget) share() int {
+	if len(b.members) == 0 {
+		return b.limit
+	}
+	return b.limit / len(b.members)
+}
+
+// freeable - buffer which keeps capacity on Reset (see sortableBuffer.free)
+type freeable interface {
+	free()
+}
+
+// reserve - updates size of collector's buffer (joins budget if needed), returns true if buffer must be flushed
+// and other members which must give back memory
+func (b *MemoryBudget) reserve(c *Collector, size int) (mustFlush bool, idle []*Collector) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	m, ok := b.members[c]
+	if !ok {
+		m = &budgetMember{}
+		b.members[c] = m
+		b.collectors.Set(uint64(len(b.members)))
+	}
+	b.used += size - m.size
+	m.size = size
+	b.usedBytes.Set(uint64(b.used))
+
+	if m.mustFlush {
+		b.forcedFlushes.Inc()
+		return true, nil
+	}
+	if size >= b.limit {
+		b.flushes.Inc()
+		return true, nil
+	}
+	if b.used <= b.limit {
+		return false, nil
+	}
+	// pressure: reclaim memory borrowed over share
+	share := b.share()
+	for oc, o := range b.members {
+		if o.size > share {
+			o.mustFlush = true
+		}
+		if o.mustFlush && oc != c {
+			idle = append(idle, oc)
+		}
+	}
+	if m.mustFlush {
+		b.forcedFlushes.Inc()
+		return true, idle
+	}
+	return false, idle
+}
+
+// release - buffer of collector was flushed or disposed
+func (b *MemoryBudget) release(c *Collector, size int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	m, ok := b.members[c]
+	if !ok {
+		return
+	}
+	b.used += size - m.size
+	m.size, m.mustFlush = size, false
+	b.usedBytes.Set(uint64(b.used))
+}
+
+// leave - collector doesn't use budget until next Collect
+func (b *MemoryBudget) leave(c *Collector) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	m, ok := b.members[c]
+	if !ok {
+		return
+	}
+	b.used -= m.size
+	delete(b.members, c)
+	b.usedBytes.Set(uint64(b.used))
+	b.collectors.Set(uint64(len(b.members)))
+}
@@ -17,7 +17,6 @@
 package etl
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -51,6 +50,7 @@ type Buffer interface {
 	Write(io.Writer) error
 	Sort()
 	CheckFlushSize() bool
+	Size() int
 }
 
 type sortableBufferEntry struct {
@@ -62,6 +62,12 @@ var (
 	_ Buffer = &sortableBuffer{}
 	_ Buffer = &appendSortableBuffer{}
 	_ Buffer = &oldestEntrySortableBuffer{}
+
+	_ sortableBy = &sortableBuffer{}
+	_ sortableBy = &appendSortableBuffer{}
+	_ sortableBy = &oldestEntrySortableBuffer{}
+
+	_ freeable = &sortableBuffer{}
 )
 
 func NewSortableBuffer(bufferOptimalSize datasize.ByteSize) *sortableBuffer {
@@ -75,6 +81,7 @@ type sortableBuffer struct {
 	lens        []int
 	data        []byte
 	optimalSize int
+	cmp         Comparator
 }
 
 // Put adds key and value to the buffer. These slices will not be accessed later,
@@ -107,9 +114,11 @@ func (b *sortableBuffer) Less(i, j int) bool {
 	i2, j2 := i*2, j*2
 	ki := b.data[b.offsets[i2] : b.offsets[i2]+b.lens[i2]]
 	kj := b.data[b.offsets[j2] : b.offsets[j2]+b.lens[j2]]
-	return bytes.Compare(ki, kj) < 0
+	return compareKeys(b.cmp, ki, kj) < 0
 }
 
+func (b *sortableBuffer) sortBy(cmp Comparator) { b.cmp = cmp }
+
 func (b *sortableBuffer) Swap(i, j int) {
 	i2, j2 := i*2, j*2
 	b.offsets[i2], b.offsets[j2] = b.offsets[j2], b.offsets[i2]
@@ -152,6 +161,11 @@ func (b *sortableBuffer) Reset() {
 	b.lens = b.lens[:0]
 	b.data = b.data[:0]
 }
+
+// free - Reset keeps capacity of slices for reuse, but budget accounts only Size: memory must be given back
+func (b *sortableBuffer) free() {
+	b.offsets, b.lens, b.data = nil, nil, nil
+}
 func (b *sortableBuffer) Sort() {
 	if sort.IsSorted(b) {
 		return
@@ -194,6 +208,7 @@ type appendSortableBuffer struct {
 	sortedBuf   []sortableBufferEntry
 	size        int
 	optimalSize int
+	cmp         Comparator
 }
 
 func (b *appendSortableBuffer) Put(k, v []byte) {
@@ -221,9 +236,11 @@ func (b *appendSortableBuffer) Sort() {
 }
 
 func (b *appendSortableBuffer) Less(i, j int) bool {
-	return bytes.Compare(b.sortedBuf[i].key, b.sortedBuf[j].key) < 0
+	return compareKeys(b.cmp, b.sortedBuf[i].key, b.sortedBuf[j].key) < 0
 }
 
+func (b *appendSortableBuffer) sortBy(cmp Comparator) { b.cmp = cmp }
+
 func (b *appendSortableBuffer) Swap(i, j int) {
 	b.sortedBuf[i], b.sortedBuf[j] = b.sortedBuf[j], b.sortedBuf[i]
 }
@@ -286,6 +303,7 @@ type oldestEntrySortableBuffer struct {
 	sortedBuf   []sortableBufferEntry
 	size        int
 	optimalSize int
+	cmp         Comparator
 }
 
 func (b *oldestEntrySortableBuffer) Put(k, v []byte) {
@@ -315,9 +333,11 @@ func (b *oldestEntrySortableBuffer) Sort() {
 }
 
 func (b *oldestEntrySortableBuffer) Less(i, j int) bool {
-	return bytes.Compare(b.sortedBuf[i].key, b.sortedBuf[j].key) < 0
+	return compareKeys(b.cmp, b.sortedBuf[i].key, b.sortedBuf[j].key) < 0
 }
 
+func (b *oldestEntrySortableBuffer) sortBy(cmp Comparator) { b.cmp = cmp }
+
 func (b *oldestEntrySortableBuffer) Swap(i, j int) {
 	b.sortedBuf[i], b.sortedBuf[j] = b.sortedBuf[j], b.sortedBuf[i]
 }
@@ -19,12 +19,14 @@ package etl
 import (
 	"bytes"
 	"container/heap"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -32,6 +34,7 @@ import (
 
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"golang.org/x/sync/errgroup"
 )
 
 type LoadNextFunc func(originalK, k, v []byte) error
@@ -50,11 +53,21 @@ type Collector struct {
 	bufType       int
 	allFlushed    bool
 	autoClean     bool
+	spillCodec    SpillCodec
+	cmp           Comparator
+
+	manifest *manifest // not nil for resumable collector
+	resumed  bool
+
+	budget     *MemoryBudget // nil if buffer has own fixed size
+	budgetSize int           // size of buffer reported to budget
+	flushErr   error         // error of flush forced by other collector, see tryForceFlush
 
 	mutex sync.Mutex
 }
 
-// NewCollectorFromFiles creates collector from existing files (left over from previous unsuccessful loading)
+// NewCollectorFromFiles creates collector from existing files (left over from previous unsuccessful loading).
+// Files written by previous versions (without block format) are also accepted.
 func NewCollectorFromFiles(logPrefix, tmpdir string) (*Collector, error) {
 	if _, err := os.Stat(tmpdir); os.IsNotExist(err) {
 		return nil, nil
@@ -66,8 +79,11 @@ func NewCollectorFromFiles(logPrefix, tmpdir string) (*Collector, error) {
 	if len(dirEntries) == 0 {
 		return nil, nil
 	}
-	dataProviders := make([]dataProvider, len(dirEntries))
-	for i, dirEntry := range dirEntries {
+	dataProviders := make([]dataProvider, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasPrefix(dirEntry.Name(), spillFilePrefix) {
+			continue // manifest of resumable collector, etc.
+		}
 		fileInfo, err := dirEntry.Info()
 		if err != nil {
 			return nil, fmt.Errorf("collector from files - reading file info %s: %w", dirEntry.Name(), err)
@@ -77,7 +93,10 @@ func NewCollectorFromFiles(logPrefix, tmpdir string) (*Collector, error) {
 		if err != nil {
 			return nil, fmt.Errorf("collector from files - opening file %s: %w", fileInfo.Name(), err)
 		}
-		dataProviders[i] = &dataProvider
+		dataProviders = append(dataProviders, &dataProvider)
+	}
+	if len(dataProviders) == 0 {
+		return nil, nil
 	}
 	return &Collector{dataProviders: dataProviders, allFlushed: true, autoClean: false, logPrefix: logPrefix}, nil
 }
@@ -93,25 +112,145 @@ func NewCollector(logPrefix, tmpdir string, sortableBuffer Buffer) *Collector {
 	return &Collector{autoClean: true, bufType: getTypeByBuffer(sortableBuffer), buf: sortableBuffer, logPrefix: logPrefix, tmpdir: tmpdir, logLvl: log.LvlInfo}
 }
 
+// NewCollectorWithBudget - collector with buffer of type `bufType` (SortableSliceBuffer, ...), which size is
+// limited by `budget` shared with other collectors instead of fixed size.
+func NewCollectorWithBudget(logPrefix, tmpdir string, bufType int, budget *MemoryBudget) *Collector {
+	c := NewCollector(logPrefix, tmpdir, getBufferByType(bufType, budget.Limit()))
+	c.budget = budget
+	return c
+}
+
+// NewResumableCollector - critical collector which survives restart. It always flushes to files in `dir`
+// (`dir` must not be shared with other collectors) and keeps there manifest: buffer type, files with checksums,
+// is collecting finished.
+//
+// If `dir` has complete and valid data of previous run - collector is restored from it and Resumed() returns true:
+// caller must skip Collect and call Load. Load progress is not stored in manifest: manifest can't know if
+// caller's tx was committed. Caller stores key received by TransformArgs.OnLoadCommit in same tx with loaded
+// data (for example as stage progress) and passes NextKey of it as TransformArgs.LoadStartKey of resumed Load.
+// Otherwise files of previous run are removed and collector starts empty.
+// Files and manifest are removed after successful Load.
+func NewResumableCollector(logPrefix, dir string, sortableBuffer Buffer) (*Collector, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("%s: resumable collector needs own dir", logPrefix)
+	}
+	c := NewCriticalCollector(logPrefix, dir, sortableBuffer)
+	c.manifest = &manifest{BufType: c.bufType}
+	m, err := readManifest(dir)
+	if err != nil {
+		log.Warn(fmt.Sprintf("[%s] ETL can't resume collector", logPrefix), "err", err)
+	}
+	if m != nil && m.Complete {
+		if m.BufType != c.bufType {
+			return nil, fmt.Errorf("%s: collector in %s was created with buffer type %d, but resumed with %d", logPrefix, dir, m.BufType, c.bufType)
+		}
+		if err = m.verify(dir); err == nil {
+			for _, mf := range m.Files {
+				f, err := os.Open(filepath.Join(dir, mf.Name))
+				if err != nil {
+					c.Close()
+					return nil, err
+				}
+				c.dataProviders = append(c.dataProviders, &fileDataProvider{file: f, size: mf.Size, checksum: mf.Checksum})
+			}
+			c.manifest, c.allFlushed, c.resumed = m, true, true
+			log.Info(fmt.Sprintf("[%s] ETL resumed collector", logPrefix), "files", len(m.Files))
+			return c, nil
+		}
+		log.Warn(fmt.Sprintf("[%s] ETL can't resume collector", logPrefix), "err", err)
+	}
+	if err = removeSpillFiles(dir); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Resumed - collector has all data of previous run, see NewResumableCollector
+func (c *Collector) Resumed() bool { return c.resumed }
+
 func (c *Collector) extractNextFunc(originalK, k []byte, v []byte) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	if c.flushErr != nil {
+		return c.flushErr
+	}
 	c.buf.Put(k, v)
-	if !c.buf.CheckFlushSize() {
+	if c.budget != nil {
+		if !c.reserve() {
+			return nil
+		}
+	} else if !c.buf.CheckFlushSize() {
 		return nil
 	}
 	return c.flushBufferInternal(false)
 }
 
+// budgetStep - collector reports growth of buffer to budget by steps, not on every Put
+const budgetStep = 64 * 1024
+
+// reserve - returns true if buffer must be flushed. Must be called under mutex.
+func (c *Collector) reserve() bool {
+	size := c.buf.Size()
+	if size-c.budgetSize < budgetStep && c.budgetSize != 0 {
+		return false
+	}
+	c.budgetSize = size
+	mustFlush, idle := c.budget.reserve(c, size)
+	for _, o := range idle {
+		o.tryForceFlush()
+	}
+	return mustFlush
+}
+
+// tryForceFlush - flushes buffer of other collector, which budget asked to give back memory. Collector which is
+// busy (in Collect or Flush) is skipped: it will flush itself on next Collect. Collector which is loading is
+// skipped too: its buffer may be used by Load.
+func (c *Collector) tryForceFlush() {
+	if !c.mutex.TryLock() {
+		return
+	}
+	defer c.mutex.Unlock()
+	if c.allFlushed || c.flushErr != nil {
+		return
+	}
+	c.budget.forcedFlushes.Inc()
+	c.flushErr = c.flushBufferInternal(false) // returned by next Collect or Load
+}
+
 func (c *Collector) Collect(k, v []byte) error {
 	return c.extractNextFunc(k, k, v)
 }
 
 func (c *Collector) LogLvl(v log.Lvl) { c.logLvl = v }
 
+// SpillCodec - compression of files created by next flushes, see SpillCodec. Default is SpillPlain: checksums
+// are always on.
+func (c *Collector) SpillCodec(v SpillCodec) { c.spillCodec = v }
+
+// SortBy - order of keys in files and on Load, see Comparator. Must be called before first Collect.
+// Resumable collector is not supported: comparator can't be stored in manifest, files of previous run may have
+// other order.
+func (c *Collector) SortBy(cmp Comparator) error {
+	if c.manifest != nil {
+		return fmt.Errorf("%s: SortBy doesn't support resumable collector", c.logPrefix)
+	}
+	if c.buf != nil {
+		b, ok := c.buf.(sortableBy)
+		if !ok {
+			return fmt.Errorf("%s: buffer %T doesn't support SortBy", c.logPrefix, c.buf)
+		}
+		b.sortBy(cmp)
+	}
+	c.cmp = cmp
+	return nil
+}
+
 func (c *Collector) FlushBuffer(canStoreInRam bool) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	if c.flushErr != nil {
+		return c.flushErr
+	}
 	return c.flushBufferInternal(canStoreInRam)
 }
 
@@ -122,19 +261,32 @@ func (c *Collector) flushBufferInternal(canStoreInRam bool) error {
 	}
 	var provider dataProvider
 	c.buf.Sort()
-	if canStoreInRam && len(c.dataProviders) == 0 {
+	if canStoreInRam && len(c.dataProviders) == 0 && c.manifest == nil {
 		provider = KeepInRAM(c.buf)
 		c.allFlushed = true
 	} else {
 		doFsync := !c.autoClean /* is critical collector */
 		var err error
-		provider, err = FlushToDisk(c.logPrefix, c.buf, c.tmpdir, doFsync, c.logLvl)
+		provider, err = flushToDisk(c.logPrefix, c.buf, c.tmpdir, doFsync, c.logLvl, c.spillCodec)
 		if err != nil {
 			return err
 		}
+		if f, ok := c.buf.(freeable); ok && c.budget != nil {
+			f.free()
+		}
 	}
 	if provider != nil {
 		c.dataProviders = append(c.dataProviders, provider)
+		if fp, ok := provider.(*fileDataProvider); ok && c.manifest != nil {
+			c.manifest.Files = append(c.manifest.Files, manifestFile{Name: filepath.Base(fp.file.Name()), Size: fp.size, Checksum: fp.checksum})
+			if err := c.manifest.save(c.tmpdir); err != nil {
+				return err
+			}
+		}
+	}
+	if c.budget != nil { // buffer kept in RAM is still accounted
+		c.budgetSize = c.buf.Size()
+		c.budget.release(c, c.budgetSize)
 	}
 	return nil
 }
@@ -149,11 +301,17 @@ func (c *Collector) Load(db kv.RwTx, toBucket string, loadFunc LoadFunc, args Tr
 			return e
 		}
 	}
+	if c.manifest != nil && !c.manifest.Complete {
+		c.manifest.Complete = true
+		if err := c.manifest.save(c.tmpdir); err != nil {
+			return err
+		}
+	}
 
 	bucket := toBucket
 
 	var cursor kv.RwCursor
-	haveSortingGuaranties := isIdentityLoadFunc(loadFunc) // user-defined loadFunc may change ordering
+	haveSortingGuaranties := isIdentityLoadFunc(loadFunc) && c.cmp == nil // user-defined loadFunc or comparator may change ordering
 	var lastKey []byte
 	if bucket != "" { // passing empty bucket name is valid case for etl when DB modification is not expected
 		var err error
@@ -239,17 +397,188 @@ func (c *Collector) Load(db kv.RwTx, toBucket string, loadFunc LoadFunc, args Tr
 		return nil
 	}
 
+	// resume: skip keys which are already loaded
+	startKey := args.LoadStartKey
+	var loadedSinceCommit int
+	var prevLoaded []byte
 	currentTable := &currentTableReader{db, bucket}
 	simpleLoad := func(k, v []byte) error {
-		return loadFunc(k, v, currentTable, loadNextFunc)
+		if startKey != nil && compareKeys(c.cmp, k, startKey) < 0 {
+			return nil
+		}
+		// commit only on key change: all values of key are loaded
+		if args.OnLoadCommit != nil && args.LoadCommitEvery > 0 && loadedSinceCommit >= args.LoadCommitEvery && !bytes.Equal(prevLoaded, k) {
+			if err := args.OnLoadCommit(db, prevLoaded, false); err != nil {
+				return err
+			}
+			loadedSinceCommit = 0
+		}
+		if err := loadFunc(k, v, currentTable, loadNextFunc); err != nil {
+			return err
+		}
+		loadedSinceCommit++
+		prevLoaded = append(prevLoaded[:0], k...)
+		return nil
 	}
-	if err := mergeSortFiles(c.logPrefix, c.dataProviders, simpleLoad, args); err != nil {
+	if err := mergeSortFiles(c.logPrefix, c.dataProviders, c.cmp, simpleLoad, args); err != nil {
 		return fmt.Errorf("loadIntoTable %s: %w", toBucket, err)
 	}
+	if args.OnLoadCommit != nil {
+		if err := args.OnLoadCommit(db, prevLoaded, true); err != nil {
+			return err
+		}
+	}
+	if c.manifest != nil { // done: nothing to resume
+		c.Close()
+		if err := removeSpillFiles(c.tmpdir); err != nil {
+			return err
+		}
+		c.manifest = &manifest{BufType: c.bufType}
+		c.resumed = false
+	}
 	//log.Trace(fmt.Sprintf("[%s] ETL Load done", c.logPrefix), "bucket", bucket, "records", i)
 	return nil
 }
 
+// LoadParallelFunc - see Collector.LoadParallel
+type LoadParallelFunc func(rangeIdx int, k, v []byte) error
+
+// LoadParallel - merges files as Load does, but merged stream is range-partitioned: every `rangeSize` pairs
+// (extended to the end of key: values of one key never go to different ranges) form next range.
+// Ranges are loaded by `workers` goroutines concurrently, pairs of one range come to `loadFunc` in order.
+// `rangeIdx` grows with keys: ranges 0, 1, 2... cover whole stream without overlap - caller can write every
+// range to own file (or table) and concatenate results.
+// Resumable collector is not supported: there is no single last loaded key.
+func (c *Collector) LoadParallel(ctx context.Context, workers, rangeSize int, loadFunc LoadParallelFunc) error {
+	return c.LoadRanges(ctx, workers, rangeSize, 0, func(rangeIdx int, keys, vals [][]byte) (func() error, error) {
+		for j := range keys {
+			if err := loadFunc(rangeIdx, keys[j], vals[j]); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+}
+
+// LoadRangeFunc - see Collector.LoadRanges. Processes range concurrently with other ranges, `keys` and `vals`
+// belong to callee. Returned `commit` (can be nil) is called after commits of all previous ranges and before
+// commit of next range: it appends result of range to output shared by all ranges.
+type LoadRangeFunc func(rangeIdx int, keys, vals [][]byte) (commit func() error, err error)
+
+// LoadRanges - same as LoadParallel, but `loadFunc` receives whole range, and range is extended to the end of key
+// prefix: keys with same first `prefixLen` bytes (0 - whole key) never go to different ranges. For builders of
+// single file from groups of keys: range is processed concurrently, its result is committed in order of ranges
+// (RecSplit builds hash functions of buckets and writes them to index in order of buckets).
+func (c *Collector) LoadRanges(ctx context.Context, workers, rangeSize, prefixLen int, loadFunc LoadRangeFunc) error {
+	if c.autoClean {
+		defer c.Close()
+	}
+	if c.manifest != nil {
+		return fmt.Errorf("%s: LoadRanges doesn't support resumable collector", c.logPrefix)
+	}
+	if !c.allFlushed {
+		if e := c.FlushBuffer(true); e != nil {
+			return e
+		}
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if rangeSize < 1 {
+		rangeSize = 1
+	}
+	prefix := func(k []byte) []byte {
+		if prefixLen > 0 && len(k) > prefixLen {
+			return k[:prefixLen]
+		}
+		return k
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	ranges := make(chan *loadRange, workers)
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			for r := range ranges {
+				commit, err := loadFunc(r.idx, r.keys, r.vals)
+				if err != nil {
+					return err
+				}
+				select {
+				case <-r.prevCommitted:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				if commit != nil {
+					if err := commit(); err != nil {
+						return err
+					}
+				}
+				close(r.committed)
+			}
+			return nil
+		})
+	}
+	g.Go(func() error {
+		defer close(ranges)
+		logEvery := time.NewTicker(30 * time.Second)
+		defer logEvery.Stop()
+
+		committed := make(chan struct{})
+		close(committed) // nothing to wait before first range
+		r := newLoadRange(0, committed)
+		send := func() error {
+			select {
+			case ranges <- r:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			r = newLoadRange(r.idx+1, r.committed)
+			return nil
+		}
+		var prevK []byte
+		hasPrev := false
+		if err := mergeSortFiles(c.logPrefix, c.dataProviders, c.cmp, func(k, v []byte) error {
+			if hasPrev && c.bufType == SortableOldestAppearedBuffer && bytes.Equal(prevK, k) { // see Load
+				return nil
+			}
+			samePrefix := hasPrev && bytes.Equal(prefix(prevK), prefix(k))
+			if !samePrefix && len(r.keys) >= rangeSize {
+				if err := send(); err != nil {
+					return err
+				}
+			}
+			prevK, hasPrev = append(prevK[:0], k...), true
+			r.keys, r.vals = append(r.keys, common.Copy(k)), append(r.vals, common.Copy(v))
+
+			select {
+			default:
+			case <-logEvery.C:
+				log.Log(c.logLvl, fmt.Sprintf("[%s] ETL [2/2] Loading", c.logPrefix), "range", r.idx, "current_prefix", makeCurrentKeyStr(k))
+			}
+			return nil
+		}, TransformArgs{Quit: ctx.Done()}); err != nil {
+			return err
+		}
+		if len(r.keys) == 0 {
+			return nil
+		}
+		return send()
+	})
+	return g.Wait()
+}
+
+// loadRange - copy of pairs of one range, see LoadRanges
+type loadRange struct {
+	idx           int
+	keys, vals    [][]byte
+	prevCommitted <-chan struct{} // closed after commit of previous range
+	committed     chan struct{}
+}
+
+func newLoadRange(idx int, prevCommitted <-chan struct{}) *loadRange {
+	return &loadRange{idx: idx, prevCommitted: prevCommitted, committed: make(chan struct{})}
+}
+
 func (c *Collector) reset() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -259,6 +588,14 @@ func (c *Collector) reset() {
 	c.dataProviders = nil
 	c.buf.Reset()
 	c.allFlushed = false
+	c.flushErr = nil
+	if c.budget != nil {
+		if f, ok := c.buf.(freeable); ok {
+			f.free()
+		}
+		c.budget.leave(c)
+		c.budgetSize = 0
+	}
 }
 
 func (c *Collector) Close() {
@@ -272,17 +609,19 @@ func (c *Collector) Close() {
 // for the next item, which is then added back to the heap.
 // The subsequent iterations pop the heap again and load up the provider associated with it to get the next element after processing LoadFunc.
 // this continues until all providers have reached their EOF.
-func mergeSortFiles(logPrefix string, providers []dataProvider, loadFunc simpleLoadFunc, args TransformArgs) error {
-	h := &Heap{}
+func mergeSortFiles(logPrefix string, providers []dataProvider, cmp Comparator, loadFunc simpleLoadFunc, args TransformArgs) error {
+	h := NewHeap(cmp)
 	heap.Init(h)
 	for i, provider := range providers {
 		if key, value, err := provider.Next(nil, nil); err == nil {
 			he := HeapElem{key, value, i}
 			heap.Push(h, he)
-		} else /* we must have at least one entry per file */ {
+		} else if errors.Is(err, io.EOF) /* we must have at least one entry per file */ {
 			eee := fmt.Errorf("%s: error reading first readers: n=%d current=%d provider=%s err=%w",
 				logPrefix, len(providers), i, provider, err)
 			panic(eee)
+		} else {
+			return fmt.Errorf("%s: error reading first element from disk: %w", logPrefix, err)
 		}
 	}
 
@@ -0,0 +1,58 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package etl
+
+import "bytes"
+
+// Comparator - order of keys in files of collector and on Load (see Collector.SortBy). Returns -1, 0, +1 as
+// bytes.Compare does. Pairs with equal keys keep order of Collect. nil means bytes.Compare.
+type Comparator func(k1, k2 []byte) int
+
+// Reverse - descending order of `cmp`
+func Reverse(cmp Comparator) Comparator {
+	return func(k1, k2 []byte) int { return compareKeys(cmp, k2, k1) }
+}
+
+// ReversePrefix - descending order of first `prefixLen` bytes of key (for example big-endian txNum - newest first),
+// then ascending order of the rest of key
+func ReversePrefix(prefixLen int) Comparator {
+	return func(k1, k2 []byte) int {
+		p1, p2 := k1, k2
+		if len(p1) > prefixLen {
+			p1 = p1[:prefixLen]
+		}
+		if len(p2) > prefixLen {
+			p2 = p2[:prefixLen]
+		}
+		if c := bytes.Compare(p2, p1); c != 0 {
+			return c
+		}
+		return bytes.Compare(k1[len(p1):], k2[len(p2):])
+	}
+}
+
+func compareKeys(cmp Comparator, k1, k2 []byte) int {
+	if cmp == nil {
+		return bytes.Compare(k1, k2)
+	}
+	return cmp(k1, k2)
+}
+
+// sortableBy - implemented by buffers of this package
+type sortableBy interface {
+	sortBy(cmp Comparator)
+}
@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 
@@ -35,10 +36,16 @@ type fileDataProvider struct {
 	file       *os.File
 	reader     io.Reader
 	byteReader io.ByteReader // Different interface to the same object as reader
+	size       int64
+	checksum   uint32 // crc32c of file, see manifest
 }
 
 // FlushToDisk - `doFsync` is true only for 'critical' collectors (which should not loose).
 func FlushToDisk(logPrefix string, b Buffer, tmpdir string, doFsync bool, lvl log.Lvl) (dataProvider, error) {
+	return flushToDisk(logPrefix, b, tmpdir, doFsync, lvl, SpillPlain)
+}
+
+func flushToDisk(logPrefix string, b Buffer, tmpdir string, doFsync bool, lvl log.Lvl, codec SpillCodec) (dataProvider, error) {
 	if b.Len() == 0 {
 		return nil, nil
 	}
@@ -51,7 +58,7 @@ func FlushToDisk(logPrefix string, b Buffer, tmpdir string, doFsync bool, lvl lo
 		}
 	}
 
-	bufferFile, err := os.CreateTemp(tmpdir, "erigon-sortable-buf-")
+	bufferFile, err := os.CreateTemp(tmpdir, spillFilePrefix)
 	if err != nil {
 		return nil, err
 	}
@@ -59,19 +66,38 @@ func FlushToDisk(logPrefix string, b Buffer, tmpdir string, doFsync bool, lvl lo
 		defer bufferFile.Sync() //nolint:errcheck
 	}
 
-	w := bufio.NewWriterSize(bufferFile, BufIOSize)
-	defer w.Flush() //nolint:errcheck
+	h := crc32.New(crc32c)
+	cw := &countingWriter{w: io.MultiWriter(bufferFile, h)}
+	w := bufio.NewWriterSize(cw, BufIOSize)
 
 	defer func() {
 		b.Reset() // run it after buf.flush and file.sync
 		log.Log(lvl, fmt.Sprintf("[%s] Flushed buffer file", logPrefix), "name", bufferFile.Name())
 	}()
 
-	if err = b.Write(w); err != nil {
+	bw := newBlockWriter(w, codec)
+	if err = b.Write(bw); err != nil {
+		return nil, fmt.Errorf("error writing entries to disk: %w", err)
+	}
+	if err = bw.Close(); err != nil {
+		return nil, fmt.Errorf("error writing entries to disk: %w", err)
+	}
+	if err = w.Flush(); err != nil {
 		return nil, fmt.Errorf("error writing entries to disk: %w", err)
 	}
 
-	return &fileDataProvider{file: bufferFile, reader: nil}, nil
+	return &fileDataProvider{file: bufferFile, reader: nil, size: cw.n, checksum: h.Sum32()}, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
 }
 
 func (p *fileDataProvider) Next(keyBuf, valBuf []byte) ([]byte, []byte, error) {
@@ -81,9 +107,14 @@ func (p *fileDataProvider) Next(keyBuf, valBuf []byte) ([]byte, []byte, error) {
 			return nil, nil, err
 		}
 		r := bufio.NewReaderSize(p.file, BufIOSize)
-		p.reader = r
-		p.byteReader = r
-
+		// files of previous versions have no header: plain sequence of elements. First byte of such file
+		// is zigzag-varint of key length (or -1 for nil) - never equal to first byte of spillMagic.
+		if magic, _ := r.Peek(len(spillMagic)); string(magic) == spillMagic {
+			br := newBlockReader(r, p.file.Name())
+			p.reader, p.byteReader = br, br
+		} else {
+			p.reader, p.byteReader = r, r
+		}
 	}
 	return readElementFromDisk(p.reader, p.byteReader, keyBuf, valBuf)
 }
@@ -73,6 +73,14 @@ type TransformArgs struct {
 	BufferType      int
 	BufferSize      int
 
+	// LoadStartKey - Load skips keys < LoadStartKey: [LoadStartKey, end)
+	LoadStartKey []byte
+	// OnLoadCommit - called by Load after every LoadCommitEvery loaded keys (only on key change) and once at the end.
+	// Receives last loaded key of collector (before LoadFunc). To continue Load after restart - store this key
+	// in `db` (it's committed together with loaded data) and pass NextKey of it as LoadStartKey.
+	OnLoadCommit    LoadCommitHandler
+	LoadCommitEvery int
+
 	UseAsyncExecution      bool
 	AsyncExtractChan       chan ExtractFuncArgs
 	AsyncExtractResultChan <-chan ExtractResult
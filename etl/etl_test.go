@@ -17,16 +17,27 @@ package etl
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/log/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -483,3 +494,560 @@ func TestReuseCollectorAfterLoad(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 1, see)
 }
+
+func TestResumableCollector(t *testing.T) {
+	require := require.New(t)
+	dir, table := t.TempDir(), kv.ChaindataTables[0]
+	db := memdb.NewTestDB(t)
+	progressKey := []byte(t.Name())
+	key := func(i int) []byte { return []byte(fmt.Sprintf("%04d", i)) }
+	collect := func() *Collector {
+		c, err := NewResumableCollector(t.Name(), dir, NewSortableBuffer(1)) // every Collect creates file
+		require.NoError(err)
+		require.False(c.Resumed())
+		for i := 99; i >= 0; i-- {
+			require.NoError(c.Collect(key(i), key(i)))
+		}
+		return c
+	}
+	errCrash := fmt.Errorf("crash")
+	// load - as stage does: progress is stored in same tx with loaded data. Load fails on `crashOnCommit`-th
+	// commit, then tx is rolled back (crash) or committed (caller committed what was loaded).
+	load := func(c *Collector, crashOnCommit int, rollback bool) error {
+		tx, err := db.BeginRw(context.Background())
+		require.NoError(err)
+		defer tx.Rollback()
+		progress, err := tx.GetOne(kv.SyncStageProgress, progressKey)
+		require.NoError(err)
+		var startKey []byte
+		if progress != nil {
+			startKey, err = NextKey(progress)
+			require.NoError(err)
+		}
+		var calls int
+		err = c.Load(tx, table, IdentityLoadFunc, TransformArgs{LoadStartKey: startKey, LoadCommitEvery: 10, OnLoadCommit: func(db kv.Putter, k []byte, isDone bool) error {
+			calls++
+			if calls == crashOnCommit {
+				return errCrash
+			}
+			return db.Put(kv.SyncStageProgress, progressKey, k)
+		}})
+		if err != nil && rollback {
+			return err
+		}
+		require.NoError(tx.Commit())
+		return err
+	}
+	files := func() int {
+		entries, err := os.ReadDir(dir)
+		require.NoError(err)
+		return len(entries)
+	}
+
+	// crash before Load: data is not complete - can't resume
+	c := collect()
+	require.Equal(100+1, files())
+	c, err := NewResumableCollector(t.Name(), dir, NewSortableBuffer(1))
+	require.NoError(err)
+	require.False(c.Resumed())
+	require.Equal(0, files())
+
+	// crash during Load, tx is rolled back: nothing was loaded - resumed Load starts from beginning
+	c = collect()
+	require.ErrorIs(load(c, 5, true), errCrash)
+	require.Equal(100+1, files())
+
+	_, err = NewResumableCollector(t.Name(), dir, NewAppendBuffer(1))
+	require.Error(err)
+	c, err = NewResumableCollector(t.Name(), dir, NewSortableBuffer(1))
+	require.NoError(err)
+	require.True(c.Resumed())
+
+	// crash again, but tx is committed: 4 commits happened before crash, progress is key(39)
+	require.ErrorIs(load(c, 5, false), errCrash)
+	c, err = NewResumableCollector(t.Name(), dir, NewSortableBuffer(1))
+	require.NoError(err)
+	require.True(c.Resumed())
+	require.NoError(load(c, 0, false))
+	require.Equal(0, files())
+
+	tx := memdb.BeginRo(t, db)
+	require.Equal(100, countKeys(t, tx, table))
+	for i := 0; i < 100; i++ {
+		v, err := tx.GetOne(table, key(i))
+		require.NoError(err)
+		require.Equal(key(i), v)
+	}
+	progress, err := tx.GetOne(kv.SyncStageProgress, progressKey)
+	require.NoError(err)
+	require.Equal(key(99), progress)
+	tx.Rollback()
+
+	// corrupted file: start from scratch
+	c = collect()
+	require.ErrorIs(load(c, 1, true), errCrash)
+	entries, err := os.ReadDir(dir)
+	require.NoError(err)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), spillFilePrefix) {
+			require.NoError(os.WriteFile(dir+"/"+e.Name(), []byte{1, 2, 3}, 0644))
+			break
+		}
+	}
+	c, err = NewResumableCollector(t.Name(), dir, NewSortableBuffer(1))
+	require.NoError(err)
+	require.False(c.Resumed())
+	require.Equal(0, files())
+}
+
+// TestResumableCollectorRestart - process is killed during Load: db and collector are not closed properly.
+// After restart both are reopened from disk and Load continues from progress committed before the crash.
+func TestResumableCollectorRestart(t *testing.T) {
+	require := require.New(t)
+	ctx, dir, dbDir, table := context.Background(), t.TempDir(), t.TempDir(), kv.ChaindataTables[0]
+	progressKey := []byte(t.Name())
+	key := func(i int) []byte { return []byte(fmt.Sprintf("%04d", i)) }
+	openDB := func() kv.RwDB { return mdbx.NewMDBX(log.New()).Path(dbDir).MustOpen() }
+	errCrash := fmt.Errorf("crash")
+
+	// 1st run: 5th commit stores progress, commits tx and crashes
+	db := openDB()
+	c, err := NewResumableCollector(t.Name(), dir, NewSortableBuffer(1024))
+	require.NoError(err)
+	require.False(c.Resumed())
+	for i := 999; i >= 0; i-- {
+		require.NoError(c.Collect(key(i), key(i)))
+	}
+	tx, err := db.BeginRw(ctx)
+	require.NoError(err)
+	var commits int
+	err = c.Load(tx, table, IdentityLoadFunc, TransformArgs{LoadCommitEvery: 100, OnLoadCommit: func(db kv.Putter, k []byte, isDone bool) error {
+		if err := db.Put(kv.SyncStageProgress, progressKey, k); err != nil {
+			return err
+		}
+		if commits++; commits == 5 {
+			return errCrash
+		}
+		return nil
+	}})
+	require.ErrorIs(err, errCrash)
+	require.NoError(tx.Commit())
+	db.Close()
+
+	// 2nd run: nothing is collected, Load starts after committed progress
+	db = openDB()
+	defer db.Close()
+	c, err = NewResumableCollector(t.Name(), dir, NewSortableBuffer(1024))
+	require.NoError(err)
+	require.True(c.Resumed())
+	var loaded int
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		progress, err := tx.GetOne(kv.SyncStageProgress, progressKey)
+		require.NoError(err)
+		require.Equal(key(499), progress)
+		startKey, err := NextKey(progress)
+		require.NoError(err)
+		return c.Load(tx, table, func(k, v []byte, table CurrentTableReader, next LoadNextFunc) error {
+			loaded++
+			return next(k, k, v)
+		}, TransformArgs{LoadStartKey: startKey, LoadCommitEvery: 100, OnLoadCommit: func(db kv.Putter, k []byte, isDone bool) error {
+			return db.Put(kv.SyncStageProgress, progressKey, k)
+		}})
+	}))
+	require.Equal(500, loaded) // keys loaded before crash are not loaded again
+	entries, err := os.ReadDir(dir)
+	require.NoError(err)
+	require.Empty(entries)
+
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		require.Equal(1000, countKeys(t, tx, table))
+		for i := 0; i < 1000; i++ {
+			v, err := tx.GetOne(table, key(i))
+			require.NoError(err)
+			require.Equal(key(i), v)
+		}
+		return nil
+	}))
+}
+
+func TestLoadStartKey(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+	table := kv.ChaindataTables[0]
+	c := NewCollector(t.Name(), "", NewSortableBuffer(1))
+	defer c.Close()
+	for i := byte(0); i < 10; i++ {
+		require.NoError(c.Collect([]byte{i}, []byte{i}))
+	}
+	require.NoError(c.Load(tx, table, IdentityLoadFunc, TransformArgs{LoadStartKey: []byte{5}}))
+	require.Equal(5, countKeys(t, tx, table))
+	k, err := kv.FirstKey(tx, table)
+	require.NoError(err)
+	require.Equal([]byte{5}, k)
+}
+
+func countKeys(t *testing.T, tx kv.Tx, table string) (cnt int) {
+	t.Helper()
+	require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+		cnt++
+		return nil
+	}))
+	return cnt
+}
+
+func TestSpillCodecs(t *testing.T) {
+	require := require.New(t)
+	const count = 50_000
+	sizes := map[SpillCodec]int64{}
+	for _, codec := range []SpillCodec{SpillPlain, SpillLZ4} {
+		tmpdir := t.TempDir()
+		c := NewCollector(t.Name(), tmpdir, NewSortableBuffer(256*1024))
+		c.SpillCodec(codec)
+		for i := count - 1; i >= 0; i-- {
+			require.NoError(c.Collect([]byte(fmt.Sprintf("key-%08d", i)), bytes.Repeat([]byte{byte(i)}, 32)))
+		}
+		require.NoError(c.FlushBuffer(false))
+		entries, err := os.ReadDir(tmpdir)
+		require.NoError(err)
+		require.Greater(len(entries), 1)
+		for _, e := range entries {
+			info, err := e.Info()
+			require.NoError(err)
+			sizes[codec] += info.Size()
+		}
+
+		var i int
+		require.NoError(c.Load(nil, "", func(k, v []byte, _ CurrentTableReader, _ LoadNextFunc) error {
+			require.Equal(fmt.Sprintf("key-%08d", i), string(k))
+			require.Equal(bytes.Repeat([]byte{byte(i)}, 32), v)
+			i++
+			return nil
+		}, TransformArgs{}))
+		require.Equal(count, i)
+	}
+	require.Less(sizes[SpillLZ4], sizes[SpillPlain]/2)
+}
+
+func TestSpillChecksum(t *testing.T) {
+	require := require.New(t)
+	tmpdir := t.TempDir()
+	c := NewCollector(t.Name(), tmpdir, NewSortableBuffer(1024*1024))
+	defer c.Close()
+	c.SpillCodec(SpillLZ4)
+	for i := 0; i < 10_000; i++ {
+		require.NoError(c.Collect([]byte(fmt.Sprintf("key-%08d", i)), []byte(fmt.Sprintf("value-%d", i))))
+	}
+	require.NoError(c.FlushBuffer(false))
+	entries, err := os.ReadDir(tmpdir)
+	require.NoError(err)
+	require.Equal(1, len(entries))
+	fileName := tmpdir + "/" + entries[0].Name()
+	data, err := os.ReadFile(fileName)
+	require.NoError(err)
+	data[len(data)/2] ^= 1 // flip 1 bit
+	require.NoError(os.WriteFile(fileName, data, 0644))
+
+	err = c.Load(nil, "", func(k, v []byte, _ CurrentTableReader, _ LoadNextFunc) error { return nil }, TransformArgs{})
+	require.ErrorIs(err, ErrChecksum)
+	require.Contains(err.Error(), fileName)
+	require.Contains(err.Error(), "offset 8")
+}
+
+func TestSpillTruncated(t *testing.T) {
+	require := require.New(t)
+	for _, cut := range []string{"trailer", "last block"} {
+		tmpdir := t.TempDir()
+		c := NewCollector(t.Name(), tmpdir, NewSortableBuffer(8*1024*1024))
+		for i := 0; i < 50_000; i++ {
+			require.NoError(c.Collect([]byte(fmt.Sprintf("key-%08d", i)), bytes.Repeat([]byte{byte(i)}, 32)))
+		}
+		require.NoError(c.FlushBuffer(false))
+		entries, err := os.ReadDir(tmpdir)
+		require.NoError(err)
+		require.Equal(1, len(entries))
+		fileName := tmpdir + "/" + entries[0].Name()
+		data, err := os.ReadFile(fileName)
+		require.NoError(err)
+
+		// file is cut at block boundary: all remaining blocks are valid
+		size := len(data) - spillTrailerSize
+		if cut == "last block" {
+			var blocks []int
+			for offset := spillFileHeaderSize; offset < size; offset += spillBlockHeaderSize + int(binary.BigEndian.Uint32(data[offset:])) {
+				blocks = append(blocks, offset)
+			}
+			require.Greater(len(blocks), 1)
+			size = blocks[len(blocks)-1]
+		}
+		require.NoError(os.WriteFile(fileName, data[:size], 0644))
+
+		err = c.Load(nil, "", func(k, v []byte, _ CurrentTableReader, _ LoadNextFunc) error { return nil }, TransformArgs{})
+		require.ErrorIs(err, ErrChecksum, cut)
+		require.Contains(err.Error(), "truncated", cut)
+	}
+}
+
+func TestCollectorFromLegacyFiles(t *testing.T) {
+	require := require.New(t)
+	tmpdir := t.TempDir()
+	// spill file of previous version: elements without block format
+	b := NewSortableBuffer(1024)
+	for i := 0; i < 100; i++ {
+		b.Put([]byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	b.Sort()
+	f, err := os.CreateTemp(tmpdir, spillFilePrefix)
+	require.NoError(err)
+	require.NoError(b.Write(f))
+	require.NoError(f.Close())
+	require.NoError(os.WriteFile(filepath.Join(tmpdir, manifestFileName), []byte("{}"), 0644))
+
+	c, err := NewCollectorFromFiles(t.Name(), tmpdir)
+	require.NoError(err)
+	var i int
+	require.NoError(c.Load(nil, "", func(k, v []byte, _ CurrentTableReader, _ LoadNextFunc) error {
+		require.Equal(fmt.Sprintf("key-%03d", i), string(k))
+		require.Equal(fmt.Sprintf("value-%d", i), string(v))
+		i++
+		return nil
+	}, TransformArgs{}))
+	require.Equal(100, i)
+}
+
+func TestMemoryBudget(t *testing.T) {
+	require := require.New(t)
+	budget := NewMemoryBudget(t.Name(), datasize.MB)
+	a := NewCollectorWithBudget("a", t.TempDir(), SortableSliceBuffer, budget)
+	defer a.Close()
+	b := NewCollectorWithBudget("b", t.TempDir(), SortableSliceBuffer, budget)
+	defer b.Close()
+	collect := func(c *Collector, from, to int) {
+		for i := from; i < to; i++ {
+			k := make([]byte, 8)
+			binary.BigEndian.PutUint64(k, uint64(i))
+			require.NoError(c.Collect(k, make([]byte, 1024-8)))
+		}
+	}
+
+	// single collector can use whole budget
+	collect(a, 0, 650)
+	require.Equal(0, len(a.dataProviders))
+	require.Equal(datasize.MB, budget.Share())
+
+	// second collector: budget is exceeded, idle `a` is forced to give back memory borrowed over its share
+	collect(b, 0, 400)
+	require.Equal(0, len(b.dataProviders))
+	require.Equal(datasize.MB/2, budget.Share())
+	require.Equal(1, len(a.dataProviders))
+	require.Nil(a.buf.(*sortableBuffer).data) // capacity is given back too
+	require.Less(budget.Used(), datasize.MB)
+	collect(a, 650, 650+budgetStep/1024+1)
+	require.Equal(1, len(a.dataProviders))
+
+	// closed collector leaves budget
+	a.Close()
+	require.Equal(datasize.MB, budget.Share())
+	b.Close()
+	require.Equal(datasize.ByteSize(0), budget.Used())
+}
+
+func TestSortBy(t *testing.T) {
+	require := require.New(t)
+	loadKeys := func(c *Collector) (keys [][]byte) {
+		require.NoError(c.Load(nil, "", func(k, v []byte, _ CurrentTableReader, _ LoadNextFunc) error {
+			keys = append(keys, common.Copy(k))
+			return nil
+		}, TransformArgs{}))
+		return keys
+	}
+	key := func(txNum uint64, suffix string) []byte {
+		k := make([]byte, 8, 8+len(suffix))
+		binary.BigEndian.PutUint64(k, txNum)
+		return append(k, suffix...)
+	}
+
+	// many files: order of buffers and of merge
+	c := NewCollector(t.Name(), t.TempDir(), NewSortableBuffer(1024))
+	require.NoError(c.SortBy(ReversePrefix(8)))
+	for _, suffix := range []string{"b", "a", "c"} {
+		for txNum := uint64(0); txNum < 100; txNum++ {
+			require.NoError(c.Collect(key(txNum, suffix), []byte(suffix)))
+		}
+	}
+	got := loadKeys(c)
+	require.Equal(300, len(got))
+	for i := range got {
+		require.Equal(key(99-uint64(i/3), []string{"a", "b", "c"}[i%3]), got[i])
+	}
+
+	// data kept in RAM
+	c = NewCollector(t.Name(), t.TempDir(), NewAppendBuffer(BufferOptimalSize))
+	require.NoError(c.SortBy(Reverse(nil)))
+	for _, k := range []string{"a", "c", "b"} {
+		require.NoError(c.Collect([]byte(k), []byte(k)))
+	}
+	require.Equal([][]byte{[]byte("c"), []byte("b"), []byte("a")}, loadKeys(c))
+
+	// buffer of other package can't be sorted
+	c = &Collector{buf: foreignBuffer{NewSortableBuffer(1024)}, logPrefix: t.Name()}
+	require.Error(c.SortBy(Reverse(nil)))
+	require.Nil(c.cmp)
+
+	// comparator is not stored in manifest
+	rc, err := NewResumableCollector(t.Name(), t.TempDir(), NewSortableBuffer(1024))
+	require.NoError(err)
+	defer rc.Close()
+	require.Error(rc.SortBy(Reverse(nil)))
+	require.Nil(rc.cmp)
+}
+
+// foreignBuffer - Buffer which doesn't implement sortableBy
+type foreignBuffer struct {
+	Buffer
+}
+
+func TestLoadParallel(t *testing.T) {
+	require := require.New(t)
+	const count, rangeSize = 5_000, 100
+	for _, bufType := range []int{SortableSliceBuffer, SortableOldestAppearedBuffer} {
+		c := NewCollector(t.Name(), t.TempDir(), getBufferByType(bufType, 8*1024))
+		for i := count - 1; i >= 0; i-- {
+			k := []byte(fmt.Sprintf("key-%08d", i/2)) // 2 values per key
+			require.NoError(c.Collect(k, []byte(fmt.Sprintf("%d", i))))
+		}
+
+		var lock sync.Mutex
+		ranges := map[int][]string{}
+		require.NoError(c.LoadParallel(context.Background(), 4, rangeSize, func(rangeIdx int, k, v []byte) error {
+			lock.Lock()
+			defer lock.Unlock()
+			ranges[rangeIdx] = append(ranges[rangeIdx], string(k)+"="+string(v))
+			return nil
+		}))
+
+		var got []string
+		for i := 0; i < len(ranges); i++ {
+			if i < len(ranges)-1 { // last range can be smaller
+				require.GreaterOrEqual(len(ranges[i]), rangeSize)
+				lastKey, _, _ := strings.Cut(ranges[i][len(ranges[i])-1], "=")
+				nextKey, _, _ := strings.Cut(ranges[i+1][0], "=")
+				require.NotEqual(lastKey, nextKey) // key is not split between ranges
+			}
+			got = append(got, ranges[i]...)
+		}
+		var expect []string
+		for i := 0; i < count/2; i++ {
+			if bufType == SortableOldestAppearedBuffer {
+				expect = append(expect, fmt.Sprintf("key-%08d=%d", i, 2*i+1))
+				continue
+			}
+			expect = append(expect, fmt.Sprintf("key-%08d=%d", i, 2*i+1), fmt.Sprintf("key-%08d=%d", i, 2*i))
+		}
+		require.Equal(expect, got)
+	}
+
+	// error of loadFunc stops loading
+	c := NewCollector(t.Name(), t.TempDir(), NewSortableBuffer(8*1024))
+	for i := 0; i < count; i++ {
+		require.NoError(c.Collect([]byte(fmt.Sprintf("key-%08d", i)), nil))
+	}
+	errStop := errors.New("stop")
+	err := c.LoadParallel(context.Background(), 4, rangeSize, func(rangeIdx int, k, v []byte) error {
+		if rangeIdx == 3 {
+			return errStop
+		}
+		return nil
+	})
+	require.ErrorIs(err, errStop)
+}
+
+func TestLoadRanges(t *testing.T) {
+	require := require.New(t)
+	const count, rangeSize = 5_000, 100
+	c := NewCollector(t.Name(), t.TempDir(), NewSortableBuffer(8*1024))
+	for i := count - 1; i >= 0; i-- {
+		require.NoError(c.Collect([]byte(fmt.Sprintf("%04d-%04d", i/30, i)), nil)) // prefixes of 30 keys
+	}
+
+	var committed []string
+	require.NoError(c.LoadRanges(context.Background(), 4, rangeSize, 4, func(rangeIdx int, keys, vals [][]byte) (func() error, error) {
+		time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond) // ranges finish out of order
+		return func() error {
+			if len(committed) > 0 && committed[len(committed)-1][:4] == string(keys[0][:4]) {
+				return fmt.Errorf("prefix %s is split between ranges", keys[0][:4])
+			}
+			for _, k := range keys {
+				committed = append(committed, string(k))
+			}
+			return nil
+		}, nil
+	}))
+	require.Equal(count, len(committed))
+	for i := range committed {
+		require.Equal(fmt.Sprintf("%04d-%04d", i/30, i), committed[i])
+	}
+
+	// error of commit stops loading
+	c = NewCollector(t.Name(), t.TempDir(), NewSortableBuffer(8*1024))
+	for i := 0; i < count; i++ {
+		require.NoError(c.Collect([]byte(fmt.Sprintf("key-%08d", i)), nil))
+	}
+	errStop := errors.New("stop")
+	err := c.LoadRanges(context.Background(), 4, rangeSize, 0, func(rangeIdx int, keys, vals [][]byte) (func() error, error) {
+		return func() error {
+			if rangeIdx == 3 {
+				return errStop
+			}
+			return nil
+		}, nil
+	})
+	require.ErrorIs(err, errStop)
+}
+
+type testIndexer map[string]uint64
+
+func (idx testIndexer) AddKey(key []byte, offset uint64) error {
+	idx[string(key)] = offset
+	return nil
+}
+
+func TestLoadToSink(t *testing.T) {
+	require := require.New(t)
+	collect := func() *Collector {
+		c := NewCollector(t.Name(), t.TempDir(), NewSortableBuffer(64)) // many files
+		for _, kv := range [][2]string{{"b", "1"}, {"a", "2"}, {"b", "3"}, {"c", "4"}, {"a", "5"}, {"b", "6"}} {
+			require.NoError(c.Collect([]byte(kv[0]), []byte(kv[1])))
+		}
+		return c
+	}
+	for dedup, expect := range map[Dedup][]string{
+		DedupNone:   {"a=2", "a=5", "b=1", "b=3", "b=6", "c=4"},
+		DedupFirst:  {"a=2", "b=1", "c=4"},
+		DedupLast:   {"a=5", "b=6", "c=4"},
+		DedupAppend: {"a=25", "b=136", "c=4"},
+	} {
+		var got []string
+		require.NoError(collect().LoadToSink(SinkFunc(func(k, v []byte) error {
+			got = append(got, string(k)+"="+string(v))
+			return nil
+		}), dedup, TransformArgs{}))
+		require.Equal(expect, got, dedup)
+	}
+
+	c := NewCollector(t.Name(), t.TempDir(), NewSortableBuffer(BufferOptimalSize))
+	for i := uint64(0); i < 10; i++ {
+		v := make([]byte, 8)
+		binary.BigEndian.PutUint64(v, i*100)
+		require.NoError(c.Collect([]byte(fmt.Sprintf("key-%d", i%5)), v))
+	}
+	idx := testIndexer{}
+	require.NoError(c.LoadToIndex(idx, DedupLast, TransformArgs{}))
+	require.Equal(testIndexer{"key-0": 500, "key-1": 600, "key-2": 700, "key-3": 800, "key-4": 900}, idx)
+
+	c = NewCollector(t.Name(), t.TempDir(), NewSortableBuffer(BufferOptimalSize))
+	require.NoError(c.Collect([]byte("key"), []byte("not offset")))
+	require.Error(c.LoadToIndex(testIndexer{}, DedupNone, TransformArgs{}))
+	require.Error(collect().LoadToSink(SinkFunc(func(k, v []byte) error { return nil }), DedupLast, TransformArgs{
+		OnLoadCommit: func(db kv.Putter, key []byte, isDone bool) error { return nil },
+	}))
+}
@@ -16,10 +16,6 @@
 
 package etl
 
-import (
-	"bytes"
-)
-
 type HeapElem struct {
 	Key     []byte
 	Value   []byte
@@ -28,14 +24,17 @@ type HeapElem struct {
 
 type Heap struct {
 	elems []HeapElem
+	cmp   Comparator // nil means bytes.Compare
 }
 
+func NewHeap(cmp Comparator) *Heap { return &Heap{cmp: cmp} }
+
 func (h Heap) Len() int {
 	return len(h.elems)
 }
 
 func (h Heap) Less(i, j int) bool {
-	if c := bytes.Compare(h.elems[i].Key, h.elems[j].Key); c != 0 {
+	if c := compareKeys(h.cmp, h.elems[i].Key, h.elems[j].Key); c != 0 {
 		return c < 0
 	}
 	return h.elems[i].TimeIdx < h.elems[j].TimeIdx
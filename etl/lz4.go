@@ -0,0 +1,163 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package etl
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// LZ4 block format (https://github.com/lz4/lz4/blob/dev/doc/lz4_Block_format.md) - without frame format:
+// size and checksum of block are stored by SpillCodec. Greedy compressor with single hash table: spill files
+// are written once and read once, speed matters more than ratio.
+
+const (
+	lz4MinMatch     = 4
+	lz4LastLiterals = 5  // last bytes of block are always literals
+	lz4MFLimit      = 12 // last match must start at least this amount of bytes before end of block
+	lz4MaxOffset    = 65535
+	lz4HashLog      = 16
+)
+
+var errLZ4Corrupted = errors.New("lz4: corrupted block")
+
+// lz4Table - positions of 4-byte sequences (+1, 0 - empty), reused between blocks
+type lz4Table [1 << lz4HashLog]int32
+
+func lz4Hash(seq uint32) uint32 { return (seq * 2654435761) >> (32 - lz4HashLog) }
+
+// lz4Compress - appends compressed `src` to `dst`
+func lz4Compress(dst, src []byte, table *lz4Table) []byte {
+	*table = lz4Table{}
+	anchor := 0
+	for i := 0; i+lz4MFLimit <= len(src); {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		h := lz4Hash(seq)
+		ref := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if ref < 0 || i-ref > lz4MaxOffset || binary.LittleEndian.Uint32(src[ref:]) != seq {
+			i++
+			continue
+		}
+		end, maxEnd := i+lz4MinMatch, len(src)-lz4LastLiterals
+		for end < maxEnd && src[end] == src[ref+end-i] {
+			end++
+		}
+		dst = lz4AppendSequence(dst, src[anchor:i], i-ref, end-i)
+		i, anchor = end, end
+	}
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence - literals and match. Last sequence of block has only literals: matchLen == 0.
+func lz4AppendSequence(dst, literals []byte, offset, matchLen int) []byte {
+	var token byte
+	if len(literals) >= 15 {
+		token = 15 << 4
+	} else {
+		token = byte(len(literals)) << 4
+	}
+	ml := matchLen - lz4MinMatch
+	if matchLen > 0 {
+		if ml >= 15 {
+			token |= 15
+		} else {
+			token |= byte(ml)
+		}
+	}
+	dst = append(dst, token)
+	if len(literals) >= 15 {
+		dst = lz4AppendLen(dst, len(literals)-15)
+	}
+	dst = append(dst, literals...)
+	if matchLen == 0 {
+		return dst
+	}
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if ml >= 15 {
+		dst = lz4AppendLen(dst, ml-15)
+	}
+	return dst
+}
+
+func lz4AppendLen(dst []byte, n int) []byte {
+	for ; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(n))
+}
+
+// lz4Decompress - appends decompressed `src` to `dst[:0]`, result must have exactly `rawLen` bytes
+func lz4Decompress(dst, src []byte, rawLen int) ([]byte, error) {
+	dst = dst[:0]
+	for i := 0; ; {
+		if i >= len(src) {
+			return nil, errLZ4Corrupted
+		}
+		token := src[i]
+		i++
+		litLen, ok := lz4ReadLen(src, &i, int(token>>4))
+		if !ok || litLen > len(src)-i || len(dst)+litLen > rawLen {
+			return nil, errLZ4Corrupted
+		}
+		dst = append(dst, src[i:i+litLen]...)
+		i += litLen
+		if i == len(src) { // last sequence
+			if len(dst) != rawLen {
+				return nil, errLZ4Corrupted
+			}
+			return dst, nil
+		}
+
+		if i+2 > len(src) {
+			return nil, errLZ4Corrupted
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		matchLen, ok := lz4ReadLen(src, &i, int(token&15))
+		matchLen += lz4MinMatch
+		if !ok || offset == 0 || offset > len(dst) || len(dst)+matchLen > rawLen {
+			return nil, errLZ4Corrupted
+		}
+		from := len(dst) - offset
+		if offset >= matchLen {
+			dst = append(dst, dst[from:from+matchLen]...)
+			continue
+		}
+		for j := 0; j < matchLen; j++ { // overlapping match: repeats last `offset` bytes
+			dst = append(dst, dst[from+j])
+		}
+	}
+}
+
+// lz4ReadLen - length from token, extended by next bytes if it's 15
+func lz4ReadLen(src []byte, i *int, n int) (int, bool) {
+	if n != 15 {
+		return n, true
+	}
+	for {
+		if *i >= len(src) {
+			return 0, false
+		}
+		b := src[*i]
+		*i++
+		n += int(b)
+		if b != 255 {
+			return n, true
+		}
+	}
+}
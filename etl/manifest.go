@@ -0,0 +1,130 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package etl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	manifestFileName = "etl-manifest.json"
+	spillFilePrefix  = "erigon-sortable-buf-"
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// manifest - state of resumable collector, stored next to its spill files (see NewResumableCollector)
+type manifest struct {
+	BufType  int            `json:"bufType"`
+	Files    []manifestFile `json:"files"`
+	Complete bool           `json:"complete"` // all data collected: list of files is final
+}
+
+type manifestFile struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Checksum uint32 `json:"crc32c"`
+}
+
+// readManifest - returns nil if `dir` has no manifest
+func readManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("etl manifest %s: %w", dir, err)
+	}
+	return m, nil
+}
+
+// save - atomic: manifest is written to tmp file and renamed
+func (m *manifest) save(dir string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(dir, manifestFileName+".tmp")
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(dir, manifestFileName))
+}
+
+// verify - all files exist and have expected size and checksum
+func (m *manifest) verify(dir string) error {
+	for _, mf := range m.Files {
+		f, err := os.Open(filepath.Join(dir, mf.Name))
+		if err != nil {
+			return err
+		}
+		h := crc32.New(crc32c)
+		n, err := io.Copy(h, bufio.NewReaderSize(f, BufIOSize))
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+		if n != mf.Size || h.Sum32() != mf.Checksum {
+			return fmt.Errorf("file %s is corrupted: size %d, crc32c %x, expected: size %d, crc32c %x", mf.Name, n, h.Sum32(), mf.Size, mf.Checksum)
+		}
+	}
+	return nil
+}
+
+// removeSpillFiles - removes files of collector and manifest from `dir`, other files are not touched
+func removeSpillFiles(dir string) error {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range dirEntries {
+		if e.IsDir() || !(strings.HasPrefix(e.Name(), spillFilePrefix) || strings.HasPrefix(e.Name(), manifestFileName)) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
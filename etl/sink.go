@@ -0,0 +1,134 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package etl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Sink - receiver of pairs loaded by Collector.LoadToSink. Pairs come in order of collector (see SortBy), after Dedup.
+// `k` and `v` are valid only until return.
+type Sink interface {
+	Add(k, v []byte) error
+}
+
+// SinkFunc - function as Sink
+type SinkFunc func(k, v []byte) error
+
+func (f SinkFunc) Add(k, v []byte) error { return f(k, v) }
+
+// Dedup - what Sink receives if key was collected many times
+type Dedup uint8
+
+const (
+	DedupNone   Dedup = iota // all pairs
+	DedupFirst               // only first collected value of key
+	DedupLast                // only last collected value of key
+	DedupAppend              // one pair with concatenation of all values of key
+)
+
+// WordAdder - part of compress.Compressor used by LoadToCompressor (etl can't import compress)
+type WordAdder interface {
+	AddWord(word []byte) error
+}
+
+// KeyIndexer - part of recsplit.RecSplit used by LoadToIndex (etl can't import recsplit)
+type KeyIndexer interface {
+	AddKey(key []byte, offset uint64) error
+}
+
+// LoadToSink - loads collected pairs to `sink`. TransformArgs.OnLoadCommit is not supported with DedupLast and
+// DedupAppend: pair is added to sink only after next key is loaded.
+func (c *Collector) LoadToSink(sink Sink, dedup Dedup, args TransformArgs) error {
+	if args.OnLoadCommit != nil && (dedup == DedupLast || dedup == DedupAppend) {
+		return fmt.Errorf("%s: OnLoadCommit is not supported with dedup %d", c.logPrefix, dedup)
+	}
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+	var added uint64
+	add := func(k, v []byte) error {
+		added++
+		select {
+		default:
+		case <-logEvery.C:
+			log.Log(c.logLvl, fmt.Sprintf("[%s] ETL [2/2] Loading", c.logPrefix), "into", fmt.Sprintf("%T", sink), "added", added, "current_prefix", makeCurrentKeyStr(k))
+		}
+		return sink.Add(k, v)
+	}
+
+	var prevK, pendingV []byte
+	hasPrev := false
+	if err := c.Load(nil, "", func(k, v []byte, _ CurrentTableReader, _ LoadNextFunc) error {
+		sameKey := hasPrev && bytes.Equal(prevK, k)
+		switch dedup {
+		case DedupFirst:
+			if sameKey {
+				return nil
+			}
+			prevK, hasPrev = append(prevK[:0], k...), true
+			return add(k, v)
+		case DedupLast, DedupAppend:
+			if sameKey {
+				if dedup == DedupLast {
+					pendingV = pendingV[:0]
+				}
+				pendingV = append(pendingV, v...)
+				return nil
+			}
+			if hasPrev {
+				if err := add(prevK, pendingV); err != nil {
+					return err
+				}
+			}
+			prevK, pendingV, hasPrev = append(prevK[:0], k...), append(pendingV[:0], v...), true
+			return nil
+		default:
+			return add(k, v)
+		}
+	}, args); err != nil {
+		return err
+	}
+	if hasPrev && (dedup == DedupLast || dedup == DedupAppend) {
+		return add(prevK, pendingV)
+	}
+	return nil
+}
+
+// LoadToCompressor - adds every pair as 2 words: key and value (layout of .kv files). Caller calls Compress.
+func (c *Collector) LoadToCompressor(comp WordAdder, dedup Dedup, args TransformArgs) error {
+	return c.LoadToSink(SinkFunc(func(k, v []byte) error {
+		if err := comp.AddWord(k); err != nil {
+			return err
+		}
+		return comp.AddWord(v)
+	}), dedup, args)
+}
+
+// LoadToIndex - adds every key with offset to index: value must be big-endian uint64 offset. Caller calls Build.
+func (c *Collector) LoadToIndex(idx KeyIndexer, dedup Dedup, args TransformArgs) error {
+	return c.LoadToSink(SinkFunc(func(k, v []byte) error {
+		if len(v) != 8 {
+			return fmt.Errorf("%s: value of key %x is not offset: %x", c.logPrefix, k, v)
+		}
+		return idx.AddKey(k, binary.BigEndian.Uint64(v))
+	}), dedup, args)
+}
@@ -0,0 +1,284 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package etl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// SpillCodec - compression of collector files (see Collector.SpillCodec). File is sequence of blocks (up to
+// BufIOSize of raw data each), every block has checksum - only compression is optional:
+//
+//	file:    magic "ETLB", codec_u8, 3 reserved bytes, blocks..., trailer
+//	block:   payloadLen_u32, rawLen_u32, crc32c(payload)_u32, payload
+//	trailer: magic "ETLE", blocks_u32, rawLen_u64, crc32c(trailer before it)_u32
+//
+// Checksums are verified on read: corrupted block fails Load with ErrChecksum instead of loading garbage.
+// Trailer detects file truncated at block boundary.
+type SpillCodec uint8
+
+const (
+	SpillPlain SpillCodec = iota // not compressed
+	SpillLZ4                     // compressed by LZ4 (pure-Go, see lz4.go)
+)
+
+func (c SpillCodec) String() string {
+	switch c {
+	case SpillPlain:
+		return "plain"
+	case SpillLZ4:
+		return "lz4"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(c))
+	}
+}
+
+var ErrChecksum = errors.New("etl: checksum mismatch")
+
+const (
+	spillMagic           = "ETLB"
+	spillTrailerMagic    = "ETLE"
+	spillFileHeaderSize  = 8
+	spillBlockHeaderSize = 12
+	spillTrailerSize     = 20
+)
+
+// blockWriter - splits stream to blocks, see SpillCodec
+type blockWriter struct {
+	w             io.Writer
+	codec         SpillCodec
+	headerWritten bool
+	buf           []byte // raw data of current block
+	out           []byte
+	table         *lz4Table
+
+	blocks uint32
+	rawLen uint64
+}
+
+func newBlockWriter(w io.Writer, codec SpillCodec) *blockWriter {
+	bw := &blockWriter{w: w, codec: codec, buf: make([]byte, 0, BufIOSize)}
+	if codec == SpillLZ4 {
+		bw.table = &lz4Table{}
+	}
+	return bw
+}
+
+func (w *blockWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		free := cap(w.buf) - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		p = p[free:]
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flushBlock(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close - writes current block and trailer. Doesn't flush underlying writer.
+func (w *blockWriter) Close() error {
+	if err := w.flushBlock(); err != nil {
+		return err
+	}
+	var trailer [spillTrailerSize]byte
+	copy(trailer[:], spillTrailerMagic)
+	binary.BigEndian.PutUint32(trailer[4:], w.blocks)
+	binary.BigEndian.PutUint64(trailer[8:], w.rawLen)
+	binary.BigEndian.PutUint32(trailer[16:], crc32.Checksum(trailer[:16], crc32c))
+	_, err := w.w.Write(trailer[:])
+	return err
+}
+
+func (w *blockWriter) flushBlock() error {
+	if !w.headerWritten {
+		var hdr [spillFileHeaderSize]byte
+		copy(hdr[:], spillMagic)
+		hdr[4] = byte(w.codec)
+		if _, err := w.w.Write(hdr[:]); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	payload := w.buf
+	if w.codec == SpillLZ4 {
+		w.out = lz4Compress(w.out[:0], w.buf, w.table)
+		payload = w.out
+	}
+	var hdr [spillBlockHeaderSize]byte
+	binary.BigEndian.PutUint32(hdr[0:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(w.buf)))
+	binary.BigEndian.PutUint32(hdr[8:], crc32.Checksum(payload, crc32c))
+	if _, err := w.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(payload); err != nil {
+		return err
+	}
+	w.blocks++
+	w.rawLen += uint64(len(w.buf))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// blockReader - reads stream written by blockWriter, verifies checksums. Codec is read from file header.
+type blockReader struct {
+	r        io.Reader
+	fileName string
+	codec    SpillCodec
+	offset   int64 // offset of next block in file, 0 - header not read yet
+	done     bool  // trailer is read and verified
+
+	blocks  uint32
+	rawLen  uint64
+	payload []byte
+	block   []byte // raw data of current block
+	pos     int
+}
+
+func newBlockReader(r io.Reader, fileName string) *blockReader {
+	return &blockReader{r: r, fileName: fileName}
+}
+
+func (r *blockReader) corrupted(offset int64, err error) error {
+	return fmt.Errorf("%s: block at offset %d: %w", r.fileName, offset, err)
+}
+
+func (r *blockReader) readHeader() error {
+	var hdr [spillFileHeaderSize]byte
+	if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+		return r.corrupted(0, fmt.Errorf("%w: %s", ErrChecksum, err))
+	}
+	if string(hdr[:4]) != spillMagic || SpillCodec(hdr[4]) > SpillLZ4 {
+		return r.corrupted(0, fmt.Errorf("%w: wrong file header %x", ErrChecksum, hdr))
+	}
+	r.codec = SpillCodec(hdr[4])
+	r.offset = spillFileHeaderSize
+	return nil
+}
+
+// readTrailer - `hdr` is beginning of trailer, read instead of block header
+func (r *blockReader) readTrailer(hdr []byte) error {
+	var trailer [spillTrailerSize]byte
+	copy(trailer[:], hdr)
+	if _, err := io.ReadFull(r.r, trailer[len(hdr):]); err != nil {
+		return r.corrupted(r.offset, fmt.Errorf("%w: %s", ErrChecksum, err))
+	}
+	if crc32.Checksum(trailer[:16], crc32c) != binary.BigEndian.Uint32(trailer[16:]) {
+		return r.corrupted(r.offset, fmt.Errorf("%w: trailer", ErrChecksum))
+	}
+	if blocks, rawLen := binary.BigEndian.Uint32(trailer[4:]), binary.BigEndian.Uint64(trailer[8:]); blocks != r.blocks || rawLen != r.rawLen {
+		return r.corrupted(r.offset, fmt.Errorf("%w: file has %d blocks (%d bytes), trailer expects %d blocks (%d bytes)", ErrChecksum, r.blocks, r.rawLen, blocks, rawLen))
+	}
+	var tail [1]byte
+	if n, _ := io.ReadFull(r.r, tail[:]); n != 0 {
+		return r.corrupted(r.offset, fmt.Errorf("%w: data after trailer", ErrChecksum))
+	}
+	r.done = true
+	return nil
+}
+
+func (r *blockReader) nextBlock() error {
+	if r.done {
+		return io.EOF
+	}
+	if r.offset == 0 {
+		if err := r.readHeader(); err != nil {
+			return err
+		}
+	}
+	offset := r.offset
+	var hdr [spillBlockHeaderSize]byte
+	if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+		return r.corrupted(offset, fmt.Errorf("%w: file is truncated: %s", ErrChecksum, err))
+	}
+	if string(hdr[:4]) == spillTrailerMagic {
+		if err := r.readTrailer(hdr[:]); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	payloadLen, rawLen := binary.BigEndian.Uint32(hdr[0:]), binary.BigEndian.Uint32(hdr[4:])
+	if rawLen > BufIOSize || payloadLen > 2*BufIOSize {
+		return r.corrupted(offset, fmt.Errorf("%w: wrong block size: %d, %d", ErrChecksum, payloadLen, rawLen))
+	}
+	if cap(r.payload) < int(payloadLen) {
+		r.payload = make([]byte, payloadLen)
+	}
+	r.payload = r.payload[:payloadLen]
+	if _, err := io.ReadFull(r.r, r.payload); err != nil {
+		return r.corrupted(offset, fmt.Errorf("%w: %s", ErrChecksum, err))
+	}
+	if crc32.Checksum(r.payload, crc32c) != binary.BigEndian.Uint32(hdr[8:]) {
+		return r.corrupted(offset, ErrChecksum)
+	}
+	r.offset += spillBlockHeaderSize + int64(payloadLen)
+
+	if r.codec == SpillPlain {
+		r.payload, r.block = r.block, r.payload // no copy: buffers are swapped
+	} else {
+		if cap(r.block) < int(rawLen) {
+			r.block = make([]byte, rawLen)
+		}
+		var err error
+		if r.block, err = lz4Decompress(r.block, r.payload, int(rawLen)); err != nil {
+			return r.corrupted(offset, err)
+		}
+	}
+	if len(r.block) != int(rawLen) {
+		return r.corrupted(offset, fmt.Errorf("%w: block size %d, expected %d", ErrChecksum, len(r.block), rawLen))
+	}
+	r.blocks++
+	r.rawLen += uint64(rawLen)
+	r.pos = 0
+	return nil
+}
+
+func (r *blockReader) Read(p []byte) (int, error) {
+	for r.pos == len(r.block) {
+		if err := r.nextBlock(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.block[r.pos:])
+	r.pos += n
+	return n, nil
+}
+
+func (r *blockReader) ReadByte() (byte, error) {
+	for r.pos == len(r.block) {
+		if err := r.nextBlock(); err != nil {
+			return 0, err
+		}
+	}
+	b := r.block[r.pos]
+	r.pos++
+	return b, nil
+}
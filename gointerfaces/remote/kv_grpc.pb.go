@@ -29,6 +29,10 @@ const (
 	KV_HistoryGet_FullMethodName   = "/remote.KV/HistoryGet"
 	KV_IndexRange_FullMethodName   = "/remote.KV/IndexRange"
 	KV_Range_FullMethodName        = "/remote.KV/Range"
+	KV_HistoryRange_FullMethodName = "/remote.KV/HistoryRange"
+	KV_DomainRange_FullMethodName  = "/remote.KV/DomainRange"
+	KV_TableStats_FullMethodName   = "/remote.KV/TableStats"
+	KV_DBSize_FullMethodName       = "/remote.KV/DBSize"
 )
 
 // KVClient is the client API for KV service.
@@ -55,6 +59,14 @@ type KVClient interface {
 	// Range(nil, to)   means [StartOfTable, to)
 	// If orderAscend=false server expecting `from`<`to`. Example: Range("B", "A")
 	Range(ctx context.Context, in *RangeReq, opts ...grpc.CallOption) (*Pairs, error)
+	// HistoryRange returns all keys changed in [from_ts, to_ts) with their values before the change
+	HistoryRange(ctx context.Context, in *HistoryRangeReq, opts ...grpc.CallOption) (*Pairs, error)
+	// DomainRange returns state of keys in [from_key, to_key) as of ts
+	DomainRange(ctx context.Context, in *DomainRangeReq, opts ...grpc.CallOption) (*Pairs, error)
+	// TableStats - space usage of table, as seen by given transaction
+	TableStats(ctx context.Context, in *TableStatsReq, opts ...grpc.CallOption) (*TableStatsReply, error)
+	// DBSize - size of database file, as seen by given transaction
+	DBSize(ctx context.Context, in *DBSizeReq, opts ...grpc.CallOption) (*DBSizeReply, error)
 }
 
 type kVClient struct {
@@ -182,6 +194,42 @@ func (c *kVClient) Range(ctx context.Context, in *RangeReq, opts ...grpc.CallOpt
 	return out, nil
 }
 
+func (c *kVClient) HistoryRange(ctx context.Context, in *HistoryRangeReq, opts ...grpc.CallOption) (*Pairs, error) {
+	out := new(Pairs)
+	err := c.cc.Invoke(ctx, KV_HistoryRange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVClient) DomainRange(ctx context.Context, in *DomainRangeReq, opts ...grpc.CallOption) (*Pairs, error) {
+	out := new(Pairs)
+	err := c.cc.Invoke(ctx, KV_DomainRange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVClient) TableStats(ctx context.Context, in *TableStatsReq, opts ...grpc.CallOption) (*TableStatsReply, error) {
+	out := new(TableStatsReply)
+	err := c.cc.Invoke(ctx, KV_TableStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVClient) DBSize(ctx context.Context, in *DBSizeReq, opts ...grpc.CallOption) (*DBSizeReply, error) {
+	out := new(DBSizeReply)
+	err := c.cc.Invoke(ctx, KV_DBSize_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KVServer is the server API for KV service.
 // All implementations must embed UnimplementedKVServer
 // for forward compatibility
@@ -206,6 +254,14 @@ type KVServer interface {
 	// Range(nil, to)   means [StartOfTable, to)
 	// If orderAscend=false server expecting `from`<`to`. Example: Range("B", "A")
 	Range(context.Context, *RangeReq) (*Pairs, error)
+	// HistoryRange returns all keys changed in [from_ts, to_ts) with their values before the change
+	HistoryRange(context.Context, *HistoryRangeReq) (*Pairs, error)
+	// DomainRange returns state of keys in [from_key, to_key) as of ts
+	DomainRange(context.Context, *DomainRangeReq) (*Pairs, error)
+	// TableStats - space usage of table, as seen by given transaction
+	TableStats(context.Context, *TableStatsReq) (*TableStatsReply, error)
+	// DBSize - size of database file, as seen by given transaction
+	DBSize(context.Context, *DBSizeReq) (*DBSizeReply, error)
 	mustEmbedUnimplementedKVServer()
 }
 
@@ -237,6 +293,18 @@ func (UnimplementedKVServer) IndexRange(context.Context, *IndexRangeReq) (*Index
 func (UnimplementedKVServer) Range(context.Context, *RangeReq) (*Pairs, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Range not implemented")
 }
+func (UnimplementedKVServer) HistoryRange(context.Context, *HistoryRangeReq) (*Pairs, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HistoryRange not implemented")
+}
+func (UnimplementedKVServer) DomainRange(context.Context, *DomainRangeReq) (*Pairs, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DomainRange not implemented")
+}
+func (UnimplementedKVServer) TableStats(context.Context, *TableStatsReq) (*TableStatsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TableStats not implemented")
+}
+func (UnimplementedKVServer) DBSize(context.Context, *DBSizeReq) (*DBSizeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DBSize not implemented")
+}
 func (UnimplementedKVServer) mustEmbedUnimplementedKVServer() {}
 
 // UnsafeKVServer may be embedded to opt out of forward compatibility for this service.
@@ -405,6 +473,78 @@ func _KV_Range_Handler(srv interface{}, ctx context.Context, dec func(interface{
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KV_HistoryRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRangeReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServer).HistoryRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KV_HistoryRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServer).HistoryRange(ctx, req.(*HistoryRangeReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KV_DomainRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DomainRangeReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServer).DomainRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KV_DomainRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServer).DomainRange(ctx, req.(*DomainRangeReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KV_TableStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TableStatsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServer).TableStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KV_TableStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServer).TableStats(ctx, req.(*TableStatsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KV_DBSize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DBSizeReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServer).DBSize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KV_DBSize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServer).DBSize(ctx, req.(*DBSizeReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // KV_ServiceDesc is the grpc.ServiceDesc for KV service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -436,6 +576,22 @@ var KV_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Range",
 			Handler:    _KV_Range_Handler,
 		},
+		{
+			MethodName: "HistoryRange",
+			Handler:    _KV_HistoryRange_Handler,
+		},
+		{
+			MethodName: "DomainRange",
+			Handler:    _KV_DomainRange_Handler,
+		},
+		{
+			MethodName: "TableStats",
+			Handler:    _KV_TableStats_Handler,
+		},
+		{
+			MethodName: "DBSize",
+			Handler:    _KV_DBSize_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -22,12 +22,21 @@ var _ KVClient = &KVClientMock{}
 //
 //		// make and configure a mocked KVClient
 //		mockedKVClient := &KVClientMock{
+//			DBSizeFunc: func(ctx context.Context, in *DBSizeReq, opts ...grpc.CallOption) (*DBSizeReply, error) {
+//				panic("mock out the DBSize method")
+//			},
 //			DomainGetFunc: func(ctx context.Context, in *DomainGetReq, opts ...grpc.CallOption) (*DomainGetReply, error) {
 //				panic("mock out the DomainGet method")
 //			},
+//			DomainRangeFunc: func(ctx context.Context, in *DomainRangeReq, opts ...grpc.CallOption) (*Pairs, error) {
+//				panic("mock out the DomainRange method")
+//			},
 //			HistoryGetFunc: func(ctx context.Context, in *HistoryGetReq, opts ...grpc.CallOption) (*HistoryGetReply, error) {
 //				panic("mock out the HistoryGet method")
 //			},
+//			HistoryRangeFunc: func(ctx context.Context, in *HistoryRangeReq, opts ...grpc.CallOption) (*Pairs, error) {
+//				panic("mock out the HistoryRange method")
+//			},
 //			IndexRangeFunc: func(ctx context.Context, in *IndexRangeReq, opts ...grpc.CallOption) (*IndexRangeReply, error) {
 //				panic("mock out the IndexRange method")
 //			},
@@ -40,6 +49,9 @@ var _ KVClient = &KVClientMock{}
 //			StateChangesFunc: func(ctx context.Context, in *StateChangeRequest, opts ...grpc.CallOption) (KV_StateChangesClient, error) {
 //				panic("mock out the StateChanges method")
 //			},
+//			TableStatsFunc: func(ctx context.Context, in *TableStatsReq, opts ...grpc.CallOption) (*TableStatsReply, error) {
+//				panic("mock out the TableStats method")
+//			},
 //			TxFunc: func(ctx context.Context, opts ...grpc.CallOption) (KV_TxClient, error) {
 //				panic("mock out the Tx method")
 //			},
@@ -53,12 +65,21 @@ var _ KVClient = &KVClientMock{}
 //
 //	}
 type KVClientMock struct {
+	// DBSizeFunc mocks the DBSize method.
+	DBSizeFunc func(ctx context.Context, in *DBSizeReq, opts ...grpc.CallOption) (*DBSizeReply, error)
+
 	// DomainGetFunc mocks the DomainGet method.
 	DomainGetFunc func(ctx context.Context, in *DomainGetReq, opts ...grpc.CallOption) (*DomainGetReply, error)
 
+	// DomainRangeFunc mocks the DomainRange method.
+	DomainRangeFunc func(ctx context.Context, in *DomainRangeReq, opts ...grpc.CallOption) (*Pairs, error)
+
 	// HistoryGetFunc mocks the HistoryGet method.
 	HistoryGetFunc func(ctx context.Context, in *HistoryGetReq, opts ...grpc.CallOption) (*HistoryGetReply, error)
 
+	// HistoryRangeFunc mocks the HistoryRange method.
+	HistoryRangeFunc func(ctx context.Context, in *HistoryRangeReq, opts ...grpc.CallOption) (*Pairs, error)
+
 	// IndexRangeFunc mocks the IndexRange method.
 	IndexRangeFunc func(ctx context.Context, in *IndexRangeReq, opts ...grpc.CallOption) (*IndexRangeReply, error)
 
@@ -71,6 +92,9 @@ type KVClientMock struct {
 	// StateChangesFunc mocks the StateChanges method.
 	StateChangesFunc func(ctx context.Context, in *StateChangeRequest, opts ...grpc.CallOption) (KV_StateChangesClient, error)
 
+	// TableStatsFunc mocks the TableStats method.
+	TableStatsFunc func(ctx context.Context, in *TableStatsReq, opts ...grpc.CallOption) (*TableStatsReply, error)
+
 	// TxFunc mocks the Tx method.
 	TxFunc func(ctx context.Context, opts ...grpc.CallOption) (KV_TxClient, error)
 
@@ -79,6 +103,15 @@ type KVClientMock struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// DBSize holds details about calls to the DBSize method.
+		DBSize []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// In is the in argument value.
+			In *DBSizeReq
+			// Opts is the opts argument value.
+			Opts []grpc.CallOption
+		}
 		// DomainGet holds details about calls to the DomainGet method.
 		DomainGet []struct {
 			// Ctx is the ctx argument value.
@@ -88,6 +121,15 @@ type KVClientMock struct {
 			// Opts is the opts argument value.
 			Opts []grpc.CallOption
 		}
+		// DomainRange holds details about calls to the DomainRange method.
+		DomainRange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// In is the in argument value.
+			In *DomainRangeReq
+			// Opts is the opts argument value.
+			Opts []grpc.CallOption
+		}
 		// HistoryGet holds details about calls to the HistoryGet method.
 		HistoryGet []struct {
 			// Ctx is the ctx argument value.
@@ -97,6 +139,15 @@ type KVClientMock struct {
 			// Opts is the opts argument value.
 			Opts []grpc.CallOption
 		}
+		// HistoryRange holds details about calls to the HistoryRange method.
+		HistoryRange []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// In is the in argument value.
+			In *HistoryRangeReq
+			// Opts is the opts argument value.
+			Opts []grpc.CallOption
+		}
 		// IndexRange holds details about calls to the IndexRange method.
 		IndexRange []struct {
 			// Ctx is the ctx argument value.
@@ -133,6 +184,15 @@ type KVClientMock struct {
 			// Opts is the opts argument value.
 			Opts []grpc.CallOption
 		}
+		// TableStats holds details about calls to the TableStats method.
+		TableStats []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// In is the in argument value.
+			In *TableStatsReq
+			// Opts is the opts argument value.
+			Opts []grpc.CallOption
+		}
 		// Tx holds details about calls to the Tx method.
 		Tx []struct {
 			// Ctx is the ctx argument value.
@@ -150,16 +210,64 @@ type KVClientMock struct {
 			Opts []grpc.CallOption
 		}
 	}
+	lockDBSize       sync.RWMutex
 	lockDomainGet    sync.RWMutex
+	lockDomainRange  sync.RWMutex
 	lockHistoryGet   sync.RWMutex
+	lockHistoryRange sync.RWMutex
 	lockIndexRange   sync.RWMutex
 	lockRange        sync.RWMutex
 	lockSnapshots    sync.RWMutex
 	lockStateChanges sync.RWMutex
+	lockTableStats   sync.RWMutex
 	lockTx           sync.RWMutex
 	lockVersion      sync.RWMutex
 }
 
+// DBSize calls DBSizeFunc.
+func (mock *KVClientMock) DBSize(ctx context.Context, in *DBSizeReq, opts ...grpc.CallOption) (*DBSizeReply, error) {
+	callInfo := struct {
+		Ctx  context.Context
+		In   *DBSizeReq
+		Opts []grpc.CallOption
+	}{
+		Ctx:  ctx,
+		In:   in,
+		Opts: opts,
+	}
+	mock.lockDBSize.Lock()
+	mock.calls.DBSize = append(mock.calls.DBSize, callInfo)
+	mock.lockDBSize.Unlock()
+	if mock.DBSizeFunc == nil {
+		var (
+			dBSizeReplyOut *DBSizeReply
+			errOut         error
+		)
+		return dBSizeReplyOut, errOut
+	}
+	return mock.DBSizeFunc(ctx, in, opts...)
+}
+
+// DBSizeCalls gets all the calls that were made to DBSize.
+// Check the length with:
+//
+//	len(mockedKVClient.DBSizeCalls())
+func (mock *KVClientMock) DBSizeCalls() []struct {
+	Ctx  context.Context
+	In   *DBSizeReq
+	Opts []grpc.CallOption
+} {
+	var calls []struct {
+		Ctx  context.Context
+		In   *DBSizeReq
+		Opts []grpc.CallOption
+	}
+	mock.lockDBSize.RLock()
+	calls = mock.calls.DBSize
+	mock.lockDBSize.RUnlock()
+	return calls
+}
+
 // DomainGet calls DomainGetFunc.
 func (mock *KVClientMock) DomainGet(ctx context.Context, in *DomainGetReq, opts ...grpc.CallOption) (*DomainGetReply, error) {
 	callInfo := struct {
@@ -204,6 +312,50 @@ func (mock *KVClientMock) DomainGetCalls() []struct {
 	return calls
 }
 
+// DomainRange calls DomainRangeFunc.
+func (mock *KVClientMock) DomainRange(ctx context.Context, in *DomainRangeReq, opts ...grpc.CallOption) (*Pairs, error) {
+	callInfo := struct {
+		Ctx  context.Context
+		In   *DomainRangeReq
+		Opts []grpc.CallOption
+	}{
+		Ctx:  ctx,
+		In:   in,
+		Opts: opts,
+	}
+	mock.lockDomainRange.Lock()
+	mock.calls.DomainRange = append(mock.calls.DomainRange, callInfo)
+	mock.lockDomainRange.Unlock()
+	if mock.DomainRangeFunc == nil {
+		var (
+			pairsOut *Pairs
+			errOut   error
+		)
+		return pairsOut, errOut
+	}
+	return mock.DomainRangeFunc(ctx, in, opts...)
+}
+
+// DomainRangeCalls gets all the calls that were made to DomainRange.
+// Check the length with:
+//
+//	len(mockedKVClient.DomainRangeCalls())
+func (mock *KVClientMock) DomainRangeCalls() []struct {
+	Ctx  context.Context
+	In   *DomainRangeReq
+	Opts []grpc.CallOption
+} {
+	var calls []struct {
+		Ctx  context.Context
+		In   *DomainRangeReq
+		Opts []grpc.CallOption
+	}
+	mock.lockDomainRange.RLock()
+	calls = mock.calls.DomainRange
+	mock.lockDomainRange.RUnlock()
+	return calls
+}
+
 // HistoryGet calls HistoryGetFunc.
 func (mock *KVClientMock) HistoryGet(ctx context.Context, in *HistoryGetReq, opts ...grpc.CallOption) (*HistoryGetReply, error) {
 	callInfo := struct {
@@ -248,6 +400,50 @@ func (mock *KVClientMock) HistoryGetCalls() []struct {
 	return calls
 }
 
+// HistoryRange calls HistoryRangeFunc.
+func (mock *KVClientMock) HistoryRange(ctx context.Context, in *HistoryRangeReq, opts ...grpc.CallOption) (*Pairs, error) {
+	callInfo := struct {
+		Ctx  context.Context
+		In   *HistoryRangeReq
+		Opts []grpc.CallOption
+	}{
+		Ctx:  ctx,
+		In:   in,
+		Opts: opts,
+	}
+	mock.lockHistoryRange.Lock()
+	mock.calls.HistoryRange = append(mock.calls.HistoryRange, callInfo)
+	mock.lockHistoryRange.Unlock()
+	if mock.HistoryRangeFunc == nil {
+		var (
+			pairsOut *Pairs
+			errOut   error
+		)
+		return pairsOut, errOut
+	}
+	return mock.HistoryRangeFunc(ctx, in, opts...)
+}
+
+// HistoryRangeCalls gets all the calls that were made to HistoryRange.
+// Check the length with:
+//
+//	len(mockedKVClient.HistoryRangeCalls())
+func (mock *KVClientMock) HistoryRangeCalls() []struct {
+	Ctx  context.Context
+	In   *HistoryRangeReq
+	Opts []grpc.CallOption
+} {
+	var calls []struct {
+		Ctx  context.Context
+		In   *HistoryRangeReq
+		Opts []grpc.CallOption
+	}
+	mock.lockHistoryRange.RLock()
+	calls = mock.calls.HistoryRange
+	mock.lockHistoryRange.RUnlock()
+	return calls
+}
+
 // IndexRange calls IndexRangeFunc.
 func (mock *KVClientMock) IndexRange(ctx context.Context, in *IndexRangeReq, opts ...grpc.CallOption) (*IndexRangeReply, error) {
 	callInfo := struct {
@@ -424,6 +620,50 @@ func (mock *KVClientMock) StateChangesCalls() []struct {
 	return calls
 }
 
+// TableStats calls TableStatsFunc.
+func (mock *KVClientMock) TableStats(ctx context.Context, in *TableStatsReq, opts ...grpc.CallOption) (*TableStatsReply, error) {
+	callInfo := struct {
+		Ctx  context.Context
+		In   *TableStatsReq
+		Opts []grpc.CallOption
+	}{
+		Ctx:  ctx,
+		In:   in,
+		Opts: opts,
+	}
+	mock.lockTableStats.Lock()
+	mock.calls.TableStats = append(mock.calls.TableStats, callInfo)
+	mock.lockTableStats.Unlock()
+	if mock.TableStatsFunc == nil {
+		var (
+			tableStatsReplyOut *TableStatsReply
+			errOut             error
+		)
+		return tableStatsReplyOut, errOut
+	}
+	return mock.TableStatsFunc(ctx, in, opts...)
+}
+
+// TableStatsCalls gets all the calls that were made to TableStats.
+// Check the length with:
+//
+//	len(mockedKVClient.TableStatsCalls())
+func (mock *KVClientMock) TableStatsCalls() []struct {
+	Ctx  context.Context
+	In   *TableStatsReq
+	Opts []grpc.CallOption
+} {
+	var calls []struct {
+		Ctx  context.Context
+		In   *TableStatsReq
+		Opts []grpc.CallOption
+	}
+	mock.lockTableStats.RLock()
+	calls = mock.calls.TableStats
+	mock.lockTableStats.RUnlock()
+	return calls
+}
+
 // Tx calls TxFunc.
 func (mock *KVClientMock) Tx(ctx context.Context, opts ...grpc.CallOption) (KV_TxClient, error) {
 	callInfo := struct {
@@ -41,6 +41,17 @@ func EnsureVersion(local Version, remote *types.VersionReply) bool {
 	return true
 }
 
+// Less - `v` is older than `o`
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
 func (v Version) String() string {
 	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
 }
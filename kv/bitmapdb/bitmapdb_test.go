@@ -17,10 +17,15 @@
 package bitmapdb_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/RoaringBitmap/roaring"
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
+	"github.com/ledgerwatch/erigon-lib/kv/iter"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/stretchr/testify/require"
 )
 
@@ -69,3 +74,77 @@ func TestCutLeft(t *testing.T) {
 	require.True(t, lft == nil)
 	require.True(t, bm.GetCardinality() == 0)
 }
+
+func TestIndex(t *testing.T) {
+	for _, idx := range []*bitmapdb.Index{bitmapdb.NewIndex(kv.CallFromIndex), bitmapdb.NewIndex64(kv.CallToIndex)} {
+		idx := idx.WithChunkLimit(128)
+		_, tx := memdb.NewTestTx(t)
+		require := require.New(t)
+		key, otherKey := []byte("key"), []byte("kez")
+		chunks := func() (n int) {
+			require.NoError(tx.ForPrefix(idx.Table(), key, func(k, v []byte) error {
+				n++
+				return nil
+			}))
+			return n
+		}
+		rangeArr := func(key []byte, from, to uint64) []uint64 {
+			it, err := idx.Range(tx, key, from, to)
+			require.NoError(err)
+			defer it.Close()
+			res, err := iter.ToU64Arr(it)
+			require.NoError(err)
+			return res
+		}
+
+		expect := roaring64.New()
+		for batch := uint64(10); batch > 0; batch-- { // inserts into middle of existing chunks
+			var values []uint64
+			for i := batch * 100; i > (batch-1)*100; i-- {
+				values = append(values, i*3)
+			}
+			expect.AddMany(values)
+			require.NoError(idx.Add(tx, key, values...))
+		}
+		require.NoError(idx.Add(tx, otherKey, 1, 2, 3))
+		require.Greater(chunks(), 1)
+		require.Equal(expect.ToArray(), rangeArr(key, 0, math.MaxUint64))
+		require.Equal([]uint64{1, 2, 3}, rangeArr(otherKey, 0, math.MaxUint64))
+		cnt, err := idx.Cardinality(tx, key)
+		require.NoError(err)
+		require.Equal(uint64(1000), cnt)
+
+		require.Equal([]uint64{102, 105, 108}, rangeArr(key, 100, 109))
+		require.Equal([]uint64{2997, 3000}, rangeArr(key, 2995, math.MaxUint64))
+		require.Nil(rangeArr(key, 3001, math.MaxUint64))
+		require.Nil(rangeArr(key, 100, 100))
+
+		if idx.Table() == kv.CallFromIndex { // format is compatible with Get
+			bm, err := bitmapdb.Get(tx, kv.CallFromIndex, key, 0, bitmapdb.MaxUint32)
+			require.NoError(err)
+			require.Equal(expect.GetCardinality(), bm.GetCardinality())
+			require.Error(idx.Add(tx, key, bitmapdb.MaxUint32+1))
+		}
+
+		var removed []uint64
+		for i := uint64(0); i < 1500; i++ {
+			removed = append(removed, i)
+		}
+		expect.RemoveRange(0, 1500)
+		chunksBefore := chunks()
+		require.NoError(idx.Remove(tx, key, removed...))
+		require.Equal(expect.ToArray(), rangeArr(key, 0, math.MaxUint64))
+		require.Less(chunks(), chunksBefore)
+
+		require.NoError(idx.Remove(tx, key, expect.ToArray()...))
+		require.Nil(rangeArr(key, 0, math.MaxUint64))
+		require.Equal(0, chunks())
+		cnt, err = idx.Cardinality(tx, key)
+		require.NoError(err)
+		require.Equal(uint64(0), cnt)
+		require.Equal([]uint64{1, 2, 3}, rangeArr(otherKey, 0, math.MaxUint64))
+
+		require.NoError(idx.Add(tx, key, 7))
+		require.Equal([]uint64{7}, rangeArr(key, 0, math.MaxUint64))
+	}
+}
@@ -20,17 +20,25 @@ import (
 	"bufio"
 	"encoding/binary"
 	"fmt"
+	"math/bits"
 	"os"
 	"path/filepath"
 	"reflect"
 	"time"
 	"unsafe"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/c2h5oh/datasize"
 	mmap2 "github.com/edsrzf/mmap-go"
 	"github.com/ledgerwatch/log/v3"
 )
 
+// FixedSizeBitmaps - file of bitmaps of `bitsPerBitmap` bits, one per item. Layout:
+//
+//	header (MetaHeaderSize bytes): version_u8, amount_u64, bitsPerBitmap_u64 (since version 2)
+//	data: bitmap of item N (N < amount) starts at bit N*bitsPerBitmap
+//
+// File can grow: see OpenFixedSizeBitmapsAppender. Reader sees only items which existed when it was opened.
 type FixedSizeBitmaps struct {
 	f                  *os.File
 	filePath, fileName string
@@ -46,6 +54,7 @@ type FixedSizeBitmaps struct {
 	modTime       time.Time
 }
 
+// OpenFixedSizeBitmaps - `bitsPerBitmap` is used only for files of version 1: since version 2 it's read from header
 func OpenFixedSizeBitmaps(filePath string, bitsPerBitmap int) (*FixedSizeBitmaps, error) {
 	_, fName := filepath.Split(filePath)
 	idx := &FixedSizeBitmaps{
@@ -74,12 +83,17 @@ func OpenFixedSizeBitmaps(filePath string, bitsPerBitmap int) (*FixedSizeBitmaps
 
 	idx.version = idx.metaData[0]
 	idx.amount = binary.BigEndian.Uint64(idx.metaData[1 : 8+1])
+	if idx.version >= 2 {
+		idx.bitsPerBitmap = int(binary.BigEndian.Uint64(idx.metaData[9 : 9+8]))
+	}
 
 	return idx, nil
 }
 
-func (bm *FixedSizeBitmaps) FileName() string { return bm.fileName }
-func (bm *FixedSizeBitmaps) FilePath() string { return bm.filePath }
+func (bm *FixedSizeBitmaps) FileName() string   { return bm.fileName }
+func (bm *FixedSizeBitmaps) FilePath() string   { return bm.filePath }
+func (bm *FixedSizeBitmaps) Amount() uint64     { return bm.amount }
+func (bm *FixedSizeBitmaps) BitsPerBitmap() int { return bm.bitsPerBitmap }
 func (bm *FixedSizeBitmaps) Close() error {
 	if bm.m != nil {
 		if err := bm.m.Unmap(); err != nil {
@@ -95,8 +109,8 @@ func (bm *FixedSizeBitmaps) Close() error {
 }
 
 func (bm *FixedSizeBitmaps) At(item uint64) (res []uint64, err error) {
-	if item > bm.amount {
-		return nil, fmt.Errorf("too big item number: %d > %d", item, bm.amount)
+	if item >= bm.amount {
+		return nil, fmt.Errorf("too big item number: %d >= %d", item, bm.amount)
 	}
 
 	n := bm.bitsPerBitmap * int(item)
@@ -122,8 +136,8 @@ func (bm *FixedSizeBitmaps) At(item uint64) (res []uint64, err error) {
 }
 
 func (bm *FixedSizeBitmaps) First2At(item, after uint64) (fst uint64, snd uint64, ok, ok2 bool, err error) {
-	if item > bm.amount {
-		return 0, 0, false, false, fmt.Errorf("too big item number: %d > %d", item, bm.amount)
+	if item >= bm.amount {
+		return 0, 0, false, false, fmt.Errorf("too big item number: %d >= %d", item, bm.amount)
 	}
 	n := bm.bitsPerBitmap * int(item)
 	blkFrom, bitFrom := n/64, n%64
@@ -156,6 +170,99 @@ func (bm *FixedSizeBitmaps) First2At(item, after uint64) (fst uint64, snd uint64
 	return
 }
 
+// checkRange - items [from, to) must exist
+func (bm *FixedSizeBitmaps) checkRange(from, to uint64) error {
+	if from > to {
+		return fmt.Errorf("wrong range: %d > %d", from, to)
+	}
+	if to > bm.amount {
+		return fmt.Errorf("too big item number: %d >= %d", to-1, bm.amount)
+	}
+	return nil
+}
+
+// RangeWithBit - items in [from, to) whose bitmap contains `bit`
+func (bm *FixedSizeBitmaps) RangeWithBit(from, to, bit uint64) (*roaring64.Bitmap, error) {
+	if err := bm.checkRange(from, to); err != nil {
+		return nil, err
+	}
+	if bit >= uint64(bm.bitsPerBitmap) {
+		return nil, fmt.Errorf("too big bit: %d >= %d", bit, bm.bitsPerBitmap)
+	}
+	res := roaring64.New()
+	for item := from; item < to; item++ {
+		n := item*uint64(bm.bitsPerBitmap) + bit
+		if blk := n / 64; blk < uint64(len(bm.data)) && bm.data[blk]&(1<<(n%64)) != 0 {
+			res.Add(item)
+		}
+	}
+	return res, nil
+}
+
+// OrRange - values which are set in bitmap of any item in [from, to)
+func (bm *FixedSizeBitmaps) OrRange(from, to uint64) ([]uint64, error) {
+	if err := bm.checkRange(from, to); err != nil {
+		return nil, err
+	}
+	acc, words := make([]uint64, (bm.bitsPerBitmap+63)/64), make([]uint64, (bm.bitsPerBitmap+63)/64)
+	for item := from; item < to; item++ {
+		bm.itemWords(item, words)
+		for i := range acc {
+			acc[i] |= words[i]
+		}
+	}
+	return wordsToValues(acc), nil
+}
+
+// AndRange - values which are set in bitmaps of all items in [from, to). Empty range has no values.
+func (bm *FixedSizeBitmaps) AndRange(from, to uint64) ([]uint64, error) {
+	if err := bm.checkRange(from, to); err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, nil
+	}
+	acc, words := make([]uint64, (bm.bitsPerBitmap+63)/64), make([]uint64, (bm.bitsPerBitmap+63)/64)
+	bm.itemWords(from, acc)
+	for item := from + 1; item < to; item++ {
+		bm.itemWords(item, words)
+		for i := range acc {
+			acc[i] &= words[i]
+		}
+	}
+	return wordsToValues(acc), nil
+}
+
+// itemWords - bitmap of `item` shifted to beginning of `dst`: bit 0 of dst[0] is value 0
+func (bm *FixedSizeBitmaps) itemWords(item uint64, dst []uint64) {
+	bitsPerBitmap := uint64(bm.bitsPerBitmap)
+	n := item * bitsPerBitmap
+	for i := range dst {
+		pos := n + uint64(i)*64
+		blk, off := pos/64, pos%64
+		var w uint64
+		if blk < uint64(len(bm.data)) {
+			w = bm.data[blk] >> off
+		}
+		if off > 0 && blk+1 < uint64(len(bm.data)) {
+			w |= bm.data[blk+1] << (64 - off)
+		}
+		if left := bitsPerBitmap - uint64(i)*64; left < 64 {
+			w &= 1<<left - 1
+		}
+		dst[i] = w
+	}
+}
+
+func wordsToValues(words []uint64) (res []uint64) {
+	for i, w := range words {
+		for ; w != 0; w &= w - 1 {
+			res = append(res, uint64(i*64+bits.TrailingZeros64(w)))
+		}
+	}
+	return res
+}
+
 type FixedSizeBitmapsWriter struct {
 	f *os.File
 
@@ -168,6 +275,7 @@ type FixedSizeBitmapsWriter struct {
 	amount        uint64
 	size          int
 	bitsPerBitmap uint64
+	appendMode    bool // file opened by OpenFixedSizeBitmapsAppender: Build doesn't rename file
 }
 
 const MetaHeaderSize = 64
@@ -183,7 +291,7 @@ func NewFixedSizeBitmapsWriter(indexFile string, bitsPerBitmap int, amount uint6
 		bitsPerBitmap:  uint64(bitsPerBitmap),
 		size:           size,
 		amount:         amount,
-		version:        1,
+		version:        2,
 	}
 
 	_ = os.Remove(idx.tmpIdxFilePath)
@@ -210,10 +318,109 @@ func NewFixedSizeBitmapsWriter(indexFile string, bitsPerBitmap int, amount uint6
 	//}
 	idx.metaData[0] = idx.version
 	binary.BigEndian.PutUint64(idx.metaData[1:], idx.amount)
+	binary.BigEndian.PutUint64(idx.metaData[9:], idx.bitsPerBitmap)
 	idx.amount = binary.BigEndian.Uint64(idx.metaData[1 : 8+1])
 
 	return idx, nil
 }
+
+// OpenFixedSizeBitmapsAppender - opens existing file to add new items to the end of it by AppendArray.
+// File is grown in place (no rebuild), new items are visible for readers opened after Flush or Build.
+// `bitsPerBitmap` is used only for files of version 1: since version 2 it's read from header.
+func OpenFixedSizeBitmapsAppender(indexFile string, bitsPerBitmap int) (*FixedSizeBitmapsWriter, error) {
+	f, err := os.OpenFile(indexFile, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("OpenFile: %w", err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if stat.Size() < MetaHeaderSize {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: file is too small: %d", indexFile, stat.Size())
+	}
+	w := &FixedSizeBitmapsWriter{
+		f:             f,
+		indexFile:     indexFile,
+		bitsPerBitmap: uint64(bitsPerBitmap),
+		size:          int(stat.Size()),
+		appendMode:    true,
+	}
+	if err := w.mmap(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	w.version = w.metaData[0]
+	w.amount = binary.BigEndian.Uint64(w.metaData[1 : 8+1])
+	if w.version >= 2 {
+		w.bitsPerBitmap = binary.BigEndian.Uint64(w.metaData[9 : 9+8])
+	}
+	return w, nil
+}
+
+func (w *FixedSizeBitmapsWriter) mmap() (err error) {
+	w.m, err = mmap2.MapRegion(w.f, w.size, mmap2.RDWR, 0, 0)
+	if err != nil {
+		return err
+	}
+	w.metaData = w.m[:MetaHeaderSize]
+	w.data = castToArrU64(w.m[MetaHeaderSize:])
+	return nil
+}
+
+// grow - makes file big enough for `item`. Size is doubled: appending of 1 item per block must not remap file every block.
+func (w *FixedSizeBitmapsWriter) grow(item uint64) error {
+	need := MetaHeaderSize + int(((item+1)*w.bitsPerBitmap+63)/64*8)
+	if need <= w.size {
+		return nil
+	}
+	pageSize := os.Getpagesize()
+	size := 2 * w.size
+	for size < need {
+		size *= 2
+	}
+	size = (size + pageSize - 1) / pageSize * pageSize
+	if err := w.m.Unmap(); err != nil {
+		return err
+	}
+	w.m = nil
+	if err := w.f.Truncate(int64(size)); err != nil {
+		return err
+	}
+	w.size = size
+	return w.mmap()
+}
+
+// AppendArray - adds new item to the end of file, returns its number
+func (w *FixedSizeBitmapsWriter) AppendArray(listOfValues []uint64) (item uint64, err error) {
+	item = w.amount
+	if err := w.grow(item); err != nil {
+		return 0, err
+	}
+	for v := uint64(0); v < w.bitsPerBitmap; v++ { // slot may be dirty: appended before crash, but header was not flushed
+		n := item*w.bitsPerBitmap + v
+		w.data[n/64] &^= 1 << (n % 64)
+	}
+	w.amount++
+	if err := w.AddArray(item, listOfValues); err != nil {
+		w.amount--
+		return 0, err
+	}
+	return item, nil
+}
+
+// Flush - makes appended items durable and visible for new readers: header is updated only after data is synced
+func (w *FixedSizeBitmapsWriter) Flush() error {
+	if err := w.m.Flush(); err != nil {
+		return err
+	}
+	w.metaData[0] = 2
+	binary.BigEndian.PutUint64(w.metaData[1:], w.amount)
+	binary.BigEndian.PutUint64(w.metaData[9:], w.bitsPerBitmap)
+	return w.m.Flush()
+}
 func (w *FixedSizeBitmapsWriter) Close() {
 	_ = w.m.Unmap()
 	_ = w.f.Close()
@@ -245,17 +452,17 @@ func castToArrU64(in []byte) []uint64 {
 }
 
 func (w *FixedSizeBitmapsWriter) AddArray(item uint64, listOfValues []uint64) error {
-	if item > w.amount {
-		return fmt.Errorf("too big item number: %d > %d", item, w.amount)
+	if item >= w.amount {
+		return fmt.Errorf("too big item number: %d >= %d", item, w.amount)
 	}
 	offset := item * w.bitsPerBitmap
 	for _, v := range listOfValues {
-		if v > w.bitsPerBitmap {
-			return fmt.Errorf("too big value: %d > %d", v, w.bitsPerBitmap)
+		if v >= w.bitsPerBitmap {
+			return fmt.Errorf("too big value: %d >= %d", v, w.bitsPerBitmap)
 		}
 		n := offset + v
 		blkAt, bitAt := int(n/64), int(n%64)
-		if blkAt > len(w.data) {
+		if blkAt >= len(w.data) {
 			return fmt.Errorf("too big value: %d, %d, max: %d", item, listOfValues, len(w.data))
 		}
 		w.data[blkAt] |= (1 << bitAt)
@@ -264,7 +471,11 @@ func (w *FixedSizeBitmapsWriter) AddArray(item uint64, listOfValues []uint64) er
 }
 
 func (w *FixedSizeBitmapsWriter) Build() error {
-	if err := w.m.Flush(); err != nil {
+	if w.appendMode {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	} else if err := w.m.Flush(); err != nil {
 		return err
 	}
 	if err := w.f.Sync(); err != nil {
@@ -280,6 +491,9 @@ func (w *FixedSizeBitmapsWriter) Build() error {
 		return err
 	}
 	w.f = nil
+	if w.appendMode {
+		return nil
+	}
 
 	_ = os.Remove(w.indexFile)
 	if err := os.Rename(w.tmpIdxFilePath, w.indexFile); err != nil {
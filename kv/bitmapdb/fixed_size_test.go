@@ -29,7 +29,7 @@ func TestFixedSizeBitmaps(t *testing.T) {
 	tmpDir, require := t.TempDir(), require.New(t)
 	must := require.NoError
 	idxPath := filepath.Join(tmpDir, "idx.tmp")
-	wr, err := NewFixedSizeBitmapsWriter(idxPath, 14, 7)
+	wr, err := NewFixedSizeBitmapsWriter(idxPath, 14, 8)
 	require.NoError(err)
 	defer wr.Close()
 
@@ -105,3 +105,119 @@ func TestPageAlined(t *testing.T) {
 	require.Equal((128/8*1000/os.Getpagesize()+1)*os.Getpagesize(), bm3.size)
 	defer bm3.Close()
 }
+
+func TestFixedSizeBitmapsAppend(t *testing.T) {
+	tmpDir, require := t.TempDir(), require.New(t)
+	idxPath := filepath.Join(tmpDir, "idx.tmp")
+	wr, err := NewFixedSizeBitmapsWriter(idxPath, 14, 3)
+	require.NoError(err)
+	require.NoError(wr.AddArray(0, []uint64{3, 9, 11}))
+	require.NoError(wr.AddArray(1, []uint64{1, 2, 3}))
+	require.NoError(wr.AddArray(2, []uint64{4, 8, 13}))
+	require.Error(wr.AddArray(3, []uint64{5})) // amount is exclusive: new items are added by AppendArray
+	require.Error(wr.AddArray(2, []uint64{14}))
+	require.NoError(wr.Build())
+	wr.Close()
+
+	// appended, but not flushed: slot is dirty and must be cleared by next append
+	ap, err := OpenFixedSizeBitmapsAppender(idxPath, 14)
+	require.NoError(err)
+	_, err = ap.AppendArray([]uint64{5, 7})
+	require.NoError(err)
+	ap.Close()
+
+	// width is read from header, not from caller
+	ap, err = OpenFixedSizeBitmapsAppender(idxPath, 15)
+	require.NoError(err)
+	require.Equal(uint64(14), ap.bitsPerBitmap)
+	ap.Close()
+
+	// many small appends: file must grow
+	const appends = 5000
+	for i := uint64(0); i < appends; i += 1000 {
+		ap, err := OpenFixedSizeBitmapsAppender(idxPath, 14)
+		require.NoError(err)
+		for j := i; j < i+1000; j++ {
+			item, err := ap.AppendArray([]uint64{j % 14, 13})
+			require.NoError(err)
+			require.Equal(3+j, item)
+		}
+		require.NoError(ap.Build())
+		ap.Close()
+	}
+
+	bm, err := OpenFixedSizeBitmaps(idxPath, 14)
+	require.NoError(err)
+	defer bm.Close()
+	require.Equal(uint64(3+appends), bm.Amount())
+	at := func(item uint64) []uint64 {
+		n, err := bm.At(item)
+		require.NoError(err)
+		return n
+	}
+	require.Equal([]uint64{3, 9, 11}, at(0))
+	require.Equal([]uint64{4, 8, 13}, at(2))
+	require.Equal([]uint64{0, 13}, at(3))
+	require.Equal([]uint64{5, 13}, at(3+5))
+	require.Equal([]uint64{13}, at(3+13))
+	require.Equal([]uint64{1, 13}, at(3+appends-1)) // (appends-1)%14 == 1
+
+	bm15, err := OpenFixedSizeBitmaps(idxPath, 15)
+	require.NoError(err)
+	require.Equal(14, bm15.BitsPerBitmap())
+	n, err := bm15.At(2)
+	require.NoError(err)
+	require.Equal([]uint64{4, 8, 13}, n)
+	bm15.Close()
+
+	// range queries
+	items, err := bm.RangeWithBit(0, 3+28, 3)
+	require.NoError(err)
+	require.Equal([]uint64{0, 1, 3 + 3, 3 + 14 + 3}, items.ToArray())
+	items, err = bm.RangeWithBit(3, 3+appends, 13)
+	require.NoError(err)
+	require.Equal(uint64(appends), items.GetCardinality())
+	_, err = bm.RangeWithBit(0, 1, 14)
+	require.Error(err)
+	_, err = bm.RangeWithBit(0, 3+appends+1, 1)
+	require.Error(err)
+	_, err = bm.At(3 + appends)
+	require.Error(err)
+
+	or, err := bm.OrRange(0, 3)
+	require.NoError(err)
+	require.Equal([]uint64{1, 2, 3, 4, 8, 9, 11, 13}, or)
+	and, err := bm.AndRange(0, 3)
+	require.NoError(err)
+	require.Nil(and)
+	and, err = bm.AndRange(3, 3+appends)
+	require.NoError(err)
+	require.Equal([]uint64{13}, and)
+	and, err = bm.AndRange(2, 4)
+	require.NoError(err)
+	require.Equal([]uint64{13}, and)
+	and, err = bm.AndRange(5, 5)
+	require.NoError(err)
+	require.Nil(and)
+}
+
+func TestFixedSizeBitmapsWideRange(t *testing.T) {
+	tmpDir, require := t.TempDir(), require.New(t)
+	idxPath := filepath.Join(tmpDir, "idx.tmp")
+	wr, err := NewFixedSizeBitmapsWriter(idxPath, 100, 10)
+	require.NoError(err)
+	defer wr.Close()
+	require.NoError(wr.AddArray(1, []uint64{0, 63, 64, 99}))
+	require.NoError(wr.AddArray(2, []uint64{63, 64, 70}))
+	require.NoError(wr.Build())
+
+	bm, err := OpenFixedSizeBitmaps(idxPath, 100)
+	require.NoError(err)
+	defer bm.Close()
+	or, err := bm.OrRange(0, 10)
+	require.NoError(err)
+	require.Equal([]uint64{0, 63, 64, 70, 99}, or)
+	and, err := bm.AndRange(1, 3)
+	require.NoError(err)
+	require.Equal([]uint64{63, 64}, and)
+}
@@ -0,0 +1,444 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package bitmapdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/RoaringBitmap/roaring/roaring64"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// Index - roaring bitmap per key, stored in `table` as chunks of limited size (see WalkChunkWithKeys):
+//
+//	key + chunk.Maximum() -> chunk
+//	key + ^0              -> last chunk
+//
+// Format is same as used by Get/TruncateRange (32-bit) and Get64/TruncateRange64 (64-bit) - existing tables
+// (like kv.LogTopicIndex) can be read and updated by Index. All updates happen inside of given RwTx: chunks are
+// split and merged on write, readers of other transactions see old or new chunks - never mix of them.
+type Index struct {
+	table      string
+	is64       bool
+	chunkLimit uint64
+}
+
+// NewIndex - index of 32-bit values (block numbers)
+func NewIndex(table string) *Index { return &Index{table: table, chunkLimit: ChunkLimit} }
+
+// NewIndex64 - index of 64-bit values (txNums)
+func NewIndex64(table string) *Index { return &Index{table: table, is64: true, chunkLimit: ChunkLimit} }
+
+// WithChunkLimit - copy of index with another size limit of chunk (in bytes)
+func (idx *Index) WithChunkLimit(limit uint64) *Index {
+	cp := *idx
+	cp.chunkLimit = limit
+	return &cp
+}
+
+func (idx *Index) Table() string { return idx.table }
+
+func (idx *Index) suffixLen() int {
+	if idx.is64 {
+		return 8
+	}
+	return 4
+}
+
+// maxValue - suffix of last chunk, also biggest value which can be stored
+func (idx *Index) maxValue() uint64 {
+	if idx.is64 {
+		return math.MaxUint64
+	}
+	return MaxUint32
+}
+
+func (idx *Index) chunkKey(key []byte, n uint64) []byte {
+	k := make([]byte, len(key)+idx.suffixLen())
+	copy(k, key)
+	if idx.is64 {
+		binary.BigEndian.PutUint64(k[len(key):], n)
+	} else {
+		binary.BigEndian.PutUint32(k[len(key):], uint32(n))
+	}
+	return k
+}
+
+// ownChunk - returns suffix of `k` if `k` is chunk key of `key`
+func (idx *Index) ownChunk(key, k []byte) (n uint64, ok bool) {
+	if len(k) != len(key)+idx.suffixLen() || !bytes.HasPrefix(k, key) {
+		return 0, false
+	}
+	if idx.is64 {
+		return binary.BigEndian.Uint64(k[len(key):]), true
+	}
+	return uint64(binary.BigEndian.Uint32(k[len(key):])), true
+}
+
+func (idx *Index) newChunk() chunk {
+	if idx.is64 {
+		return bitmap64{roaring64.New()}
+	}
+	return bitmap32{roaring.New()}
+}
+
+func (idx *Index) readChunk(v []byte) (chunk, error) {
+	bm := idx.newChunk()
+	if err := bm.readFrom(v); err != nil {
+		return nil, fmt.Errorf("bitmapdb.Index(%s): %w", idx.table, err)
+	}
+	return bm, nil
+}
+
+// sortedValues - sorted copy of `values`, checks that they fit to index
+func (idx *Index) sortedValues(values []uint64) ([]uint64, error) {
+	res := append(make([]uint64, 0, len(values)), values...)
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	if len(res) > 0 && res[len(res)-1] > idx.maxValue() {
+		return nil, fmt.Errorf("bitmapdb.Index(%s): value %d doesn't fit 32 bits", idx.table, res[len(res)-1])
+	}
+	return res, nil
+}
+
+// Add - adds `values` to bitmap of `key`. Only chunks which can contain `values` are re-written.
+func (idx *Index) Add(tx kv.RwTx, key []byte, values ...uint64) error {
+	values, err := idx.sortedValues(values)
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	l, err := idx.load(tx, key, values[0], values[len(values)-1], false)
+	if err != nil {
+		return err
+	}
+	l.bm.add(values)
+	return idx.store(tx, key, l)
+}
+
+// Remove - removes `values` from bitmap of `key`. Re-written chunks are merged with neighbour chunks: then
+// many removals don't leave many small chunks. Key without values has no chunks.
+func (idx *Index) Remove(tx kv.RwTx, key []byte, values ...uint64) error {
+	values, err := idx.sortedValues(values)
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	l, err := idx.load(tx, key, values[0], values[len(values)-1], true)
+	if err != nil {
+		return err
+	}
+	if len(l.keys) == 0 {
+		return nil
+	}
+	l.bm.remove(values)
+	return idx.store(tx, key, l)
+}
+
+// Cardinality - amount of values in bitmap of `key`
+func (idx *Index) Cardinality(tx kv.Tx, key []byte) (uint64, error) {
+	c, err := tx.Cursor(idx.table)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	var res uint64
+	for k, v, err := c.Seek(key); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.HasPrefix(k, key) {
+			break
+		}
+		if _, ok := idx.ownChunk(key, k); !ok {
+			continue
+		}
+		bm, err := idx.readChunk(v)
+		if err != nil {
+			return 0, err
+		}
+		res += bm.card()
+	}
+	return res, nil
+}
+
+// Range - values of `key` in [from, to) in ascending order. Chunks are read lazily: only chunks which overlap
+// with [from, to) are read. Iterator must be closed.
+func (idx *Index) Range(tx kv.Tx, key []byte, from, to uint64) (*IndexRangeIter, error) {
+	c, err := tx.Cursor(idx.table)
+	if err != nil {
+		return nil, err
+	}
+	it := &IndexRangeIter{idx: idx, c: c, key: key, from: from, to: to}
+	if from < to && from <= idx.maxValue() {
+		it.k, it.v, it.err = c.Seek(idx.chunkKey(key, from))
+	}
+	it.advance()
+	return it, nil
+}
+
+// loaded - chunks of key which will be replaced by store
+type loaded struct {
+	keys  [][]byte // chunk keys to delete
+	bm    chunk    // union of chunks
+	lastN uint64   // suffix of last loaded chunk: key of last written chunk
+}
+
+// load - reads chunks which can contain values in [from, to]. With `neighbours` also reads chunk before and
+// after them - to merge small chunks.
+func (idx *Index) load(tx kv.RwTx, key []byte, from, to uint64, neighbours bool) (*loaded, error) {
+	l := &loaded{bm: idx.newChunk(), lastN: idx.maxValue()}
+	c, err := tx.Cursor(idx.table)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	read := func(k, v []byte, n uint64) error {
+		bm, err := idx.readChunk(v)
+		if err != nil {
+			return err
+		}
+		l.bm.or(bm)
+		l.keys = append(l.keys, libcommon.Copy(k))
+		l.lastN = n
+		return nil
+	}
+
+	seek := idx.chunkKey(key, from)
+	k, v, err := c.Seek(seek)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := idx.ownChunk(key, k); !ok { // last chunk has suffix ^0: key has no chunks
+		return l, nil
+	}
+	if neighbours {
+		pk, pv, err := c.Prev()
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := idx.ownChunk(key, pk); ok {
+			if err := read(pk, pv, n); err != nil {
+				return nil, err
+			}
+		}
+		if k, v, err = c.Seek(seek); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		n, ok := idx.ownChunk(key, k)
+		if !ok { // chunk with suffix ^0 not found
+			l.lastN = idx.maxValue()
+			return l, nil
+		}
+		if err := read(k, v, n); err != nil {
+			return nil, err
+		}
+		if k, v, err = c.Next(); err != nil {
+			return nil, err
+		}
+		if n >= to {
+			break
+		}
+	}
+	if n, ok := idx.ownChunk(key, k); ok && neighbours {
+		if err := read(k, v, n); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// store - replaces loaded chunks by chunks of `l.bm`
+func (idx *Index) store(tx kv.RwTx, key []byte, l *loaded) error {
+	for _, k := range l.keys {
+		if err := tx.Delete(idx.table, k); err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	for !l.bm.isEmpty() {
+		part := l.bm.cutLeft(idx.chunkLimit)
+		n := part.max()
+		if l.bm.isEmpty() {
+			n = l.lastN
+		}
+		buf.Reset()
+		if err := part.writeTo(&buf); err != nil {
+			return err
+		}
+		if err := tx.Put(idx.table, idx.chunkKey(key, n), libcommon.Copy(buf.Bytes())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexRangeIter - see Index.Range
+type IndexRangeIter struct {
+	idx      *Index
+	c        kv.Cursor
+	key      []byte
+	from, to uint64
+
+	k, v    []byte // next chunk
+	chunk   chunkIterator
+	hasNext bool
+	nextV   uint64
+	err     error
+}
+
+func (it *IndexRangeIter) advance() {
+	it.hasNext = false
+	for it.err == nil {
+		if it.chunk != nil && it.chunk.HasNext() {
+			v := it.chunk.next()
+			if v >= it.to {
+				return
+			}
+			it.hasNext, it.nextV = true, v
+			return
+		}
+		n, ok := it.idx.ownChunk(it.key, it.k)
+		if !ok {
+			return
+		}
+		bm, err := it.idx.readChunk(it.v)
+		if err != nil {
+			it.err = err
+			return
+		}
+		it.chunk = bm.iterator(it.from)
+		if n >= it.to {
+			it.k, it.v = nil, nil
+			continue
+		}
+		it.k, it.v, it.err = it.c.Next()
+	}
+}
+
+func (it *IndexRangeIter) HasNext() bool { return it.err != nil || it.hasNext }
+func (it *IndexRangeIter) Next() (uint64, error) {
+	if it.err != nil {
+		return 0, it.err
+	}
+	v := it.nextV
+	it.advance()
+	return v, nil
+}
+func (it *IndexRangeIter) Close() {
+	if it.c != nil {
+		it.c.Close()
+	}
+}
+
+// chunk - same operations for roaring.Bitmap and roaring64.Bitmap
+type chunk interface {
+	add(values []uint64)
+	remove(values []uint64)
+	or(o chunk)
+	isEmpty() bool
+	card() uint64
+	max() uint64
+	cutLeft(sizeLimit uint64) chunk
+	readFrom(v []byte) error
+	writeTo(buf *bytes.Buffer) error
+	iterator(from uint64) chunkIterator
+}
+
+type chunkIterator interface {
+	HasNext() bool
+	next() uint64
+}
+
+type bitmap32 struct{ *roaring.Bitmap }
+
+func (b bitmap32) add(values []uint64) {
+	for _, v := range values {
+		b.Add(uint32(v))
+	}
+}
+func (b bitmap32) remove(values []uint64) {
+	for _, v := range values {
+		b.Remove(uint32(v))
+	}
+}
+func (b bitmap32) or(o chunk)    { b.Or(o.(bitmap32).Bitmap) }
+func (b bitmap32) isEmpty() bool { return b.IsEmpty() }
+func (b bitmap32) card() uint64  { return b.GetCardinality() }
+func (b bitmap32) max() uint64   { return uint64(b.Maximum()) }
+func (b bitmap32) cutLeft(sizeLimit uint64) chunk {
+	return bitmap32{CutLeft(b.Bitmap, sizeLimit)}
+}
+func (b bitmap32) readFrom(v []byte) error {
+	_, err := b.ReadFrom(bytes.NewReader(v))
+	return err
+}
+func (b bitmap32) writeTo(buf *bytes.Buffer) error {
+	_, err := b.WriteTo(buf)
+	return err
+}
+func (b bitmap32) iterator(from uint64) chunkIterator {
+	if from > MaxUint32 {
+		return iterator32{roaring.New().Iterator()}
+	}
+	it := b.Iterator()
+	it.AdvanceIfNeeded(uint32(from))
+	return iterator32{it}
+}
+
+type iterator32 struct{ roaring.IntPeekable }
+
+func (it iterator32) next() uint64 { return uint64(it.Next()) }
+
+type bitmap64 struct{ *roaring64.Bitmap }
+
+func (b bitmap64) add(values []uint64) { b.AddMany(values) }
+func (b bitmap64) remove(values []uint64) {
+	for _, v := range values {
+		b.Remove(v)
+	}
+}
+func (b bitmap64) or(o chunk)    { b.Or(o.(bitmap64).Bitmap) }
+func (b bitmap64) isEmpty() bool { return b.IsEmpty() }
+func (b bitmap64) card() uint64  { return b.GetCardinality() }
+func (b bitmap64) max() uint64   { return b.Maximum() }
+func (b bitmap64) cutLeft(sizeLimit uint64) chunk {
+	return bitmap64{CutLeft64(b.Bitmap, sizeLimit)}
+}
+func (b bitmap64) readFrom(v []byte) error {
+	_, err := b.ReadFrom(bytes.NewReader(v))
+	return err
+}
+func (b bitmap64) writeTo(buf *bytes.Buffer) error {
+	_, err := b.WriteTo(buf)
+	return err
+}
+func (b bitmap64) iterator(from uint64) chunkIterator {
+	it := b.Iterator()
+	it.AdvanceIfNeeded(from)
+	return iterator64{it}
+}
+
+type iterator64 struct{ roaring64.IntPeekable64 }
+
+func (it iterator64) next() uint64 { return it.Next() }
@@ -17,6 +17,7 @@
 package kv
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -26,6 +27,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/torquem-ch/mdbx-go/mdbx"
 	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
 )
 
 func DefaultPageSize() uint64 {
@@ -91,6 +93,140 @@ func BigChunks(db RoDB, table string, from []byte, walker func(tx Tx, k, v []byt
 	return nil
 }
 
+// ParallelRange - calls `walker` for every key of `table` (for DupSort tables: for every value), `workers` goroutines
+// process different key ranges concurrently. Every worker uses own read transaction.
+//
+// Table is split to `workers*16` ranges with about equal amount of keys (see RangeSplitPoints). Workers take ranges
+// one-by-one - then ranges with slow keys don't make 1 worker much slower than others.
+//
+// `walker` is called concurrently: keys are ascending only inside 1 range. k, v are valid only inside walker call.
+// First error stops all workers. `progress` (optional) - amount of processed keys, can be read by other goroutine for logs.
+func ParallelRange(ctx context.Context, db RoDB, table string, workers int, progress *atomic.Uint64, walker func(tx Tx, k, v []byte) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	var splits [][]byte
+	if err := db.View(ctx, func(tx Tx) (err error) {
+		splits, err = RangeSplitPoints(tx, table, workers*16)
+		return err
+	}); err != nil {
+		return err
+	}
+	ranges := make(chan [2][]byte, len(splits)+1)
+	var from []byte
+	for _, to := range splits {
+		ranges <- [2][]byte{from, to}
+		from = to
+	}
+	ranges <- [2][]byte{from, nil}
+	close(ranges)
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			return db.View(ctx, func(tx Tx) error {
+				c, err := tx.Cursor(table)
+				if err != nil {
+					return err
+				}
+				defer c.Close()
+				for r := range ranges {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					if err := walkRange(ctx, tx, c, r[0], r[1], progress, walker); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	}
+	return g.Wait()
+}
+
+// RangeSplitPoints - up to `n-1` existing keys of `table` which split it to `n` ranges with about equal amount of
+// entries (for DupSort tables: values). Every `Count()/n`-th entry is taken by walk over table: it's sequential read
+// without values processing - much cheaper than processing of ranges. Skewed keys don't make ranges unbalanced.
+func RangeSplitPoints(tx Tx, table string, n int) ([][]byte, error) {
+	if n < 2 {
+		return nil, nil
+	}
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	cnt, err := c.Count()
+	if err != nil {
+		return nil, err
+	}
+	stride := cnt / uint64(n)
+	if stride == 0 {
+		stride = 1
+	}
+
+	var res [][]byte
+	var prev []byte // split point must differ from previous one and from first key: no empty ranges
+	next := stride
+	var i uint64
+	for k, _, err := c.First(); k != nil && len(res) < n-1; k, _, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			prev = common.Copy(k)
+		} else if i >= next && !bytes.Equal(k, prev) {
+			prev = common.Copy(k)
+			res = append(res, prev)
+			next = uint64(len(res)+1) * stride
+		}
+		i++
+	}
+	return res, nil
+}
+
+// walkRange - walks keys in [from, to), nil `from` means: from first key, nil `to` means: to last key
+func walkRange(ctx context.Context, tx Tx, c Cursor, from, to []byte, progress *atomic.Uint64, walker func(tx Tx, k, v []byte) error) error {
+	var processed uint64
+	defer func() {
+		if progress != nil {
+			progress.Add(processed)
+		}
+	}()
+	var k, v []byte
+	var err error
+	if from == nil {
+		k, v, err = c.First()
+	} else {
+		k, v, err = c.Seek(from)
+	}
+	for ; k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if to != nil && bytes.Compare(k, to) >= 0 {
+			break
+		}
+		if err := walker(tx, k, v); err != nil {
+			return err
+		}
+		processed++
+		if processed%1024 == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			if progress != nil {
+				progress.Add(processed)
+				processed = 0
+			}
+		}
+	}
+	return err
+}
+
 var (
 	bytesTrue  = []byte{1}
 	bytesFalse = []byte{0}
@@ -0,0 +1,331 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package iter
+
+import "errors"
+
+// ErrNoMoreItems - Next called on exhausted stream
+var ErrNoMoreItems = errors.New("iter: no more items")
+
+// Adapters don't depend on order of stream - work same for order.Asc and order.Desc.
+// All of them implement Close: close underlying streams.
+
+type ArrDualStream[K, V any] struct {
+	keys   []K
+	values []V
+	i      int
+}
+
+func ArrayDual[K, V any](keys []K, values []V) *ArrDualStream[K, V] {
+	return &ArrDualStream[K, V]{keys: keys, values: values}
+}
+func ArrayKV(keys, values [][]byte) *ArrDualStream[[]byte, []byte] {
+	return ArrayDual[[]byte, []byte](keys, values)
+}
+func (it *ArrDualStream[K, V]) HasNext() bool { return it.i < len(it.keys) }
+func (it *ArrDualStream[K, V]) Close()        {}
+func (it *ArrDualStream[K, V]) Next() (K, V, error) {
+	k, v := it.keys[it.i], it.values[it.i]
+	it.i++
+	return k, v, nil
+}
+
+// LimitIter - returns first `limit` items of stream. limit -1 means Unlimited (as in UnionKV)
+type LimitIter[T any] struct {
+	it    Unary[T]
+	limit int
+}
+
+func Limit[T any](it Unary[T], limit int) *LimitIter[T] { return &LimitIter[T]{it: it, limit: limit} }
+func (m *LimitIter[T]) HasNext() bool                   { return m.limit != 0 && m.it.HasNext() }
+func (m *LimitIter[T]) Next() (T, error) {
+	m.limit--
+	return m.it.Next()
+}
+func (m *LimitIter[T]) Close() { closeAll(m.it) }
+
+type LimitDualIter[K, V any] struct {
+	it    Dual[K, V]
+	limit int
+}
+
+func LimitDual[K, V any](it Dual[K, V], limit int) *LimitDualIter[K, V] {
+	return &LimitDualIter[K, V]{it: it, limit: limit}
+}
+func LimitKV(it KV, limit int) *LimitDualIter[[]byte, []byte] {
+	return LimitDual[[]byte, []byte](it, limit)
+}
+func (m *LimitDualIter[K, V]) HasNext() bool { return m.limit != 0 && m.it.HasNext() }
+func (m *LimitDualIter[K, V]) Next() (K, V, error) {
+	m.limit--
+	return m.it.Next()
+}
+func (m *LimitDualIter[K, V]) Close() { closeAll(m.it) }
+
+// SkipIter - skips first `n` items of stream. Skipping is lazy: happens on first HasNext
+type SkipIter[T any] struct {
+	it  Unary[T]
+	n   int
+	err error
+}
+
+func Skip[T any](it Unary[T], n int) *SkipIter[T] { return &SkipIter[T]{it: it, n: n} }
+func (m *SkipIter[T]) skip() {
+	for ; m.n > 0 && m.err == nil && m.it.HasNext(); m.n-- {
+		_, m.err = m.it.Next()
+	}
+	m.n = 0
+}
+func (m *SkipIter[T]) HasNext() bool {
+	m.skip()
+	return m.err != nil || m.it.HasNext()
+}
+func (m *SkipIter[T]) Next() (v T, err error) {
+	m.skip()
+	if m.err != nil {
+		return v, m.err
+	}
+	return m.it.Next()
+}
+func (m *SkipIter[T]) Close() { closeAll(m.it) }
+
+type SkipDualIter[K, V any] struct {
+	it  Dual[K, V]
+	n   int
+	err error
+}
+
+func SkipDual[K, V any](it Dual[K, V], n int) *SkipDualIter[K, V] {
+	return &SkipDualIter[K, V]{it: it, n: n}
+}
+func SkipKV(it KV, n int) *SkipDualIter[[]byte, []byte] { return SkipDual[[]byte, []byte](it, n) }
+func (m *SkipDualIter[K, V]) skip() {
+	for ; m.n > 0 && m.err == nil && m.it.HasNext(); m.n-- {
+		_, _, m.err = m.it.Next()
+	}
+	m.n = 0
+}
+func (m *SkipDualIter[K, V]) HasNext() bool {
+	m.skip()
+	return m.err != nil || m.it.HasNext()
+}
+func (m *SkipDualIter[K, V]) Next() (k K, v V, err error) {
+	m.skip()
+	if m.err != nil {
+		return k, v, m.err
+	}
+	return m.it.Next()
+}
+func (m *SkipDualIter[K, V]) Close() { closeAll(m.it) }
+
+// ChainIter - returns all items of 1-st stream, then all items of 2-nd stream, etc... (aka Concat).
+// Result is sorted only if streams don't overlap: use MergeUnary otherwise.
+type ChainIter[T any] struct {
+	its []Unary[T]
+	i   int // current stream
+}
+
+func Chain[T any](its ...Unary[T]) *ChainIter[T] { return &ChainIter[T]{its: its} }
+func (m *ChainIter[T]) HasNext() bool {
+	for ; m.i < len(m.its); m.i++ {
+		if m.its[m.i] != nil && m.its[m.i].HasNext() {
+			return true
+		}
+	}
+	return false
+}
+func (m *ChainIter[T]) Next() (v T, err error) {
+	if !m.HasNext() {
+		return v, ErrNoMoreItems
+	}
+	return m.its[m.i].Next()
+}
+func (m *ChainIter[T]) Close() { closeAll(m.its...) }
+
+type ChainDualIter[K, V any] struct {
+	its []Dual[K, V]
+	i   int
+}
+
+func ChainDual[K, V any](its ...Dual[K, V]) *ChainDualIter[K, V] {
+	return &ChainDualIter[K, V]{its: its}
+}
+func ChainKV(its ...KV) *ChainDualIter[[]byte, []byte] {
+	duals := make([]Dual[[]byte, []byte], len(its))
+	for i := range its {
+		duals[i] = its[i]
+	}
+	return ChainDual[[]byte, []byte](duals...)
+}
+func (m *ChainDualIter[K, V]) HasNext() bool {
+	for ; m.i < len(m.its); m.i++ {
+		if m.its[m.i] != nil && m.its[m.i].HasNext() {
+			return true
+		}
+	}
+	return false
+}
+func (m *ChainDualIter[K, V]) Next() (k K, v V, err error) {
+	if !m.HasNext() {
+		return k, v, ErrNoMoreItems
+	}
+	return m.its[m.i].Next()
+}
+func (m *ChainDualIter[K, V]) Close() { closeAll(m.its...) }
+
+// TakeWhileIter - returns items while `f` returns true. Item on which `f` returned false is dropped.
+// Useful to stop on key prefix or on txNum: `TakeWhile(it, func(txNum uint64) bool { return txNum < toTxNum })`
+type TakeWhileIter[T any] struct {
+	it      Unary[T]
+	f       func(T) bool
+	hasNext bool
+	err     error
+	nextK   T
+}
+
+func TakeWhile[T any](it Unary[T], f func(T) bool) *TakeWhileIter[T] {
+	m := &TakeWhileIter[T]{it: it, f: f}
+	m.advance()
+	return m
+}
+func (m *TakeWhileIter[T]) advance() {
+	m.hasNext = false
+	if m.err != nil || !m.it.HasNext() {
+		return
+	}
+	k, err := m.it.Next()
+	if err != nil {
+		m.err = err
+		return
+	}
+	if m.f(k) {
+		m.hasNext, m.nextK = true, k
+	}
+}
+func (m *TakeWhileIter[T]) HasNext() bool { return m.err != nil || m.hasNext }
+func (m *TakeWhileIter[T]) Next() (k T, err error) {
+	k, err = m.nextK, m.err
+	m.advance()
+	return k, err
+}
+func (m *TakeWhileIter[T]) Close() { closeAll(m.it) }
+
+type TakeWhileDualIter[K, V any] struct {
+	it      Dual[K, V]
+	f       func(K, V) bool
+	hasNext bool
+	err     error
+	nextK   K
+	nextV   V
+}
+
+func TakeWhileDual[K, V any](it Dual[K, V], f func(K, V) bool) *TakeWhileDualIter[K, V] {
+	m := &TakeWhileDualIter[K, V]{it: it, f: f}
+	m.advance()
+	return m
+}
+func TakeWhileKV(it KV, f func(k, v []byte) bool) *TakeWhileDualIter[[]byte, []byte] {
+	return TakeWhileDual[[]byte, []byte](it, f)
+}
+func (m *TakeWhileDualIter[K, V]) advance() {
+	m.hasNext = false
+	if m.err != nil || !m.it.HasNext() {
+		return
+	}
+	k, v, err := m.it.Next()
+	if err != nil {
+		m.err = err
+		return
+	}
+	if m.f(k, v) {
+		m.hasNext, m.nextK, m.nextV = true, k, v
+	}
+}
+func (m *TakeWhileDualIter[K, V]) HasNext() bool { return m.err != nil || m.hasNext }
+func (m *TakeWhileDualIter[K, V]) Next() (k K, v V, err error) {
+	k, v, err = m.nextK, m.nextV, m.err
+	m.advance()
+	return k, v, err
+}
+func (m *TakeWhileDualIter[K, V]) Close() { closeAll(m.it) }
+
+// MapIter - converts items of stream to another type. See also TransformDual - it keeps types.
+type MapIter[T, R any] struct {
+	it Unary[T]
+	f  func(T) (R, error)
+}
+
+func Map[T, R any](it Unary[T], f func(T) (R, error)) *MapIter[T, R] {
+	return &MapIter[T, R]{it: it, f: f}
+}
+func (m *MapIter[T, R]) HasNext() bool { return m.it.HasNext() }
+func (m *MapIter[T, R]) Next() (r R, err error) {
+	v, err := m.it.Next()
+	if err != nil {
+		return r, err
+	}
+	return m.f(v)
+}
+func (m *MapIter[T, R]) Close() { closeAll(m.it) }
+
+type MapDualIter[K, V, K2, V2 any] struct {
+	it Dual[K, V]
+	f  func(K, V) (K2, V2, error)
+}
+
+func MapDual[K, V, K2, V2 any](it Dual[K, V], f func(K, V) (K2, V2, error)) *MapDualIter[K, V, K2, V2] {
+	return &MapDualIter[K, V, K2, V2]{it: it, f: f}
+}
+func (m *MapDualIter[K, V, K2, V2]) HasNext() bool { return m.it.HasNext() }
+func (m *MapDualIter[K, V, K2, V2]) Next() (k2 K2, v2 V2, err error) {
+	k, v, err := m.it.Next()
+	if err != nil {
+		return k2, v2, err
+	}
+	return m.f(k, v)
+}
+func (m *MapDualIter[K, V, K2, V2]) Close() { closeAll(m.it) }
+
+// NextBatch - reads up to `limit` items of stream. Returns nil when stream is exhausted.
+func NextBatch[T any](it Unary[T], limit int) ([]T, error) {
+	var res []T
+	for i := 0; i < limit && it.HasNext(); i++ {
+		v, err := it.Next()
+		if err != nil {
+			return res, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// ChunkIter - returns items of stream in batches of `size` items (last batch can be smaller).
+// Every batch is new slice: caller can keep it.
+type ChunkIter[T any] struct {
+	it   Unary[T]
+	size int
+}
+
+func Chunk[T any](it Unary[T], size int) *ChunkIter[T] {
+	if size <= 0 {
+		size = 1
+	}
+	return &ChunkIter[T]{it: it, size: size}
+}
+func (m *ChunkIter[T]) HasNext() bool      { return m.it.HasNext() }
+func (m *ChunkIter[T]) Next() ([]T, error) { return NextBatch(m.it, m.size) }
+func (m *ChunkIter[T]) Close()             { closeAll(m.it) }
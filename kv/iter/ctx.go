@@ -0,0 +1,126 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package iter
+
+import "context"
+
+// Close - closes `it` if it implements Closer. Every iterator of this package closes its upstream iterators
+// in own Close (also ones it already dropped as exhausted), so it's enough to close last iterator of chain:
+//
+//	it := iter.FilterKV(iter.UnionKV(files, db, -1), f)
+//	defer iter.Close(it)
+func Close(it any) {
+	if x, ok := it.(Closer); ok {
+		x.Close()
+	}
+}
+
+func closeAll[T any](its ...T) {
+	for _, it := range its {
+		Close(it)
+	}
+}
+
+// CtxUnary - stops iteration when `ctx` is done: upstream is closed immediately (to release cursors and files
+// as soon as possible - for example on RPC timeout), then HasNext returns true and Next returns ctx.Err().
+// Cancellation is checked only while upstream has items: exhausted stream stays exhausted.
+type CtxUnary[T any] struct {
+	ctx context.Context
+	it  Unary[T]
+	err error
+}
+
+func WithContext[T any](ctx context.Context, it Unary[T]) *CtxUnary[T] {
+	return &CtxUnary[T]{ctx: ctx, it: it}
+}
+func WithContextU64(ctx context.Context, it U64) *CtxUnary[uint64] {
+	return WithContext[uint64](ctx, it)
+}
+func (m *CtxUnary[T]) check() bool {
+	if m.err != nil {
+		return false
+	}
+	select {
+	case <-m.ctx.Done():
+		m.err = m.ctx.Err()
+		Close(m.it)
+		return false
+	default:
+		return true
+	}
+}
+func (m *CtxUnary[T]) HasNext() bool {
+	if m.err != nil {
+		return true
+	}
+	if !m.it.HasNext() {
+		return false
+	}
+	m.check()
+	return true
+}
+func (m *CtxUnary[T]) Next() (v T, err error) {
+	if !m.check() {
+		return v, m.err
+	}
+	return m.it.Next()
+}
+func (m *CtxUnary[T]) Close() { Close(m.it) }
+
+// CtxDual - see CtxUnary
+type CtxDual[K, V any] struct {
+	ctx context.Context
+	it  Dual[K, V]
+	err error
+}
+
+func WithContextDual[K, V any](ctx context.Context, it Dual[K, V]) *CtxDual[K, V] {
+	return &CtxDual[K, V]{ctx: ctx, it: it}
+}
+func WithContextKV(ctx context.Context, it KV) *CtxDual[[]byte, []byte] {
+	return WithContextDual[[]byte, []byte](ctx, it)
+}
+func (m *CtxDual[K, V]) check() bool {
+	if m.err != nil {
+		return false
+	}
+	select {
+	case <-m.ctx.Done():
+		m.err = m.ctx.Err()
+		Close(m.it)
+		return false
+	default:
+		return true
+	}
+}
+func (m *CtxDual[K, V]) HasNext() bool {
+	if m.err != nil {
+		return true
+	}
+	if !m.it.HasNext() {
+		return false
+	}
+	m.check()
+	return true
+}
+func (m *CtxDual[K, V]) Next() (k K, v V, err error) {
+	if !m.check() {
+		return k, v, m.err
+	}
+	return m.it.Next()
+}
+func (m *CtxDual[K, V]) Close() { Close(m.it) }
@@ -18,9 +18,11 @@ package iter
 
 import (
 	"fmt"
+	"runtime/debug"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 )
 
 func ToArr[T any](s Unary[T]) (res []T, err error) {
@@ -112,3 +114,56 @@ func CountDual[K, V any](s Dual[K, V]) (cnt int, err error) {
 	}
 	return cnt, err
 }
+
+// TrackedUnary - see TrackUnary
+type TrackedUnary[T any] struct {
+	Unary[T]
+	closed *atomic.Bool
+}
+
+// TrackUnary - leak-check: fails test (at the end of test) if returned iterator was not closed
+//
+//	it := iter.TrackU64(t, tx.IndexRange(...))
+//	// pass `it` to code under test
+func TrackUnary[T any](tb testing.TB, it Unary[T]) *TrackedUnary[T] {
+	tb.Helper()
+	return &TrackedUnary[T]{Unary: it, closed: trackClose(tb)}
+}
+func TrackU64(tb testing.TB, it U64) *TrackedUnary[uint64] {
+	tb.Helper()
+	return TrackUnary[uint64](tb, it)
+}
+func (m *TrackedUnary[T]) Close() {
+	m.closed.Store(true)
+	Close(m.Unary)
+}
+
+// TrackedDual - see TrackUnary
+type TrackedDual[K, V any] struct {
+	Dual[K, V]
+	closed *atomic.Bool
+}
+
+func TrackDual[K, V any](tb testing.TB, it Dual[K, V]) *TrackedDual[K, V] {
+	tb.Helper()
+	return &TrackedDual[K, V]{Dual: it, closed: trackClose(tb)}
+}
+func TrackKV(tb testing.TB, it KV) *TrackedDual[[]byte, []byte] {
+	tb.Helper()
+	return TrackDual[[]byte, []byte](tb, it)
+}
+func (m *TrackedDual[K, V]) Close() {
+	m.closed.Store(true)
+	Close(m.Dual)
+}
+
+func trackClose(tb testing.TB) *atomic.Bool {
+	closed := atomic.NewBool(false)
+	stack := debug.Stack()
+	tb.Cleanup(func() {
+		if !closed.Load() {
+			tb.Errorf("iterator is not closed, created at:\n%s", stack)
+		}
+	})
+	return closed
+}
@@ -40,8 +40,10 @@ type (
 
 func (EmptyUnary[T]) HasNext() bool                 { return false }
 func (EmptyUnary[T]) Next() (v T, err error)        { return v, err }
+func (EmptyUnary[T]) Close()                        {}
 func (EmptyDual[K, V]) HasNext() bool               { return false }
 func (EmptyDual[K, V]) Next() (k K, v V, err error) { return k, v, err }
+func (EmptyDual[K, V]) Close()                      {}
 
 type ArrStream[V any] struct {
 	arr []V
@@ -90,15 +92,17 @@ func (it *RangeIter[T]) Next() (T, error) {
 
 // UnionKVIter - merge 2 kv.Pairs streams to 1 in lexicographically order
 // 1-st stream has higher priority - when 2 streams return same key
+// limit -1 means Unlimited
 type UnionKVIter struct {
 	x, y               KV
 	xHasNext, yHasNext bool
 	xNextK, xNextV     []byte
 	yNextK, yNextV     []byte
+	limit              int
 	err                error
 }
 
-func UnionKV(x, y KV) KV {
+func UnionKV(x, y KV, limit int) KV {
 	if x == nil && y == nil {
 		return EmptyKV
 	}
@@ -108,12 +112,14 @@ func UnionKV(x, y KV) KV {
 	if y == nil {
 		return x
 	}
-	m := &UnionKVIter{x: x, y: y}
+	m := &UnionKVIter{x: x, y: y, limit: limit}
 	m.advanceX()
 	m.advanceY()
 	return m
 }
-func (m *UnionKVIter) HasNext() bool { return m.xHasNext || m.yHasNext }
+func (m *UnionKVIter) HasNext() bool {
+	return m.err != nil || (m.limit != 0 && m.xHasNext) || (m.limit != 0 && m.yHasNext)
+}
 func (m *UnionKVIter) advanceX() {
 	if m.err != nil {
 		return
@@ -136,6 +142,7 @@ func (m *UnionKVIter) Next() ([]byte, []byte, error) {
 	if m.err != nil {
 		return nil, nil, m.err
 	}
+	m.limit--
 	if m.xHasNext && m.yHasNext {
 		cmp := bytes.Compare(m.xNextK, m.yNextK)
 		if cmp < 0 {
@@ -191,12 +198,7 @@ func Union[T constraints.Ordered](x, y Unary[T], asc order.By) Unary[T] {
 	if y == nil {
 		return x
 	}
-	if !x.HasNext() {
-		return y
-	}
-	if !y.HasNext() {
-		return x
-	}
+	// don't return `x` or `y` even if other one is empty: other one still must be closed
 	m := &UnionUnary[T]{x: x, y: y, asc: asc}
 	m.advanceX()
 	m.advanceY()
@@ -257,14 +259,7 @@ func (m *UnionUnary[T]) Next() (res T, err error) {
 	m.advanceY()
 	return k, err
 }
-func (m *UnionUnary[T]) Close() {
-	if x, ok := m.x.(Closer); ok {
-		x.Close()
-	}
-	if y, ok := m.y.(Closer); ok {
-		y.Close()
-	}
-}
+func (m *UnionUnary[T]) Close() { closeAll(m.x, m.y) }
 
 // IntersectIter
 type IntersectIter[T constraints.Ordered] struct {
@@ -275,14 +270,15 @@ type IntersectIter[T constraints.Ordered] struct {
 }
 
 func Intersect[T constraints.Ordered](x, y Unary[T]) Unary[T] {
-	if x == nil || y == nil || !x.HasNext() || !y.HasNext() {
+	if x == nil || y == nil {
+		closeAll(x, y) // result is empty: nobody will close them
 		return &EmptyUnary[T]{}
 	}
 	m := &IntersectIter[T]{x: x, y: y}
 	m.advance()
 	return m
 }
-func (m *IntersectIter[T]) HasNext() bool { return m.xHasNext && m.yHasNext }
+func (m *IntersectIter[T]) HasNext() bool { return m.err != nil || (m.xHasNext && m.yHasNext) }
 func (m *IntersectIter[T]) advance() {
 	m.advanceX()
 	m.advanceY()
@@ -323,8 +319,11 @@ func (m *IntersectIter[T]) advanceY() {
 }
 func (m *IntersectIter[T]) Next() (T, error) {
 	k, err := m.xNextK, m.err
+	if err != nil {
+		return k, err
+	}
 	m.advance()
-	return k, err
+	return k, nil
 }
 func (m *IntersectIter[T]) Close() {
 	if x, ok := m.x.(Closer); ok {
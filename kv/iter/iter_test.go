@@ -85,11 +85,25 @@ func TestUnionPairs(t *testing.T) {
 		_ = tx.Put(kv.PlainState, []byte{3}, []byte{9})
 		it, _ := tx.Range(kv.AccountsHistory, nil, nil)
 		it2, _ := tx.Range(kv.PlainState, nil, nil)
-		keys, values, err := iter.ToKVArray(iter.UnionKV(it, it2))
+		keys, values, err := iter.ToKVArray(iter.UnionKV(it, it2, -1))
 		require.NoError(err)
 		require.Equal([][]byte{{1}, {2}, {3}, {4}}, keys)
 		require.Equal([][]byte{{1}, {9}, {1}, {1}}, values)
 	})
+	t.Run("limit", func(t *testing.T) {
+		require := require.New(t)
+		tx, _ := db.BeginRw(ctx)
+		defer tx.Rollback()
+		_ = tx.Put(kv.AccountsHistory, []byte{1}, []byte{1})
+		_ = tx.Put(kv.AccountsHistory, []byte{3}, []byte{1})
+		_ = tx.Put(kv.PlainState, []byte{2}, []byte{9})
+		_ = tx.Put(kv.PlainState, []byte{3}, []byte{9})
+		it, _ := tx.Range(kv.AccountsHistory, nil, nil)
+		it2, _ := tx.Range(kv.PlainState, nil, nil)
+		keys, _, err := iter.ToKVArray(iter.UnionKV(it, it2, 2))
+		require.NoError(err)
+		require.Equal([][]byte{{1}, {2}}, keys)
+	})
 	t.Run("empty 1st", func(t *testing.T) {
 		require := require.New(t)
 		tx, _ := db.BeginRw(ctx)
@@ -98,7 +112,7 @@ func TestUnionPairs(t *testing.T) {
 		_ = tx.Put(kv.PlainState, []byte{3}, []byte{9})
 		it, _ := tx.Range(kv.AccountsHistory, nil, nil)
 		it2, _ := tx.Range(kv.PlainState, nil, nil)
-		keys, _, err := iter.ToKVArray(iter.UnionKV(it, it2))
+		keys, _, err := iter.ToKVArray(iter.UnionKV(it, it2, -1))
 		require.NoError(err)
 		require.Equal([][]byte{{2}, {3}}, keys)
 	})
@@ -111,7 +125,7 @@ func TestUnionPairs(t *testing.T) {
 		_ = tx.Put(kv.AccountsHistory, []byte{4}, []byte{1})
 		it, _ := tx.Range(kv.AccountsHistory, nil, nil)
 		it2, _ := tx.Range(kv.PlainState, nil, nil)
-		keys, _, err := iter.ToKVArray(iter.UnionKV(it, it2))
+		keys, _, err := iter.ToKVArray(iter.UnionKV(it, it2, -1))
 		require.NoError(err)
 		require.Equal([][]byte{{1}, {3}, {4}}, keys)
 	})
@@ -121,7 +135,7 @@ func TestUnionPairs(t *testing.T) {
 		defer tx.Rollback()
 		it, _ := tx.Range(kv.AccountsHistory, nil, nil)
 		it2, _ := tx.Range(kv.PlainState, nil, nil)
-		m := iter.UnionKV(it, it2)
+		m := iter.UnionKV(it, it2, -1)
 		require.False(m.HasNext())
 	})
 	t.Run("error handling", func(t *testing.T) {
@@ -130,7 +144,7 @@ func TestUnionPairs(t *testing.T) {
 		defer tx.Rollback()
 		it := iter.PairsWithError(10)
 		it2 := iter.PairsWithError(12)
-		keys, _, err := iter.ToKVArray(iter.UnionKV(it, it2))
+		keys, _, err := iter.ToKVArray(iter.UnionKV(it, it2, -1))
 		require.Equal("expected error at iteration: 10", err.Error())
 		require.Equal(10, len(keys))
 	})
@@ -400,3 +414,369 @@ func TestFiler(t *testing.T) {
 		require.Nil(t, res)
 	})
 }
+
+func TestMerge(t *testing.T) {
+	arr := func(asc order.By, a ...uint64) iter.Unary[uint64] {
+		if asc {
+			return iter.Array[uint64](a)
+		}
+		return iter.ReverseArray[uint64](a)
+	}
+	for _, asc := range []order.By{order.Asc, order.Desc} {
+		asc := asc
+		t.Run(fmt.Sprintf("unary asc=%t", asc), func(t *testing.T) {
+			s := iter.MergeUnary[uint64](asc, nil, arr(asc, 1, 3, 6), arr(asc, 2, 3, 7), nil, arr(asc), arr(asc, 0, 9))
+			res, err := iter.ToArr[uint64](s)
+			require.NoError(t, err)
+			expect := []uint64{0, 1, 2, 3, 3, 6, 7, 9}
+			if !asc {
+				expect = []uint64{9, 7, 6, 3, 3, 2, 1, 0}
+			}
+			require.Equal(t, expect, res)
+
+			res, err = iter.ToArr[uint64](iter.Dedup[uint64](iter.MergeU64(asc, arr(asc, 1, 3, 6), arr(asc, 2, 3, 7), arr(asc, 3))))
+			require.NoError(t, err)
+			expect = []uint64{1, 2, 3, 6, 7}
+			if !asc {
+				expect = []uint64{7, 6, 3, 2, 1}
+			}
+			require.Equal(t, expect, res)
+
+			res, err = iter.ToArr[uint64](iter.MergeU64(asc))
+			require.NoError(t, err)
+			require.Nil(t, res)
+		})
+		t.Run(fmt.Sprintf("kv asc=%t", asc), func(t *testing.T) {
+			kv := func(keys ...byte) iter.KV {
+				if !asc {
+					for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+						keys[i], keys[j] = keys[j], keys[i]
+					}
+				}
+				ks, vs := make([][]byte, len(keys)), make([][]byte, len(keys))
+				for i, k := range keys {
+					ks[i], vs[i] = []byte{k}, []byte{k}
+				}
+				return iter.ArrayKV(ks, vs)
+			}
+			withValue := func(it iter.KV, v byte) iter.KV {
+				return iter.TransformKV(it, func(k, _ []byte) ([]byte, []byte, error) { return k, []byte{v}, nil })
+			}
+			// newest stream (higher index) wins
+			newestFirst := func(i, j int) bool { return i > j }
+			s := iter.MergeKV(asc, newestFirst, withValue(kv(1, 2, 3), 0), withValue(kv(2, 4), 1), withValue(kv(2, 3), 2))
+			keys, values, err := iter.ToKVArray(s)
+			require.NoError(t, err)
+			expectK := [][]byte{{1}, {2}, {2}, {2}, {3}, {3}, {4}}
+			expectV := [][]byte{{0}, {2}, {1}, {0}, {2}, {0}, {1}}
+			if !asc {
+				expectK = [][]byte{{4}, {3}, {3}, {2}, {2}, {2}, {1}}
+				expectV = [][]byte{{1}, {2}, {0}, {2}, {1}, {0}, {0}}
+			}
+			require.Equal(t, expectK, keys)
+			require.Equal(t, expectV, values)
+
+			s = iter.MergeKV(asc, newestFirst, withValue(kv(1, 2, 3), 0), withValue(kv(2, 4), 1), withValue(kv(2, 3), 2))
+			keys, values, err = iter.ToKVArray(iter.DedupKV(s))
+			require.NoError(t, err)
+			expectK = [][]byte{{1}, {2}, {3}, {4}}
+			expectV = [][]byte{{0}, {2}, {2}, {1}}
+			if !asc {
+				expectK = [][]byte{{4}, {3}, {2}, {1}}
+				expectV = [][]byte{{1}, {2}, {2}, {0}}
+			}
+			require.Equal(t, expectK, keys)
+			require.Equal(t, expectV, values)
+		})
+	}
+	t.Run("error", func(t *testing.T) {
+		s := iter.MergeKV(order.Asc, nil, iter.PairsWithError(3), iter.EmptyKV)
+		keys, _, err := iter.ToKVArray(s)
+		require.Error(t, err)
+		require.Equal(t, 3, len(keys))
+	})
+}
+
+func TestAdapters(t *testing.T) {
+	for _, asc := range []order.By{order.Asc, order.Desc} {
+		asc := asc
+		arr := func(a ...uint64) iter.Unary[uint64] {
+			if asc {
+				return iter.Array[uint64](a)
+			}
+			return iter.ReverseArray[uint64](a)
+		}
+		// expect - returns `a` in order of stream
+		expect := func(a ...uint64) []uint64 {
+			if asc {
+				return a
+			}
+			res, _ := iter.ToArr[uint64](iter.ReverseArray[uint64](a))
+			return res
+		}
+		// kvArr - pairs k=v in order of stream
+		kvArr := func(keys ...byte) iter.KV {
+			ks := make([][]byte, len(keys))
+			for i := range keys {
+				if asc {
+					ks[i] = []byte{keys[i]}
+				} else {
+					ks[i] = []byte{keys[len(keys)-1-i]}
+				}
+			}
+			return iter.ArrayKV(ks, ks)
+		}
+		// expectKV - returns `keys` in order of stream
+		expectKV := func(keys ...byte) [][]byte {
+			res := make([][]byte, len(keys))
+			for i := range keys {
+				if asc {
+					res[i] = []byte{keys[i]}
+				} else {
+					res[i] = []byte{keys[len(keys)-1-i]}
+				}
+			}
+			return res
+		}
+		t.Run(fmt.Sprintf("limit and skip asc=%t", asc), func(t *testing.T) {
+			res, err := iter.ToArr[uint64](iter.Limit[uint64](arr(1, 2, 3, 4), 2))
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2, 3, 4)[:2], res)
+
+			res, err = iter.ToArr[uint64](iter.Skip[uint64](arr(1, 2, 3, 4), 3))
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2, 3, 4)[3:], res)
+
+			// pagination: skip+limit
+			res, err = iter.ToArr[uint64](iter.Limit[uint64](iter.Skip[uint64](arr(1, 2, 3, 4, 5), 1), 3))
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2, 3, 4, 5)[1:4], res)
+
+			res, err = iter.ToArr[uint64](iter.Skip[uint64](arr(1, 2), 5))
+			require.NoError(t, err)
+			require.Nil(t, res)
+			res, err = iter.ToArr[uint64](iter.Limit[uint64](arr(1, 2), 0))
+			require.NoError(t, err)
+			require.Nil(t, res)
+			res, err = iter.ToArr[uint64](iter.Limit[uint64](arr(1, 2), -1)) // unlimited
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2), res)
+
+			keys, _, err := iter.ToKVArray(iter.LimitKV(iter.SkipKV(kvArr(1, 2, 3, 4, 5), 1), 3))
+			require.NoError(t, err)
+			require.Equal(t, expectKV(1, 2, 3, 4, 5)[1:4], keys)
+			keys, _, err = iter.ToKVArray(iter.LimitKV(kvArr(1, 2, 3), -1))
+			require.NoError(t, err)
+			require.Equal(t, expectKV(1, 2, 3), keys)
+			keys, _, err = iter.ToKVArray(iter.LimitKV(kvArr(1, 2, 3), 0))
+			require.NoError(t, err)
+			require.Nil(t, keys)
+
+			n, err := iter.CountKV(iter.LimitKV(iter.SkipKV(iter.PairsWithError(10), 2), 3))
+			require.NoError(t, err)
+			require.Equal(t, 3, n)
+			_, err = iter.CountKV(iter.SkipKV(iter.PairsWithError(1), 2))
+			require.Error(t, err)
+		})
+		t.Run(fmt.Sprintf("chain asc=%t", asc), func(t *testing.T) {
+			var s *iter.ChainIter[uint64]
+			if asc {
+				s = iter.Chain[uint64](arr(1, 2), nil, iter.EmptyU64, arr(3), arr(4, 5))
+			} else {
+				s = iter.Chain[uint64](arr(4, 5), arr(3), iter.EmptyU64, nil, arr(1, 2))
+			}
+			res, err := iter.ToArr[uint64](s)
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2, 3, 4, 5), res)
+			_, err = s.Next()
+			require.ErrorIs(t, err, iter.ErrNoMoreItems)
+
+			var kvs *iter.ChainDualIter[[]byte, []byte]
+			if asc {
+				kvs = iter.ChainKV(kvArr(1, 2), iter.EmptyKV, kvArr(3))
+			} else {
+				kvs = iter.ChainKV(kvArr(3), iter.EmptyKV, kvArr(1, 2))
+			}
+			keys, _, err := iter.ToKVArray(kvs)
+			require.NoError(t, err)
+			require.Equal(t, expectKV(1, 2, 3), keys)
+			_, _, err = kvs.Next()
+			require.ErrorIs(t, err, iter.ErrNoMoreItems)
+		})
+		t.Run(fmt.Sprintf("take while asc=%t", asc), func(t *testing.T) {
+			res, err := iter.ToArr[uint64](iter.TakeWhile[uint64](arr(1, 2, 3, 4, 5), func(v uint64) bool {
+				if asc {
+					return v < 3
+				}
+				return v > 3
+			}))
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2, 3, 4, 5)[:2], res)
+
+			keys, _, err := iter.ToKVArray(iter.TakeWhileKV(kvArr(1, 2, 3, 4, 5), func(k, v []byte) bool {
+				if asc {
+					return k[0] < 3
+				}
+				return k[0] > 3
+			}))
+			require.NoError(t, err)
+			require.Equal(t, expectKV(1, 2, 3, 4, 5)[:2], keys)
+
+			keys, _, err = iter.ToKVArray(iter.TakeWhileKV(iter.PairsWithError(10), func(k, v []byte) bool { return !bytes.Equal(k, []byte("4")) }))
+			require.NoError(t, err)
+			require.Equal(t, 3, len(keys))
+			_, _, err = iter.ToKVArray(iter.TakeWhileKV(iter.PairsWithError(2), func(k, v []byte) bool { return true }))
+			require.Error(t, err)
+		})
+		t.Run(fmt.Sprintf("map asc=%t", asc), func(t *testing.T) {
+			res, err := iter.ToArr[string](iter.Map[uint64, string](arr(1, 2), func(v uint64) (string, error) { return fmt.Sprintf("%d", v), nil }))
+			require.NoError(t, err)
+			if asc {
+				require.Equal(t, []string{"1", "2"}, res)
+			} else {
+				require.Equal(t, []string{"2", "1"}, res)
+			}
+
+			s := iter.MapDual[[]byte, []byte, string, int](kvArr(1, 2), func(k, v []byte) (string, int, error) {
+				return fmt.Sprintf("%x", k), int(v[0]) + 2, nil
+			})
+			keys, values, err := iter.ToDualArray[string, int](s)
+			require.NoError(t, err)
+			if asc {
+				require.Equal(t, []string{"01", "02"}, keys)
+				require.Equal(t, []int{3, 4}, values)
+			} else {
+				require.Equal(t, []string{"02", "01"}, keys)
+				require.Equal(t, []int{4, 3}, values)
+			}
+		})
+		t.Run(fmt.Sprintf("chunk asc=%t", asc), func(t *testing.T) {
+			res, err := iter.ToArr[[]uint64](iter.Chunk[uint64](arr(1, 2, 3, 4, 5), 2))
+			require.NoError(t, err)
+			if asc {
+				require.Equal(t, [][]uint64{{1, 2}, {3, 4}, {5}}, res)
+			} else {
+				require.Equal(t, [][]uint64{{5, 4}, {3, 2}, {1}}, res)
+			}
+
+			s := arr(1, 2, 3)
+			batch, err := iter.NextBatch[uint64](s, 2)
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2, 3)[:2], batch)
+			batch, err = iter.NextBatch[uint64](s, 2)
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2, 3)[2:], batch)
+			batch, err = iter.NextBatch[uint64](s, 2)
+			require.NoError(t, err)
+			require.Nil(t, batch)
+		})
+		t.Run(fmt.Sprintf("dedup asc=%t", asc), func(t *testing.T) {
+			res, err := iter.ToArr[uint64](iter.Dedup[uint64](arr(1, 1, 2, 3, 3, 3)))
+			require.NoError(t, err)
+			require.Equal(t, expect(1, 2, 3), res)
+
+			keys, _, err := iter.ToKVArray(iter.DedupKV(kvArr(1, 1, 2, 3, 3)))
+			require.NoError(t, err)
+			require.Equal(t, expectKV(1, 2, 3), keys)
+		})
+	}
+}
+
+type leakTB struct {
+	testing.TB
+	cleanups []func()
+	errors   int
+}
+
+func (tb *leakTB) Helper()                           {}
+func (tb *leakTB) Cleanup(f func())                  { tb.cleanups = append(tb.cleanups, f) }
+func (tb *leakTB) Errorf(format string, args ...any) { tb.errors++ }
+func (tb *leakTB) finish() {
+	for _, f := range tb.cleanups {
+		f()
+	}
+}
+
+func TestClose(t *testing.T) {
+	t.Run("leak check", func(t *testing.T) {
+		tb := &leakTB{TB: t}
+		iter.TrackU64(tb, iter.EmptyU64)
+		closed := iter.TrackU64(tb, iter.EmptyU64)
+		iter.Close(closed)
+		tb.finish()
+		require.Equal(t, 1, tb.errors)
+	})
+	t.Run("union closes exhausted input", func(t *testing.T) {
+		x, y := iter.TrackU64(t, iter.EmptyU64), iter.TrackU64(t, iter.Array[uint64]([]uint64{1, 2}))
+		s := iter.Union[uint64](x, y, order.Asc)
+		defer iter.Close(s)
+		res, err := iter.ToArr[uint64](s)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{1, 2}, res)
+	})
+	t.Run("intersect with nil", func(t *testing.T) {
+		s := iter.Intersect[uint64](iter.TrackU64(t, iter.Array[uint64]([]uint64{1})), nil)
+		require.False(t, s.HasNext())
+	})
+	t.Run("through combinators", func(t *testing.T) {
+		x, y := iter.TrackKV(t, iter.PairsWithError(10)), iter.TrackKV(t, iter.EmptyKV)
+		it := iter.FilterKV(iter.TransformKV(iter.UnionKV(x, y, -1), func(k, v []byte) ([]byte, []byte, error) { return k, v, nil }), func(k, v []byte) bool { return true })
+		defer iter.Close(iter.LimitKV(iter.DedupKV(iter.MergeKV(order.Asc, nil, it)), 2))
+	})
+	t.Run("error propagation", func(t *testing.T) {
+		broken := iter.Map[uint64, uint64](iter.Range[uint64](0, 10), func(v uint64) (uint64, error) {
+			if v == 3 {
+				return 0, fmt.Errorf("broken")
+			}
+			return v, nil
+		})
+		res, err := iter.ToArr[uint64](iter.Intersect[uint64](broken, iter.Range[uint64](0, 10)))
+		require.Error(t, err)
+		require.Equal(t, []uint64{0, 1, 2}, res)
+	})
+}
+
+func TestWithContext(t *testing.T) {
+	for _, asc := range []order.By{order.Asc, order.Desc} {
+		asc := asc
+		t.Run(fmt.Sprintf("asc=%t", asc), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var upstream iter.U64 = iter.Range[uint64](0, 10)
+			if !asc {
+				upstream = iter.ReverseArray[uint64](iter.ToArrU64Must(upstream))
+			}
+			// upstream is closed by cancellation: tracker doesn't report leak
+			s := iter.WithContextU64(ctx, iter.TrackU64(t, upstream))
+			for i := 0; i < 2; i++ {
+				require.True(t, s.HasNext())
+				_, err := s.Next()
+				require.NoError(t, err)
+			}
+			cancel()
+			require.True(t, s.HasNext())
+			_, err := s.Next()
+			require.ErrorIs(t, err, context.Canceled)
+			_, err = s.Next()
+			require.ErrorIs(t, err, context.Canceled)
+
+			kvs := iter.WithContextKV(ctx, iter.PairsWithError(10))
+			_, err = iter.CountKV(kvs)
+			require.ErrorIs(t, err, context.Canceled)
+		})
+	}
+	t.Run("cancel after exhausted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := iter.WithContextU64(ctx, iter.Range[uint64](0, 2))
+		res, err := iter.ToArr[uint64](s)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{0, 1}, res)
+		cancel()
+		require.False(t, s.HasNext())
+
+		ctx, cancel = context.WithCancel(context.Background())
+		kvs := iter.WithContextKV(ctx, iter.EmptyKV)
+		cancel()
+		require.False(t, kvs.HasNext())
+	})
+}
@@ -0,0 +1,283 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package iter
+
+import (
+	"bytes"
+	"container/heap"
+
+	"github.com/ledgerwatch/erigon-lib/kv/order"
+	"golang.org/x/exp/constraints"
+)
+
+// TieBreak - order of equal keys from different streams: true if item of stream `i` must go before item of stream `j`.
+// nil means: stream with lower index goes first.
+type TieBreak func(i, j int) bool
+
+// MergedDual - k-way merge of sorted streams. Unlike Union it returns all items - also items with equal keys
+// (in order defined by TieBreak). Use DedupKV/Dedup on top of it to keep only first of equal keys:
+//
+//	// newest file wins
+//	it := iter.DedupKV(iter.MergeKV(order.Asc, func(i, j int) bool { return i > j }, files...))
+type MergedDual[K, V any] struct {
+	its []Dual[K, V]
+	h   mergeHeap[K, V]
+	err error
+}
+
+type mergeItem[K, V any] struct {
+	k K
+	v V
+	i int // index of stream
+}
+
+type mergeHeap[K, V any] struct {
+	items []mergeItem[K, V]
+	cmp   func(a, b K) int
+	asc   order.By
+	tie   TieBreak
+}
+
+func (h *mergeHeap[K, V]) Len() int      { return len(h.items) }
+func (h *mergeHeap[K, V]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *mergeHeap[K, V]) Less(i, j int) bool {
+	c := h.cmp(h.items[i].k, h.items[j].k)
+	if !h.asc {
+		c = -c
+	}
+	if c != 0 {
+		return c < 0
+	}
+	if h.tie == nil {
+		return h.items[i].i < h.items[j].i
+	}
+	return h.tie(h.items[i].i, h.items[j].i)
+}
+func (h *mergeHeap[K, V]) Push(x any) { h.items = append(h.items, x.(mergeItem[K, V])) }
+func (h *mergeHeap[K, V]) Pop() any {
+	old := h.items
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = mergeItem[K, V]{}
+	h.items = old[:n-1]
+	return x
+}
+
+// MergeDual - `cmp` returns -1, 0, +1. All streams must be sorted in `asc` order.
+func MergeDual[K, V any](cmp func(a, b K) int, asc order.By, tie TieBreak, its ...Dual[K, V]) *MergedDual[K, V] {
+	m := &MergedDual[K, V]{its: its, h: mergeHeap[K, V]{cmp: cmp, asc: asc, tie: tie, items: make([]mergeItem[K, V], 0, len(its))}}
+	for i := range its {
+		if its[i] == nil {
+			continue
+		}
+		if !m.push(i) {
+			break
+		}
+	}
+	heap.Init(&m.h)
+	return m
+}
+
+func MergeKV(asc order.By, tie TieBreak, its ...KV) *MergedDual[[]byte, []byte] {
+	duals := make([]Dual[[]byte, []byte], len(its))
+	for i := range its {
+		duals[i] = its[i]
+	}
+	return MergeDual[[]byte, []byte](bytes.Compare, asc, tie, duals...)
+}
+
+// push - reads next item of stream `i` to heap, returns false on error
+func (m *MergedDual[K, V]) push(i int) bool {
+	if !m.its[i].HasNext() {
+		return true
+	}
+	k, v, err := m.its[i].Next()
+	if err != nil {
+		m.err = err
+		return false
+	}
+	m.h.items = append(m.h.items, mergeItem[K, V]{k: k, v: v, i: i})
+	return true
+}
+
+func (m *MergedDual[K, V]) HasNext() bool { return m.err != nil || m.h.Len() > 0 }
+func (m *MergedDual[K, V]) Next() (k K, v V, err error) {
+	if m.err != nil {
+		return k, v, m.err
+	}
+	top := m.h.items[0]
+	if !m.its[top.i].HasNext() {
+		heap.Pop(&m.h)
+		return top.k, top.v, nil
+	}
+	nk, nv, err := m.its[top.i].Next()
+	if err != nil {
+		m.err = err // will be returned by next call
+		return top.k, top.v, nil
+	}
+	m.h.items[0] = mergeItem[K, V]{k: nk, v: nv, i: top.i}
+	heap.Fix(&m.h, 0)
+	return top.k, top.v, nil
+}
+func (m *MergedDual[K, V]) Close() {
+	for _, it := range m.its {
+		if x, ok := it.(Closer); ok {
+			x.Close()
+		}
+	}
+}
+
+// MergedUnary - k-way merge of sorted Unary streams, see MergedDual
+type MergedUnary[T any] struct {
+	m *MergedDual[T, struct{}]
+}
+
+func MergeUnary[T constraints.Ordered](asc order.By, tie TieBreak, its ...Unary[T]) *MergedUnary[T] {
+	duals := make([]Dual[T, struct{}], len(its))
+	for i := range its {
+		if its[i] != nil {
+			duals[i] = &unaryAsDual[T]{it: its[i]}
+		}
+	}
+	cmp := func(a, b T) int {
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+		return 0
+	}
+	return &MergedUnary[T]{m: MergeDual[T, struct{}](cmp, asc, tie, duals...)}
+}
+func MergeU64(asc order.By, its ...U64) *MergedUnary[uint64] {
+	unaries := make([]Unary[uint64], len(its))
+	for i := range its {
+		unaries[i] = its[i]
+	}
+	return MergeUnary[uint64](asc, nil, unaries...)
+}
+func (m *MergedUnary[T]) HasNext() bool { return m.m.HasNext() }
+func (m *MergedUnary[T]) Next() (T, error) {
+	k, _, err := m.m.Next()
+	return k, err
+}
+func (m *MergedUnary[T]) Close() { m.m.Close() }
+
+type unaryAsDual[T any] struct{ it Unary[T] }
+
+func (m *unaryAsDual[T]) HasNext() bool { return m.it.HasNext() }
+func (m *unaryAsDual[T]) Next() (T, struct{}, error) {
+	k, err := m.it.Next()
+	return k, struct{}{}, err
+}
+func (m *unaryAsDual[T]) Close() {
+	if x, ok := m.it.(Closer); ok {
+		x.Close()
+	}
+}
+
+// DedupIter - skips consecutive equal items: keeps first of them
+type DedupIter[T comparable] struct {
+	it      Unary[T]
+	hasNext bool
+	err     error
+	nextK   T
+}
+
+func Dedup[T comparable](it Unary[T]) *DedupIter[T] {
+	m := &DedupIter[T]{it: it}
+	m.hasNext = m.it.HasNext()
+	if m.hasNext {
+		m.nextK, m.err = m.it.Next()
+	}
+	return m
+}
+func (m *DedupIter[T]) advance(prev T) {
+	m.hasNext = false
+	for m.it.HasNext() {
+		k, err := m.it.Next()
+		if err != nil {
+			m.err = err
+			return
+		}
+		if k != prev {
+			m.hasNext, m.nextK = true, k
+			return
+		}
+	}
+}
+func (m *DedupIter[T]) HasNext() bool { return m.err != nil || m.hasNext }
+func (m *DedupIter[T]) Next() (k T, err error) {
+	if m.err != nil {
+		return k, m.err
+	}
+	k = m.nextK
+	m.advance(k)
+	return k, nil
+}
+func (m *DedupIter[T]) Close() {
+	if x, ok := m.it.(Closer); ok {
+		x.Close()
+	}
+}
+
+// DedupKVIter - skips consecutive items with equal keys: keeps first of them
+type DedupKVIter struct {
+	it           KV
+	hasNext      bool
+	err          error
+	nextK, nextV []byte
+	last         []byte // copy of last returned key: underlying stream may reuse memory
+}
+
+func DedupKV(it KV) *DedupKVIter {
+	m := &DedupKVIter{it: it}
+	m.hasNext = m.it.HasNext()
+	if m.hasNext {
+		m.nextK, m.nextV, m.err = m.it.Next()
+	}
+	return m
+}
+func (m *DedupKVIter) advance() {
+	m.hasNext = false
+	for m.it.HasNext() {
+		k, v, err := m.it.Next()
+		if err != nil {
+			m.err = err
+			return
+		}
+		if !bytes.Equal(k, m.last) {
+			m.hasNext, m.nextK, m.nextV = true, k, v
+			return
+		}
+	}
+}
+func (m *DedupKVIter) HasNext() bool { return m.err != nil || m.hasNext }
+func (m *DedupKVIter) Next() ([]byte, []byte, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	k, v := m.nextK, m.nextV
+	m.last = append(m.last[:0], k...)
+	m.advance()
+	return k, v, nil
+}
+func (m *DedupKVIter) Close() {
+	if x, ok := m.it.(Closer); ok {
+		x.Close()
+	}
+}
@@ -45,6 +45,7 @@ type CacheValidationResult struct {
 	LatestStateID      uint64
 	StateKeysOutOfSync [][]byte
 	CodeKeysOutOfSync  [][]byte
+	TableKeysOutOfSync map[string][][]byte // by table name, only registered tables
 }
 
 type Cache interface {
@@ -59,6 +60,20 @@ type CacheView interface {
 	GetCode(k []byte) ([]byte, error)
 }
 
+// TableCacheView - implemented by views of Coherent and DummyCache: reads tables registered in CoherentConfig.Tables.
+// Not part of CacheView - other implementations of CacheView don't have to support it, see GetTable.
+type TableCacheView interface {
+	GetTable(table string, k []byte) ([]byte, error)
+}
+
+// GetTable - reads `k` of `table` through `view` if it implements TableCacheView, otherwise from `tx`
+func GetTable(view CacheView, tx kv.Tx, table string, k []byte) ([]byte, error) {
+	if casted, ok := view.(TableCacheView); ok {
+		return casted.GetTable(table, k)
+	}
+	return tx.GetOne(table, k)
+}
+
 // Coherent works on top of Database Transaction and pair Coherent+ReadTransaction must
 // provide "Serializable Isolation Level" semantic: all data form consistent db view at moment
 // when read transaction started, read data are immutable until end of read transaction, reader can't see newer updates
@@ -110,8 +125,9 @@ type Coherent struct {
 	hits                 *metrics.Counter
 	codeHits             *metrics.Counter
 	roots                map[uint64]*CoherentRoot
-	stateEvict           *ThreadSafeEvictionList
-	codeEvict            *ThreadSafeEvictionList
+	stateEvict           EvictionPolicy
+	codeEvict            EvictionPolicy
+	tables               map[string]*tableCache // registered tables, immutable after New
 	miss                 *metrics.Counter
 	cfg                  CoherentConfig
 	latestStateVersionID uint64
@@ -122,6 +138,7 @@ type Coherent struct {
 type CoherentRoot struct {
 	cache           *btree2.BTreeG[*Element]
 	codeCache       *btree2.BTreeG[*Element]
+	tables          map[string]*btree2.BTreeG[*Element]
 	ready           chan struct{} // close when ready
 	readyChanClosed atomic.Bool   // protecting `ready` field from double-close (on unwind). Consumers don't need check this field.
 
@@ -144,9 +161,13 @@ func (c *CoherentView) Get(k []byte) ([]byte, error) { return c.cache.Get(k, c.t
 func (c *CoherentView) GetCode(k []byte) ([]byte, error) {
 	return c.cache.GetCode(k, c.tx, c.stateVersionID)
 }
+func (c *CoherentView) GetTable(table string, k []byte) ([]byte, error) {
+	return c.cache.GetTable(table, k, c.tx, c.stateVersionID)
+}
 
-var _ Cache = (*Coherent)(nil)         // compile-time interface check
-var _ CacheView = (*CoherentView)(nil) // compile-time interface check
+var _ Cache = (*Coherent)(nil)              // compile-time interface check
+var _ CacheView = (*CoherentView)(nil)      // compile-time interface check
+var _ TableCacheView = (*CoherentView)(nil) // compile-time interface check
 
 const (
 	DEGREE    = 32
@@ -161,6 +182,12 @@ type CoherentConfig struct {
 	MetricsLabel    string
 	NewBlockWait    time.Duration // how long wait
 	KeepViews       uint64        // keep in memory up to this amount of views, evict older
+	EvictionPolicy  string        // EvictLRU, Evict2Q or EvictLFU. Empty means EvictLRU
+
+	WarmRestartFile string // if not empty: Close saves hottest keys of latest state to this file, Preload reads them back
+	WarmRestartKeys int    // max amount of saved keys (separately for state and code). 0 means DefaultWarmRestartKeys
+
+	Tables []TableConfig // additional tables to cache, see TableConfig
 }
 
 var DefaultCoherentConfig = CoherentConfig{
@@ -171,17 +198,27 @@ var DefaultCoherentConfig = CoherentConfig{
 	MetricsLabel:    "default",
 	WithStorage:     true,
 	WaitForNewBlock: true,
+	EvictionPolicy:  EvictLRU,
 }
 
 func New(cfg CoherentConfig) *Coherent {
 	if cfg.KeepViews == 0 {
 		panic("empty config passed")
 	}
+	stateEvict, err := NewEvictionPolicy(cfg.EvictionPolicy, int(cfg.CacheSize.Bytes()))
+	if err != nil {
+		panic(err)
+	}
+	codeEvict, err := NewEvictionPolicy(cfg.EvictionPolicy, int(cfg.CodeCacheSize.Bytes()))
+	if err != nil {
+		panic(err)
+	}
 
 	return &Coherent{
 		roots:        map[uint64]*CoherentRoot{},
-		stateEvict:   &ThreadSafeEvictionList{l: NewList()},
-		codeEvict:    &ThreadSafeEvictionList{l: NewList()},
+		stateEvict:   stateEvict,
+		codeEvict:    codeEvict,
+		tables:       newTableCaches(cfg),
 		hasher:       sha3.NewLegacyKeccak256(),
 		cfg:          cfg,
 		miss:         metrics.GetOrCreateCounter(fmt.Sprintf(`cache_total{result="miss",name="%s"}`, cfg.MetricsLabel)),
@@ -209,6 +246,7 @@ func (c *Coherent) selectOrCreateRoot(versionID uint64) *CoherentRoot {
 		ready:     make(chan struct{}),
 		cache:     btree2.NewBTreeG[*Element](Less),
 		codeCache: btree2.NewBTreeG[*Element](Less),
+		tables:    c.newTableTrees(),
 	}
 	c.roots[versionID] = r
 	return r
@@ -232,9 +270,11 @@ func (c *Coherent) advanceRoot(stateVersionID uint64) (r *CoherentRoot) {
 		//log.Info("advance: clone", "from", viewID-1, "to", viewID)
 		r.cache = prevView.cache.Copy()
 		r.codeCache = prevView.codeCache.Copy()
+		r.tables = copyTableTrees(prevView.tables)
 	} else {
 		c.stateEvict.Init()
 		c.codeEvict.Init()
+		c.initTablesEvict(r)
 		if r.cache == nil {
 			//log.Info("advance: new", "to", viewID)
 			r.cache = btree2.NewBTreeG[*Element](Less)
@@ -242,13 +282,13 @@ func (c *Coherent) advanceRoot(stateVersionID uint64) (r *CoherentRoot) {
 		} else {
 			r.cache.Walk(func(items []*Element) bool {
 				for _, i := range items {
-					c.stateEvict.PushFront(i)
+					c.stateEvict.Add(i)
 				}
 				return true
 			})
 			r.codeCache.Walk(func(items []*Element) bool {
 				for _, i := range items {
-					c.codeEvict.PushFront(i)
+					c.codeEvict.Add(i)
 				}
 				return true
 			})
@@ -317,6 +357,7 @@ func (c *Coherent) OnNewBlock(stateChanges *remote.StateChangeBatch) {
 			}
 		}
 	}
+	c.onNewBlockTables(stateChanges, r, id)
 
 	switched := r.readyChanClosed.CAS(false, true)
 	if switched {
@@ -376,11 +417,14 @@ func (c *Coherent) getFromCache(k []byte, id uint64, code bool) (*Element, *Cohe
 	var it *Element
 	if code {
 		it, _ = r.codeCache.Get(&Element{K: k})
+		if it != nil && isLatest {
+			c.codeEvict.Touch(it)
+		}
 	} else {
 		it, _ = r.cache.Get(&Element{K: k})
-	}
-	if it != nil && isLatest {
-		c.stateEvict.MoveToFront(it)
+		if it != nil && isLatest {
+			c.stateEvict.Touch(it)
+		}
 	}
 
 	return it, r, nil
@@ -435,16 +479,12 @@ func (c *Coherent) GetCode(k []byte, tx kv.Tx, id uint64) ([]byte, error) {
 	return v, nil
 }
 func (c *Coherent) removeOldest(r *CoherentRoot) {
-	e := c.stateEvict.Oldest()
-	if e != nil {
-		c.stateEvict.Remove(e)
+	if e := c.stateEvict.Evict(); e != nil {
 		r.cache.Delete(e)
 	}
 }
 func (c *Coherent) removeOldestCode(r *CoherentRoot) {
-	e := c.codeEvict.Oldest()
-	if e != nil {
-		c.codeEvict.Remove(e)
+	if e := c.codeEvict.Evict(); e != nil {
 		r.codeCache.Delete(e)
 	}
 }
@@ -456,9 +496,10 @@ func (c *Coherent) add(k, v []byte, r *CoherentRoot, id uint64) *Element {
 		return it
 	}
 	if replaced != nil {
-		c.stateEvict.Remove(replaced)
+		c.stateEvict.Replace(replaced, it)
+	} else {
+		c.stateEvict.Add(it)
 	}
-	c.stateEvict.PushFront(it)
 
 	// clear down cache until size below the configured limit
 	for c.stateEvict.Size() > int(c.cfg.CacheSize.Bytes()) {
@@ -475,9 +516,10 @@ func (c *Coherent) addCode(k, v []byte, r *CoherentRoot, id uint64) *Element {
 		return it
 	}
 	if replaced != nil {
-		c.codeEvict.Remove(replaced)
+		c.codeEvict.Replace(replaced, it)
+	} else {
+		c.codeEvict.Add(it)
 	}
-	c.codeEvict.PushFront(it)
 
 	for c.codeEvict.Size() > int(c.cfg.CodeCacheSize.Bytes()) {
 		c.removeOldestCode(r)
@@ -584,6 +626,24 @@ func (c *Coherent) ValidateCurrentRoot(ctx context.Context, tx kv.Tx) (*CacheVal
 		return result, nil
 	}
 
+	tables := c.cloneTableCaches(root)
+	for _, table := range c.sortedTables() {
+		cancelled, keys, err = compare(tables[table], table)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			if result.TableKeysOutOfSync == nil {
+				result.TableKeysOutOfSync = map[string][][]byte{}
+			}
+			result.TableKeysOutOfSync[table] = keys
+		}
+		if cancelled {
+			result.RequestCancelled = true
+			return result, nil
+		}
+	}
+
 	if clearCache {
 		c.clearCaches(root)
 	}
@@ -600,11 +660,20 @@ func (c *Coherent) cloneCaches(r *CoherentRoot) (cache *btree2.BTreeG[*Element],
 	return cache, codeCache
 }
 
+func (c *Coherent) cloneTableCaches(r *CoherentRoot) map[string]*btree2.BTreeG[*Element] {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return copyTableTrees(r.tables)
+}
+
 func (c *Coherent) clearCaches(r *CoherentRoot) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	r.cache.Clear()
 	r.codeCache.Clear()
+	for _, tree := range r.tables {
+		tree.Clear()
+	}
 }
 
 type Stat struct {
@@ -713,6 +782,8 @@ type Element struct {
 
 	// The value stored with this element.
 	K, V []byte
+
+	freq uint32 // used by EvictLFU
 }
 
 func (e *Element) Size() int { return len(e.K) + len(e.V) }
@@ -747,6 +818,37 @@ func (l *ThreadSafeEvictionList) Remove(e *Element) {
 	l.lock.Unlock()
 }
 
+// Add, Touch, Replace, Evict, Walk - implementation of EvictLRU policy
+
+func (l *ThreadSafeEvictionList) Add(e *Element)   { l.PushFront(e) }
+func (l *ThreadSafeEvictionList) Touch(e *Element) { l.MoveToFront(e) }
+func (l *ThreadSafeEvictionList) Replace(old, e *Element) {
+	l.lock.Lock()
+	l.l.Remove(old)
+	l.l.PushFront(e)
+	l.lock.Unlock()
+}
+
+func (l *ThreadSafeEvictionList) Evict() *Element {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	e := l.l.Back()
+	if e != nil {
+		l.l.Remove(e)
+	}
+	return e
+}
+
+func (l *ThreadSafeEvictionList) Walk(f func(*Element) bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for e := l.l.Front(); e != nil; e = e.Next() {
+		if !f(e) {
+			return
+		}
+	}
+}
+
 func (l *ThreadSafeEvictionList) Oldest() *Element {
 	l.lock.Lock()
 	e := l.l.Back()
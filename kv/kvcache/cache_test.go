@@ -19,9 +19,12 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
@@ -372,3 +375,271 @@ func TestCode(t *testing.T) {
 		return nil
 	})
 }
+
+func TestEvictionPolicies(t *testing.T) {
+	el := func(k byte) *Element { return &Element{K: []byte{k}, V: []byte{k}} }
+	evictAll := func(p EvictionPolicy) (res []byte) {
+		for e := p.Evict(); e != nil; e = p.Evict() {
+			res = append(res, e.K[0])
+		}
+		return res
+	}
+	hottest := func(p EvictionPolicy) (res []byte) {
+		p.Walk(func(e *Element) bool {
+			res = append(res, e.K[0])
+			return true
+		})
+		return res
+	}
+
+	t.Run("lru", func(t *testing.T) {
+		p, err := NewEvictionPolicy(EvictLRU, 100)
+		require.NoError(t, err)
+		a, b, c := el(1), el(2), el(3)
+		p.Add(a)
+		p.Add(b)
+		p.Add(c)
+		p.Touch(a)
+		b2 := el(2)
+		p.Replace(b, b2)
+		require.Equal(t, 6, p.Size())
+		require.Equal(t, []byte{2, 1, 3}, hottest(p))
+		require.Equal(t, []byte{3, 1, 2}, evictAll(p))
+	})
+	t.Run("2q", func(t *testing.T) {
+		p, err := NewEvictionPolicy(Evict2Q, 8)
+		require.NoError(t, err)
+		a, b, c := el(1), el(2), el(3)
+		p.Add(a)
+		p.Add(b)
+		p.Add(c)
+		p.Touch(a) // seen twice - goes to frequent queue
+		require.Equal(t, []byte{1, 3, 2}, hottest(p))
+		require.Equal(t, byte(2), p.Evict().K[0]) // recent queue is bigger than 1/4 of cache
+		// evicted key comes back soon - goes to frequent queue
+		p.Add(el(2))
+		p.Add(el(4))
+		require.Equal(t, []byte{2, 1, 4, 3}, hottest(p))
+		require.Equal(t, byte(3), p.Evict().K[0])
+		require.Equal(t, []byte{1, 2, 4}, evictAll(p))
+		require.Equal(t, 0, p.Len())
+	})
+	t.Run("lfu", func(t *testing.T) {
+		p, err := NewEvictionPolicy(EvictLFU, 100)
+		require.NoError(t, err)
+		a, b, c := el(1), el(2), el(3)
+		p.Add(a)
+		p.Add(b)
+		p.Add(c)
+		p.Touch(a)
+		p.Touch(a)
+		p.Touch(c)
+		c2 := el(3)
+		p.Replace(c, c2) // keeps frequency
+		require.Equal(t, []byte{1, 3, 2}, hottest(p))
+		require.Equal(t, 6, p.Size())
+		require.Equal(t, []byte{2, 3, 1}, evictAll(p))
+		require.Equal(t, 0, p.Size())
+	})
+	t.Run("unknown", func(t *testing.T) {
+		_, err := NewEvictionPolicy("fifo", 100)
+		require.Error(t, err)
+	})
+
+	for _, policy := range []string{EvictLRU, Evict2Q, EvictLFU} {
+		t.Run("coherent "+policy, func(t *testing.T) {
+			cfg := DefaultCoherentConfig
+			cfg.CacheSize = 21 * 8
+			cfg.NewBlockWait = 0
+			cfg.EvictionPolicy = policy
+			c := New(cfg)
+			c.advanceRoot(1)
+			r := c.roots[1]
+			for i := 0; i < 10; i++ {
+				k := [20]byte{byte(i)}
+				c.add(k[:], []byte{byte(i)}, r, 1)
+				it, _, err := c.getFromCache(k[:], 1, false)
+				require.NoError(t, err)
+				require.NotNil(t, it)
+				require.LessOrEqual(t, c.stateEvict.Size(), int(cfg.CacheSize.Bytes()))
+				require.Equal(t, r.cache.Len(), c.stateEvict.Len())
+			}
+			require.Equal(t, 8, c.stateEvict.Len())
+		})
+	}
+}
+
+func TestWarmRestart(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+	cfg := DefaultCoherentConfig
+	cfg.NewBlockWait = 0
+	cfg.WarmRestartFile = filepath.Join(t.TempDir(), "kvcache_warm")
+	cfg.WarmRestartKeys = 3
+
+	var id uint64
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for i := 0; i < 5; i++ {
+			k := [20]byte{byte(i)}
+			if err := tx.Put(kv.PlainState, k[:], []byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		if err := tx.Put(kv.Code, []byte{0xc}, []byte{0xc0, 0xde}); err != nil {
+			return err
+		}
+		id = tx.ViewID()
+		var versionID [8]byte
+		binary.BigEndian.PutUint64(versionID[:], id)
+		return tx.Put(kv.Sequence, kv.PlainStateVersion, versionID[:])
+	}))
+
+	c := New(cfg)
+	c.OnNewBlock(&remote.StateChangeBatch{StateVersionID: id})
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		for i := 0; i < 5; i++ {
+			k := [20]byte{byte(i)}
+			if _, err := c.Get(k[:], tx, id); err != nil {
+				return err
+			}
+		}
+		_, err := c.GetCode([]byte{0xc}, tx, id)
+		return err
+	}))
+	require.NoError(t, c.Close())
+
+	// hottest keys are restored, values are read from db
+	c2 := New(cfg)
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		n, err := c2.Preload(ctx, tx)
+		require.NoError(t, err)
+		require.Equal(t, 4, n)
+		return nil
+	}))
+	require.Equal(t, 3, c2.Len())
+	for i := 2; i < 5; i++ {
+		k := [20]byte{byte(i)}
+		it, _, err := c2.getFromCache(k[:], id, false)
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i)}, it.V)
+	}
+	it, _, err := c2.getFromCache([]byte{0xc}, id, true)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xc0, 0xde}, it.V)
+
+	// view doesn't wait for OnNewBlock: preloaded root is ready
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		view, err := c2.View(ctx, tx)
+		require.NoError(t, err)
+		v, err := view.Get([]byte{4})
+		require.NoError(t, err)
+		require.Nil(t, v)
+		return nil
+	}))
+
+	// broken file
+	require.NoError(t, os.WriteFile(cfg.WarmRestartFile, []byte("kvcwarm1garbage"), 0644))
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		_, err := New(cfg).Preload(ctx, tx)
+		require.ErrorIs(t, err, ErrWarmRestartFile)
+		return nil
+	}))
+}
+
+func TestTables(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+	cfg := DefaultCoherentConfig
+	cfg.NewBlockWait = 0
+	cfg.Tables = []TableConfig{
+		{Table: kv.HeaderCanonical, CacheSize: datasize.MB, Changes: CanonicalHashChanges},
+		{Table: kv.Receipts, CacheSize: datasize.MB, Changes: BlockNumInvalidation},
+	}
+	c := New(cfg)
+	num1 := []byte{0, 0, 0, 0, 0, 0, 0, 1}
+	hash1, hash2 := [32]byte{1}, [32]byte{2}
+
+	put := func(hash [32]byte, receipt []byte) (id uint64) {
+		require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+			if err := tx.Put(kv.HeaderCanonical, num1, hash[:]); err != nil {
+				return err
+			}
+			if err := tx.Put(kv.Receipts, num1, receipt); err != nil {
+				return err
+			}
+			if err := tx.Put(kv.Headers, num1, receipt); err != nil {
+				return err
+			}
+			id = tx.ViewID()
+			var versionID [8]byte
+			binary.BigEndian.PutUint64(versionID[:], id)
+			return tx.Put(kv.Sequence, kv.PlainStateVersion, versionID[:])
+		}))
+		return id
+	}
+	cached := func(table string, id uint64) []byte {
+		it, _, err := c.getFromTable(c.tables[table], num1, id)
+		require.NoError(t, err)
+		if it == nil {
+			return nil
+		}
+		return it.V
+	}
+
+	id1 := put(hash1, []byte{1})
+	c.OnNewBlock(&remote.StateChangeBatch{StateVersionID: id1})
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		view, err := c.View(ctx, tx)
+		require.NoError(t, err)
+		tableView, ok := view.(TableCacheView)
+		require.True(t, ok)
+		v, err := tableView.GetTable(kv.HeaderCanonical, num1)
+		require.NoError(t, err)
+		require.Equal(t, hash1[:], v)
+		v, err = tableView.GetTable(kv.Receipts, num1)
+		require.NoError(t, err)
+		require.Equal(t, []byte{1}, v)
+		v, err = GetTable(view, tx, kv.Headers, num1) // not registered
+		require.NoError(t, err)
+		require.Equal(t, []byte{1}, v)
+		return nil
+	}))
+	require.Equal(t, hash1[:], cached(kv.HeaderCanonical, id1))
+	require.Equal(t, []byte{1}, cached(kv.Receipts, id1))
+	require.Equal(t, 1, c.tables[kv.Receipts].evict.Len())
+
+	// reorg of block 1: canonical hash comes with notification, receipt is invalidated
+	id2 := put(hash2, []byte{2})
+	c.OnNewBlock(&remote.StateChangeBatch{
+		StateVersionID: id2,
+		ChangeBatch: []*remote.StateChange{
+			{Direction: remote.Direction_UNWIND, BlockHeight: 1, BlockHash: gointerfaces.ConvertHashToH256(hash1)},
+			{Direction: remote.Direction_FORWARD, BlockHeight: 1, BlockHash: gointerfaces.ConvertHashToH256(hash2)},
+		},
+	})
+	require.Equal(t, hash2[:], cached(kv.HeaderCanonical, id2))
+	require.Nil(t, cached(kv.Receipts, id2))
+	require.Equal(t, 0, c.tables[kv.Receipts].evict.Len())
+	require.Equal(t, []byte{1}, cached(kv.Receipts, id1)) // older view is not affected
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		view, err := c.View(ctx, tx)
+		require.NoError(t, err)
+		v, err := GetTable(view, tx, kv.Receipts, num1)
+		require.NoError(t, err)
+		require.Equal(t, []byte{2}, v)
+		return nil
+	}))
+
+	// change without notification is found by validation
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.Receipts, num1, []byte{3})
+	}))
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		res, err := c.ValidateCurrentRoot(ctx, tx)
+		require.NoError(t, err)
+		require.True(t, res.CacheCleared)
+		require.Equal(t, map[string][][]byte{kv.Receipts: {num1}}, res.TableKeysOutOfSync)
+		return nil
+	}))
+	require.Nil(t, cached(kv.HeaderCanonical, id2))
+}
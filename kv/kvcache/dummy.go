@@ -25,8 +25,9 @@ import (
 // DummyCache - doesn't remember anything - can be used when service is not remote
 type DummyCache struct{}
 
-var _ Cache = (*DummyCache)(nil)    // compile-time interface check
-var _ CacheView = (*DummyView)(nil) // compile-time interface check
+var _ Cache = (*DummyCache)(nil)         // compile-time interface check
+var _ CacheView = (*DummyView)(nil)      // compile-time interface check
+var _ TableCacheView = (*DummyView)(nil) // compile-time interface check
 
 func NewDummy() *DummyCache { return &DummyCache{} }
 func (c *DummyCache) View(_ context.Context, tx kv.Tx) (CacheView, error) {
@@ -41,6 +42,9 @@ func (c *DummyCache) Get(k []byte, tx kv.Tx, id uint64) ([]byte, error) {
 func (c *DummyCache) GetCode(k []byte, tx kv.Tx, id uint64) ([]byte, error) {
 	return tx.GetOne(kv.Code, k)
 }
+func (c *DummyCache) GetTable(table string, k []byte, tx kv.Tx, id uint64) ([]byte, error) {
+	return tx.GetOne(table, k)
+}
 func (c *DummyCache) ValidateCurrentRoot(_ context.Context, _ kv.Tx) (*CacheValidationResult, error) {
 	return &CacheValidationResult{Enabled: false}, nil
 }
@@ -52,3 +56,6 @@ type DummyView struct {
 
 func (c *DummyView) Get(k []byte) ([]byte, error)     { return c.cache.Get(k, c.tx, 0) }
 func (c *DummyView) GetCode(k []byte) ([]byte, error) { return c.cache.GetCode(k, c.tx, 0) }
+func (c *DummyView) GetTable(table string, k []byte) ([]byte, error) {
+	return c.cache.GetTable(table, k, c.tx, 0)
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2023 Erigon contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kvcache
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	EvictLRU = "lru" // least recently used
+	Evict2Q  = "2q"  // keys seen once are evicted before keys seen many times (scan-resistant LRU)
+	EvictLFU = "lfu" // least frequently used, frequencies are halved periodically
+)
+
+// EvictionPolicy - decides which element of latest root to evict when cache exceeds size limit.
+// Tracks only elements of latest canonical root. Coherent calls it under own lock.
+type EvictionPolicy interface {
+	Init()                      // forget all elements
+	Add(e *Element)             // new element
+	Touch(e *Element)           // cache hit
+	Replace(old, e *Element)    // value of key changed: `e` inherits hotness of `old`
+	Remove(e *Element)          // key is invalidated, does nothing if `e` is not tracked
+	Evict() *Element            // removes and returns next victim, nil if empty
+	Walk(f func(*Element) bool) // from hottest to coldest element, stops if f returns false
+	Len() int
+	Size() int // sum of Element.Size()
+}
+
+// NewEvictionPolicy - `maxSize` is size limit of cache in bytes
+func NewEvictionPolicy(name string, maxSize int) (EvictionPolicy, error) {
+	switch name {
+	case EvictLRU, "":
+		return &ThreadSafeEvictionList{l: NewList()}, nil
+	case Evict2Q:
+		return newTwoQueue(maxSize), nil
+	case EvictLFU:
+		return newLFU(), nil
+	default:
+		return nil, fmt.Errorf("kvcache: unknown eviction policy %q", name)
+	}
+}
+
+// twoQueue - simplified 2Q: new elements go to `recent` FIFO, elements hit again - to `frequent` LRU.
+// Victims are taken from `recent` while it's bigger than 1/4 of cache. Keys evicted from `recent` are remembered
+// in `ghost` - if key comes back soon, it goes directly to `frequent`.
+type twoQueue struct {
+	maxSize  int
+	recent   List
+	frequent List
+
+	ghost  map[string]struct{}
+	ghostQ []string
+}
+
+func newTwoQueue(maxSize int) *twoQueue {
+	q := &twoQueue{maxSize: maxSize}
+	q.Init()
+	return q
+}
+
+func (q *twoQueue) Init() {
+	q.recent.Init()
+	q.frequent.Init()
+	q.ghost = map[string]struct{}{}
+	q.ghostQ = nil
+}
+
+func (q *twoQueue) Add(e *Element) {
+	if _, ok := q.ghost[string(e.K)]; ok {
+		delete(q.ghost, string(e.K))
+		q.frequent.PushFront(e)
+		return
+	}
+	q.recent.PushFront(e)
+}
+
+func (q *twoQueue) Touch(e *Element) {
+	switch e.list {
+	case &q.frequent:
+		q.frequent.MoveToFront(e)
+	case &q.recent:
+		q.recent.Remove(e)
+		q.frequent.PushFront(e)
+	}
+}
+
+func (q *twoQueue) Replace(old, e *Element) {
+	switch old.list {
+	case &q.frequent:
+		q.frequent.Remove(old)
+		q.frequent.PushFront(e)
+	case &q.recent:
+		q.recent.Remove(old)
+		q.recent.PushFront(e)
+	default:
+		q.Add(e)
+	}
+}
+
+func (q *twoQueue) Remove(e *Element) {
+	q.recent.Remove(e)
+	q.frequent.Remove(e)
+}
+
+func (q *twoQueue) Evict() *Element {
+	if q.recent.Len() > 0 && (q.recent.Size() > q.maxSize/4 || q.frequent.Len() == 0) {
+		e := q.recent.Back()
+		q.recent.Remove(e)
+		q.addGhost(string(e.K))
+		return e
+	}
+	e := q.frequent.Back()
+	if e != nil {
+		q.frequent.Remove(e)
+	}
+	return e
+}
+
+// addGhost - ghost keeps up to Len()/2 keys
+func (q *twoQueue) addGhost(k string) {
+	q.ghost[k] = struct{}{}
+	q.ghostQ = append(q.ghostQ, k)
+	for len(q.ghost) > q.Len()/2+1 && len(q.ghostQ) > 0 {
+		delete(q.ghost, q.ghostQ[0])
+		q.ghostQ = q.ghostQ[1:]
+	}
+	if cap(q.ghostQ) > 4*len(q.ghostQ)+1024 { // amortized shrink
+		q.ghostQ = append([]string(nil), q.ghostQ...)
+	}
+}
+
+func (q *twoQueue) Walk(f func(*Element) bool) {
+	for _, l := range []*List{&q.frequent, &q.recent} {
+		for e := l.Front(); e != nil; e = e.Next() {
+			if !f(e) {
+				return
+			}
+		}
+	}
+}
+
+func (q *twoQueue) Len() int  { return q.recent.Len() + q.frequent.Len() }
+func (q *twoQueue) Size() int { return q.recent.Size() + q.frequent.Size() }
+
+// lfu - elements are grouped by frequency of hits, every group is LRU.
+// New element starts with lowest frequency in cache - otherwise it would be next victim and cache full of hot keys
+// would never accept new ones. To let old hot keys go: after every 8*Len() hits all frequencies are halved.
+type lfu struct {
+	buckets map[uint32]*List
+	minFreq uint32
+	len     int
+	size    int
+	hits    int
+}
+
+func newLFU() *lfu {
+	l := &lfu{}
+	l.Init()
+	return l
+}
+
+func (l *lfu) Init() {
+	l.buckets = map[uint32]*List{}
+	l.minFreq, l.len, l.size, l.hits = 0, 0, 0, 0
+}
+
+func (l *lfu) bucket(freq uint32) *List {
+	b, ok := l.buckets[freq]
+	if !ok {
+		b = NewList()
+		l.buckets[freq] = b
+	}
+	return b
+}
+
+func (l *lfu) push(e *Element) {
+	l.bucket(e.freq).PushFront(e)
+	if l.minFreq == 0 || e.freq < l.minFreq {
+		l.minFreq = e.freq
+	}
+}
+
+func (l *lfu) min() uint32 {
+	if l.minFreq == 0 {
+		for freq := range l.buckets {
+			if l.minFreq == 0 || freq < l.minFreq {
+				l.minFreq = freq
+			}
+		}
+	}
+	return l.minFreq
+}
+
+func (l *lfu) remove(e *Element) {
+	b := e.list
+	b.Remove(e)
+	if b.Len() == 0 {
+		delete(l.buckets, e.freq)
+		if e.freq == l.minFreq {
+			l.minFreq = 0 // will be found by Evict
+		}
+	}
+}
+
+func (l *lfu) Add(e *Element) {
+	e.freq = l.min()
+	if e.freq == 0 {
+		e.freq = 1
+	}
+	l.push(e)
+	l.len++
+	l.size += e.Size()
+}
+
+func (l *lfu) Touch(e *Element) {
+	if e.list == nil || l.buckets[e.freq] != e.list {
+		return
+	}
+	l.remove(e)
+	e.freq++
+	l.push(e)
+	l.hits++
+	if l.hits > 8*l.len+1024 {
+		l.age()
+	}
+}
+
+func (l *lfu) Replace(old, e *Element) {
+	if old.list == nil || l.buckets[old.freq] != old.list {
+		l.Add(e)
+		return
+	}
+	l.size += e.Size() - old.Size()
+	e.freq = old.freq
+	b := old.list
+	b.Remove(old)
+	b.PushFront(e)
+}
+
+func (l *lfu) Remove(e *Element) {
+	if e.list == nil || l.buckets[e.freq] != e.list {
+		return
+	}
+	l.remove(e)
+	l.len--
+	l.size -= e.Size()
+}
+
+func (l *lfu) Evict() *Element {
+	if l.len == 0 {
+		return nil
+	}
+	e := l.buckets[l.min()].Back()
+	l.remove(e)
+	l.len--
+	l.size -= e.Size()
+	return e
+}
+
+// age - halves all frequencies, keeps order of elements inside of bucket
+func (l *lfu) age() {
+	l.hits = 0
+	old := l.buckets
+	l.buckets = make(map[uint32]*List, len(old))
+	l.minFreq = 0
+	for _, freq := range l.freqs(old) {
+		b := old[freq]
+		for e := b.Back(); e != nil; e = b.Back() {
+			b.Remove(e)
+			e.freq = freq/2 + 1
+			l.push(e)
+		}
+	}
+}
+
+// freqs - sorted from lowest
+func (l *lfu) freqs(buckets map[uint32]*List) []uint32 {
+	res := make([]uint32, 0, len(buckets))
+	for freq := range buckets {
+		res = append(res, freq)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func (l *lfu) Walk(f func(*Element) bool) {
+	freqs := l.freqs(l.buckets)
+	for i := len(freqs) - 1; i >= 0; i-- {
+		for e := l.buckets[freqs[i]].Front(); e != nil; e = e.Next() {
+			if !f(e) {
+				return
+			}
+		}
+	}
+}
+
+func (l *lfu) Len() int  { return l.len }
+func (l *lfu) Size() int { return l.size }
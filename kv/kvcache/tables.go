@@ -0,0 +1,253 @@
+/*
+Copyright 2023 Erigon contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kvcache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/c2h5oh/datasize"
+	btree2 "github.com/tidwall/btree"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// Registered tables: Coherent can cache any table, not only PlainState and Code. Every registered table
+// has own btree in every CoherentRoot (same versioning as PlainState: View(ctx, tx) sees only data of tx's version)
+// and own eviction policy and size limit.
+//
+// Cache of table is coherent only if every change of table is known to OnNewBlock: TableConfig.Changes
+// extracts them from StateChangeBatch. Table without Changes must be immutable by key (like kv.Headers: block_num_u64+hash).
+
+// TableChange - change of registered table in new state version. Value == nil is a marker of absent key in db.
+type TableChange struct {
+	Key, Value []byte
+	Invalidate bool // new value is unknown: forget key, next read will go to db
+}
+
+// TableChangesSource - extracts changes of table from StateChangeBatch. Called by OnNewBlock under cache lock - must not block.
+type TableChangesSource func(sc *remote.StateChangeBatch) []TableChange
+
+type TableConfig struct {
+	Table     string
+	CacheSize datasize.ByteSize
+	Changes   TableChangesSource // nil means table is immutable by key
+}
+
+type tableCache struct {
+	cfg   TableConfig
+	evict EvictionPolicy
+	hits  *metrics.Counter
+	miss  *metrics.Counter
+	keys  *metrics.Counter
+}
+
+func newTableCaches(cfg CoherentConfig) map[string]*tableCache {
+	res := make(map[string]*tableCache, len(cfg.Tables))
+	for _, tcfg := range cfg.Tables {
+		if tcfg.Table == kv.PlainState || tcfg.Table == kv.Code {
+			panic(fmt.Sprintf("kvcache: table %s is cached by default", tcfg.Table))
+		}
+		if _, ok := res[tcfg.Table]; ok {
+			panic(fmt.Sprintf("kvcache: table %s registered twice", tcfg.Table))
+		}
+		if tcfg.CacheSize == 0 {
+			panic(fmt.Sprintf("kvcache: table %s has empty CacheSize", tcfg.Table))
+		}
+		evict, err := NewEvictionPolicy(cfg.EvictionPolicy, int(tcfg.CacheSize.Bytes()))
+		if err != nil {
+			panic(err)
+		}
+		res[tcfg.Table] = &tableCache{
+			cfg:   tcfg,
+			evict: evict,
+			hits:  metrics.GetOrCreateCounter(fmt.Sprintf(`cache_table_total{result="hit",name="%s",table="%s"}`, cfg.MetricsLabel, tcfg.Table)),
+			miss:  metrics.GetOrCreateCounter(fmt.Sprintf(`cache_table_total{result="miss",name="%s",table="%s"}`, cfg.MetricsLabel, tcfg.Table)),
+			keys:  metrics.GetOrCreateCounter(fmt.Sprintf(`cache_table_keys_total{name="%s",table="%s"}`, cfg.MetricsLabel, tcfg.Table)),
+		}
+	}
+	return res
+}
+
+// sortedTables - registered tables in deterministic order
+func (c *Coherent) sortedTables() []string {
+	res := make([]string, 0, len(c.tables))
+	for table := range c.tables {
+		res = append(res, table)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (c *Coherent) newTableTrees() map[string]*btree2.BTreeG[*Element] {
+	if len(c.tables) == 0 {
+		return nil
+	}
+	res := make(map[string]*btree2.BTreeG[*Element], len(c.tables))
+	for table := range c.tables {
+		res[table] = btree2.NewBTreeG[*Element](Less)
+	}
+	return res
+}
+
+func copyTableTrees(from map[string]*btree2.BTreeG[*Element]) map[string]*btree2.BTreeG[*Element] {
+	if from == nil {
+		return nil
+	}
+	res := make(map[string]*btree2.BTreeG[*Element], len(from))
+	for table, tree := range from {
+		res[table] = tree.Copy()
+	}
+	return res
+}
+
+// initTablesEvict - must be called when root is not cloned from canonical parent: eviction policies start from scratch
+func (c *Coherent) initTablesEvict(r *CoherentRoot) {
+	for table, t := range c.tables {
+		t.evict.Init()
+		if r.tables == nil {
+			continue
+		}
+		r.tables[table].Walk(func(items []*Element) bool {
+			for _, i := range items {
+				t.evict.Add(i)
+			}
+			return true
+		})
+	}
+	if r.tables == nil {
+		r.tables = c.newTableTrees()
+	}
+}
+
+// onNewBlockTables - applies changes of registered tables to new root `r`
+func (c *Coherent) onNewBlockTables(sc *remote.StateChangeBatch, r *CoherentRoot, id uint64) {
+	for table, t := range c.tables {
+		if t.cfg.Changes == nil {
+			continue
+		}
+		for _, change := range t.cfg.Changes(sc) {
+			if change.Invalidate {
+				c.invalidateTable(t, change.Key, r, id)
+				continue
+			}
+			c.addTable(t, change.Key, change.Value, r, id)
+		}
+		t.keys.Set(uint64(r.tables[table].Len()))
+	}
+}
+
+func (c *Coherent) addTable(t *tableCache, k, v []byte, r *CoherentRoot, id uint64) *Element {
+	tree := r.tables[t.cfg.Table]
+	it := &Element{K: k, V: v}
+	replaced, _ := tree.Set(it)
+	if c.latestStateVersionID != id {
+		return it
+	}
+	if replaced != nil {
+		t.evict.Replace(replaced, it)
+	} else {
+		t.evict.Add(it)
+	}
+	for t.evict.Size() > int(t.cfg.CacheSize.Bytes()) {
+		if e := t.evict.Evict(); e != nil {
+			tree.Delete(e)
+		}
+	}
+	return it
+}
+
+func (c *Coherent) invalidateTable(t *tableCache, k []byte, r *CoherentRoot, id uint64) {
+	removed, ok := r.tables[t.cfg.Table].Delete(&Element{K: k})
+	if !ok || c.latestStateVersionID != id {
+		return
+	}
+	t.evict.Remove(removed)
+}
+
+func (c *Coherent) getFromTable(t *tableCache, k []byte, id uint64) (*Element, *CoherentRoot, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	r, ok := c.roots[id]
+	if !ok {
+		return nil, r, fmt.Errorf("too old ViewID: %d, latestStateVersionID=%d", id, c.latestStateVersionID)
+	}
+	it, _ := r.tables[t.cfg.Table].Get(&Element{K: k})
+	if it != nil && c.latestStateVersionID == id {
+		t.evict.Touch(it)
+	}
+	return it, r, nil
+}
+
+// GetTable - value of `k` in `table` at state version `id`. Tables not registered in CoherentConfig.Tables are read from `tx`.
+func (c *Coherent) GetTable(table string, k []byte, tx kv.Tx, id uint64) ([]byte, error) {
+	t, ok := c.tables[table]
+	if !ok {
+		return tx.GetOne(table, k)
+	}
+	it, r, err := c.getFromTable(t, k, id)
+	if err != nil {
+		return nil, err
+	}
+	if it != nil {
+		t.hits.Inc()
+		return it.V, nil
+	}
+	t.miss.Inc()
+
+	v, err := tx.GetOne(table, k)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	v = c.addTable(t, common.Copy(k), common.Copy(v), r, id).V
+	return v, nil
+}
+
+// CanonicalHashChanges - TableChangesSource for kv.HeaderCanonical
+func CanonicalHashChanges(sc *remote.StateChangeBatch) []TableChange {
+	res := make([]TableChange, 0, len(sc.ChangeBatch))
+	for _, change := range sc.ChangeBatch {
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, change.BlockHeight)
+		if change.Direction == remote.Direction_FORWARD && change.BlockHash != nil {
+			hash := gointerfaces.ConvertH256ToHash(change.BlockHash)
+			res = append(res, TableChange{Key: k, Value: hash[:]})
+			continue
+		}
+		res = append(res, TableChange{Key: k, Invalidate: true})
+	}
+	return res
+}
+
+// BlockNumInvalidation - TableChangesSource for tables with block_num_u64 key (like kv.Receipts):
+// forgets keys of all blocks mentioned in batch
+func BlockNumInvalidation(sc *remote.StateChangeBatch) []TableChange {
+	res := make([]TableChange, 0, len(sc.ChangeBatch))
+	for _, change := range sc.ChangeBatch {
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, change.BlockHeight)
+		res = append(res, TableChange{Key: k, Invalidate: true})
+	}
+	return res
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2023 Erigon contributors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kvcache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// Warm restart: cache is empty after restart and it takes long time to reach steady hit-rate.
+// Close saves keys (not values) of latest state in order of hotness, Preload reads their values from db
+// in given tx - so preloaded cache is coherent with db even if file is older than db.
+//
+// File format: magic, uvarint stateVersionID, uvarint amount of state keys, state keys,
+// uvarint amount of code keys, code keys, crc32c of all previous bytes. Key is: uvarint len, bytes.
+
+const DefaultWarmRestartKeys = 100_000
+
+const warmRestartMagic = "kvcwarm1"
+
+var ErrWarmRestartFile = errors.New("kvcache: broken warm restart file")
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// Close - saves hottest keys to CoherentConfig.WarmRestartFile (if configured)
+func (c *Coherent) Close() error {
+	if c.cfg.WarmRestartFile == "" {
+		return nil
+	}
+	limit := c.cfg.WarmRestartKeys
+	if limit <= 0 {
+		limit = DefaultWarmRestartKeys
+	}
+	c.lock.Lock()
+	id := c.latestStateVersionID
+	stateKeys := hottestKeys(c.stateEvict, limit)
+	codeKeys := hottestKeys(c.codeEvict, limit)
+	c.lock.Unlock()
+	return writeWarmRestartFile(c.cfg.WarmRestartFile, id, stateKeys, codeKeys)
+}
+
+// Preload - fills cache by keys saved in CoherentConfig.WarmRestartFile. Values are read from `tx`.
+// Must be called on start - before OnNewBlock: does nothing if cache already has newer state than `tx`.
+// Returns amount of preloaded keys. Missing file is not an error.
+func (c *Coherent) Preload(ctx context.Context, tx kv.Tx) (int, error) {
+	if c.cfg.WarmRestartFile == "" {
+		return 0, nil
+	}
+	_, stateKeys, codeKeys, err := readWarmRestartFile(c.cfg.WarmRestartFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	idBytes, err := tx.GetOne(kv.Sequence, kv.PlainStateVersion)
+	if err != nil {
+		return 0, err
+	}
+	var id uint64
+	if len(idBytes) > 0 {
+		id = binary.BigEndian.Uint64(idBytes)
+	}
+
+	read := func(table string, keys [][]byte) ([][]byte, error) {
+		values := make([][]byte, len(keys))
+		for i, k := range keys {
+			if i%10_000 == 0 {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				default:
+				}
+			}
+			v, err := tx.GetOne(table, k)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = common.Copy(v)
+		}
+		return values, nil
+	}
+	stateValues, err := read(kv.PlainState, stateKeys)
+	if err != nil {
+		return 0, err
+	}
+	codeValues, err := read(kv.Code, codeKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.latestStateVersionID > id {
+		return 0, nil
+	}
+	r := c.advanceRoot(id)
+	// coldest first: hottest keys must be added last
+	for i := len(stateKeys) - 1; i >= 0; i-- {
+		c.add(stateKeys[i], stateValues[i], r, id)
+	}
+	for i := len(codeKeys) - 1; i >= 0; i-- {
+		c.addCode(codeKeys[i], codeValues[i], r, id)
+	}
+	c.keys.Set(uint64(r.cache.Len()))
+	c.codeKeys.Set(uint64(r.codeCache.Len()))
+	if r.readyChanClosed.CAS(false, true) {
+		close(r.ready)
+	}
+	return len(stateKeys) + len(codeKeys), nil
+}
+
+func hottestKeys(p EvictionPolicy, limit int) [][]byte {
+	keys := make([][]byte, 0, limit)
+	p.Walk(func(e *Element) bool {
+		keys = append(keys, e.K)
+		return len(keys) < limit
+	})
+	return keys
+}
+
+func writeWarmRestartFile(fileName string, id uint64, stateKeys, codeKeys [][]byte) error {
+	tmpFileName := fileName + ".tmp"
+	f, err := os.Create(tmpFileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	crc := crc32.New(crc32c)
+	w := bufio.NewWriter(io.MultiWriter(f, crc))
+	var buf [binary.MaxVarintLen64]byte
+	writeUvarint := func(v uint64) error {
+		_, err := w.Write(buf[:binary.PutUvarint(buf[:], v)])
+		return err
+	}
+	writeKeys := func(keys [][]byte) error {
+		if err := writeUvarint(uint64(len(keys))); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := writeUvarint(uint64(len(k))); err != nil {
+				return err
+			}
+			if _, err := w.Write(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := w.WriteString(warmRestartMagic); err != nil {
+		return err
+	}
+	if err := writeUvarint(id); err != nil {
+		return err
+	}
+	if err := writeKeys(stateKeys); err != nil {
+		return err
+	}
+	if err := writeKeys(codeKeys); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := binary.Write(f, binary.BigEndian, crc.Sum32()); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFileName, fileName)
+}
+
+func readWarmRestartFile(fileName string) (id uint64, stateKeys, codeKeys [][]byte, err error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if len(data) < len(warmRestartMagic)+4 || string(data[:len(warmRestartMagic)]) != warmRestartMagic {
+		return 0, nil, nil, fmt.Errorf("%w: %s", ErrWarmRestartFile, fileName)
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.Checksum(body, crc32c) != sum {
+		return 0, nil, nil, fmt.Errorf("%w: checksum mismatch, %s", ErrWarmRestartFile, fileName)
+	}
+	p := body[len(warmRestartMagic):]
+	readUvarint := func() (uint64, error) {
+		v, n := binary.Uvarint(p)
+		if n <= 0 {
+			return 0, fmt.Errorf("%w: %s", ErrWarmRestartFile, fileName)
+		}
+		p = p[n:]
+		return v, nil
+	}
+	readKeys := func() ([][]byte, error) {
+		amount, err := readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if amount > uint64(len(p)) { // every key takes at least 1 byte
+			return nil, fmt.Errorf("%w: %s", ErrWarmRestartFile, fileName)
+		}
+		keys := make([][]byte, amount)
+		for i := range keys {
+			l, err := readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			if l > uint64(len(p)) {
+				return nil, fmt.Errorf("%w: %s", ErrWarmRestartFile, fileName)
+			}
+			keys[i], p = p[:l:l], p[l:]
+		}
+		return keys, nil
+	}
+	if id, err = readUvarint(); err != nil {
+		return 0, nil, nil, err
+	}
+	if stateKeys, err = readKeys(); err != nil {
+		return 0, nil, nil, err
+	}
+	if codeKeys, err = readKeys(); err != nil {
+		return 0, nil, nil, err
+	}
+	return id, stateKeys, codeKeys, nil
+}
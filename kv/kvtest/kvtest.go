@@ -0,0 +1,704 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package kvtest - kv.RwDB wrapper for tests: injects errors and latency into calls of underlying db.
+// Allows to test error handling of code which is hard to reach with real db: failed Commit, broken iteration, etc...
+//
+//	db := kvtest.New(memdb.NewTestDB(t)).Inject(kvtest.Fault{Method: kvtest.Next, Table: kv.Headers, After: 10, Err: errBroken})
+package kvtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/iter"
+	"github.com/ledgerwatch/erigon-lib/kv/order"
+)
+
+// Method - group of methods which share faults
+type Method string
+
+const (
+	BeginRo Method = "BeginRo" // BeginRo, View
+	BeginRw Method = "BeginRw" // BeginRw, BeginRwNosync, Update, UpdateNosync
+	Commit  Method = "Commit"
+	Get     Method = "Get"    // GetOne, Has
+	Put     Method = "Put"    // Put, Append, AppendDup, IncrementSequence, same methods of cursors
+	Delete  Method = "Delete" // Delete, ClearBucket, Delete* methods of cursors
+	Seek    Method = "Seek"   // cursor positioning: First, Last, Seek, SeekExact, SeekBoth*, FirstDup, LastDup
+	Next    Method = "Next"   // cursor Next/Prev/NextDup/..., Next of Range* iterators, every step of ForEach/ForPrefix/ForAmount
+)
+
+// Fault - what to do with matching call. Fault fires after `After` matching calls, `Times` times (0 - unlimited).
+// Fired fault sleeps `Latency`, then calls `Cancel` (if set) and returns `Err`.
+// Err=nil and Cancel=nil - only latency. Err=nil and Cancel!=nil - returns context.Canceled:
+// it simulates cancellation of context in the middle of iteration.
+type Fault struct {
+	Method  Method
+	Table   string // empty - any table. BeginRo/BeginRw/Commit have no table
+	Err     error
+	Latency time.Duration
+	After   int
+	Times   int
+	Cancel  context.CancelFunc
+
+	calls, fired int
+}
+
+// DB - wraps kv.RwDB. Wrapped transactions keep optional interfaces of underlying: kv.TemporalTx, kv.TableStatsReader.
+// Write methods of read-only underlying tx/cursor (for example temporal.Tx) return kv.ErrNotSupported.
+type DB struct {
+	kv.RwDB
+
+	lock   sync.Mutex
+	faults []*Fault
+	fired  int
+}
+
+var _ kv.RwDB = (*DB)(nil)                     // compile-time interface check
+var _ kv.TemporalRwDB = (*TemporalDB)(nil)     // compile-time interface check
+var _ kv.TemporalTx = (*temporalTx)(nil)       // compile-time interface check
+var _ kv.TableStatsReader = (*temporalTx)(nil) // compile-time interface check
+
+func New(db kv.RwDB) *DB { return &DB{RwDB: db} }
+
+// Inject - adds faults. Faults are checked in order of adding: all matching faults add latency, first error wins.
+func (db *DB) Inject(faults ...Fault) *DB {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	for i := range faults {
+		f := faults[i]
+		f.calls, f.fired = 0, 0
+		db.faults = append(db.faults, &f)
+	}
+	return db
+}
+
+// Reset - removes all faults
+func (db *DB) Reset() {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	db.faults, db.fired = nil, 0
+}
+
+// Fired - how many times faults did fire
+func (db *DB) Fired() int {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	return db.fired
+}
+
+func (db *DB) check(m Method, table string) error {
+	var latency time.Duration
+	var err error
+	var cancel context.CancelFunc
+	db.lock.Lock()
+	for _, f := range db.faults {
+		if f.Method != m || (f.Table != "" && f.Table != table) {
+			continue
+		}
+		f.calls++
+		if f.calls <= f.After || (f.Times > 0 && f.fired >= f.Times) {
+			continue
+		}
+		f.fired++
+		db.fired++
+		latency += f.Latency
+		if err == nil && cancel == nil {
+			err, cancel = f.Err, f.Cancel
+		}
+	}
+	db.lock.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if cancel != nil {
+		cancel()
+		if err == nil {
+			err = context.Canceled
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("kvtest: %s %s: %w", m, table, err)
+	}
+	return nil
+}
+
+func (db *DB) View(ctx context.Context, f func(tx kv.Tx) error) error {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+func (db *DB) Update(ctx context.Context, f func(tx kv.RwTx) error) error {
+	tx, err := db.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) UpdateNosync(ctx context.Context, f func(tx kv.RwTx) error) error {
+	tx, err := db.BeginRwNosync(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) BeginRo(ctx context.Context) (kv.Tx, error) {
+	if err := db.check(BeginRo, ""); err != nil {
+		return nil, err
+	}
+	t, err := db.RwDB.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.wrapTx(t), nil
+}
+
+func (db *DB) BeginRw(ctx context.Context) (kv.RwTx, error) {
+	if err := db.check(BeginRw, ""); err != nil {
+		return nil, err
+	}
+	t, err := db.RwDB.BeginRw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.wrapTx(t), nil
+}
+
+func (db *DB) BeginRwNosync(ctx context.Context) (kv.RwTx, error) {
+	if err := db.check(BeginRw, ""); err != nil {
+		return nil, err
+	}
+	t, err := db.RwDB.BeginRwNosync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.wrapTx(t), nil
+}
+
+// wrapTx - returned tx implements kv.TemporalTx if `t` does
+func (db *DB) wrapTx(t kv.Tx) kv.RwTx {
+	wrapped := &kvTx{Tx: t, db: db}
+	if casted, ok := t.(kv.TemporalTx); ok {
+		return &temporalTx{kvTx: wrapped, ttx: casted}
+	}
+	return wrapped
+}
+
+// TemporalDB - wraps kv.TemporalRwDB: same as DB plus BeginTemporalRo/ViewTemporal
+type TemporalDB struct {
+	*DB
+	tdb kv.TemporalRwDB
+}
+
+func NewTemporal(db kv.TemporalRwDB) *TemporalDB { return &TemporalDB{DB: New(db), tdb: db} }
+
+// Inject - see DB.Inject
+func (db *TemporalDB) Inject(faults ...Fault) *TemporalDB {
+	db.DB.Inject(faults...)
+	return db
+}
+
+func (db *TemporalDB) BeginTemporalRo(ctx context.Context) (kv.TemporalTx, error) {
+	if err := db.check(BeginRo, ""); err != nil {
+		return nil, err
+	}
+	t, err := db.tdb.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &temporalTx{kvTx: &kvTx{Tx: t, db: db.DB}, ttx: t}, nil
+}
+
+func (db *TemporalDB) ViewTemporal(ctx context.Context, f func(tx kv.TemporalTx) error) error {
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+type kvTx struct {
+	kv.Tx
+	db *DB
+}
+
+// rw - checks faults of write method `m`, then casts underlying tx
+func (tx *kvTx) rw(m Method, table string) (kv.RwTx, error) {
+	if err := tx.db.check(m, table); err != nil {
+		return nil, err
+	}
+	return tx.asRw()
+}
+
+func (tx *kvTx) asRw() (kv.RwTx, error) {
+	casted, ok := tx.Tx.(kv.RwTx)
+	if !ok {
+		return nil, fmt.Errorf("%w: write to %T", kv.ErrNotSupported, tx.Tx)
+	}
+	return casted, nil
+}
+
+func (tx *kvTx) Commit() error {
+	if err := tx.db.check(Commit, ""); err != nil {
+		tx.Tx.Rollback() // same as real db: failed Commit releases tx
+		return err
+	}
+	return tx.Tx.Commit()
+}
+
+func (tx *kvTx) TableStats(table string) (kv.TableStat, error) { return kv.TableStats(tx.Tx, table) }
+
+func (tx *kvTx) GetOne(table string, key []byte) ([]byte, error) {
+	if err := tx.db.check(Get, table); err != nil {
+		return nil, err
+	}
+	return tx.Tx.GetOne(table, key)
+}
+
+func (tx *kvTx) Has(table string, key []byte) (bool, error) {
+	if err := tx.db.check(Get, table); err != nil {
+		return false, err
+	}
+	return tx.Tx.Has(table, key)
+}
+
+func (tx *kvTx) Put(table string, k, v []byte) error {
+	rw, err := tx.rw(Put, table)
+	if err != nil {
+		return err
+	}
+	return rw.Put(table, k, v)
+}
+
+func (tx *kvTx) Append(table string, k, v []byte) error {
+	rw, err := tx.rw(Put, table)
+	if err != nil {
+		return err
+	}
+	return rw.Append(table, k, v)
+}
+
+func (tx *kvTx) AppendDup(table string, k, v []byte) error {
+	rw, err := tx.rw(Put, table)
+	if err != nil {
+		return err
+	}
+	return rw.AppendDup(table, k, v)
+}
+
+func (tx *kvTx) IncrementSequence(table string, amount uint64) (uint64, error) {
+	rw, err := tx.rw(Put, table)
+	if err != nil {
+		return 0, err
+	}
+	return rw.IncrementSequence(table, amount)
+}
+
+func (tx *kvTx) Delete(table string, k []byte) error {
+	rw, err := tx.rw(Delete, table)
+	if err != nil {
+		return err
+	}
+	return rw.Delete(table, k)
+}
+
+func (tx *kvTx) ClearBucket(table string) error {
+	rw, err := tx.rw(Delete, table)
+	if err != nil {
+		return err
+	}
+	return rw.ClearBucket(table)
+}
+
+func (tx *kvTx) DropBucket(table string) error {
+	rw, err := tx.asRw()
+	if err != nil {
+		return err
+	}
+	return rw.DropBucket(table)
+}
+
+func (tx *kvTx) CreateBucket(table string) error {
+	rw, err := tx.asRw()
+	if err != nil {
+		return err
+	}
+	return rw.CreateBucket(table)
+}
+
+func (tx *kvTx) ExistsBucket(table string) (bool, error) {
+	rw, err := tx.asRw()
+	if err != nil {
+		return false, err
+	}
+	return rw.ExistsBucket(table)
+}
+
+func (tx *kvTx) CollectMetrics() {
+	if casted, ok := tx.Tx.(kv.RwTx); ok {
+		casted.CollectMetrics()
+	}
+}
+
+func (tx *kvTx) ForEach(table string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	return tx.Tx.ForEach(table, fromPrefix, tx.walker(table, walker))
+}
+
+func (tx *kvTx) ForPrefix(table string, prefix []byte, walker func(k, v []byte) error) error {
+	return tx.Tx.ForPrefix(table, prefix, tx.walker(table, walker))
+}
+
+func (tx *kvTx) ForAmount(table string, prefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	return tx.Tx.ForAmount(table, prefix, amount, tx.walker(table, walker))
+}
+
+func (tx *kvTx) walker(table string, walker func(k, v []byte) error) func(k, v []byte) error {
+	return func(k, v []byte) error {
+		if err := tx.db.check(Next, table); err != nil {
+			return err
+		}
+		return walker(k, v)
+	}
+}
+
+func (tx *kvTx) Range(table string, fromPrefix, toPrefix []byte) (iter.KV, error) {
+	it, err := tx.Tx.Range(table, fromPrefix, toPrefix)
+	return tx.iter(table, it, err)
+}
+
+func (tx *kvTx) RangeAscend(table string, fromPrefix, toPrefix []byte, limit int) (iter.KV, error) {
+	it, err := tx.Tx.RangeAscend(table, fromPrefix, toPrefix, limit)
+	return tx.iter(table, it, err)
+}
+
+func (tx *kvTx) RangeDescend(table string, fromPrefix, toPrefix []byte, limit int) (iter.KV, error) {
+	it, err := tx.Tx.RangeDescend(table, fromPrefix, toPrefix, limit)
+	return tx.iter(table, it, err)
+}
+
+func (tx *kvTx) Prefix(table string, prefix []byte) (iter.KV, error) {
+	it, err := tx.Tx.Prefix(table, prefix)
+	return tx.iter(table, it, err)
+}
+
+func (tx *kvTx) RangeDupSort(table string, key []byte, fromPrefix, toPrefix []byte, asc order.By, limit int) (iter.KV, error) {
+	it, err := tx.Tx.RangeDupSort(table, key, fromPrefix, toPrefix, asc, limit)
+	return tx.iter(table, it, err)
+}
+
+func (tx *kvTx) iter(table string, it iter.KV, err error) (iter.KV, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &kvIter{KV: it, table: table, db: tx.db}, nil
+}
+
+func (tx *kvTx) Cursor(table string) (kv.Cursor, error) {
+	c, err := tx.Tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.db.wrapCursor(table, c), nil
+}
+
+func (tx *kvTx) RwCursor(table string) (kv.RwCursor, error) {
+	rw, err := tx.asRw()
+	if err != nil {
+		return nil, err
+	}
+	c, err := rw.RwCursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.db.wrapCursor(table, c), nil
+}
+
+func (tx *kvTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	c, err := tx.Tx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.db.wrapDupSortCursor(table, c), nil
+}
+
+func (tx *kvTx) RwCursorDupSort(table string) (kv.RwCursorDupSort, error) {
+	rw, err := tx.asRw()
+	if err != nil {
+		return nil, err
+	}
+	c, err := rw.RwCursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.db.wrapDupSortCursor(table, c), nil
+}
+
+// temporalTx - faults of temporal methods use name of domain/history/index as Table
+type temporalTx struct {
+	*kvTx
+	ttx kv.TemporalTx
+}
+
+func (tx *temporalTx) DomainGet(name kv.Domain, k, k2 []byte, ts uint64) (v []byte, ok bool, err error) {
+	if err := tx.db.check(Get, string(name)); err != nil {
+		return nil, false, err
+	}
+	return tx.ttx.DomainGet(name, k, k2, ts)
+}
+
+func (tx *temporalTx) HistoryGet(name kv.History, k []byte, ts uint64) (v []byte, ok bool, err error) {
+	if err := tx.db.check(Get, string(name)); err != nil {
+		return nil, false, err
+	}
+	return tx.ttx.HistoryGet(name, k, ts)
+}
+
+func (tx *temporalTx) IndexRange(name kv.InvertedIdx, k []byte, fromTs, toTs int, asc order.By, limit int) (iter.U64, error) {
+	it, err := tx.ttx.IndexRange(name, k, fromTs, toTs, asc, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &u64Iter{U64: it, table: string(name), db: tx.db}, nil
+}
+
+func (tx *temporalTx) HistoryRange(name kv.History, fromTs, toTs int, asc order.By, limit int) (iter.KV, error) {
+	it, err := tx.ttx.HistoryRange(name, fromTs, toTs, asc, limit)
+	return tx.iter(string(name), it, err)
+}
+
+func (tx *temporalTx) DomainRange(name kv.Domain, k1, k2 []byte, asOfTs uint64, asc order.By, limit int) (iter.KV, error) {
+	it, err := tx.ttx.DomainRange(name, k1, k2, asOfTs, asc, limit)
+	return tx.iter(string(name), it, err)
+}
+
+type kvIter struct {
+	iter.KV
+	table string
+	db    *DB
+}
+
+func (it *kvIter) Next() ([]byte, []byte, error) {
+	if err := it.db.check(Next, it.table); err != nil {
+		return nil, nil, err
+	}
+	return it.KV.Next()
+}
+
+func (it *kvIter) Close() {
+	if c, ok := it.KV.(kv.Closer); ok {
+		c.Close()
+	}
+}
+
+type u64Iter struct {
+	iter.U64
+	table string
+	db    *DB
+}
+
+func (it *u64Iter) Next() (uint64, error) {
+	if err := it.db.check(Next, it.table); err != nil {
+		return 0, err
+	}
+	return it.U64.Next()
+}
+
+func (it *u64Iter) Close() {
+	if c, ok := it.U64.(kv.Closer); ok {
+		c.Close()
+	}
+}
+
+// wrapCursor - keeps DupSort-ness of cursor: callers do cast kv.Cursor to kv.CursorDupSort
+func (db *DB) wrapCursor(table string, c kv.Cursor) kv.RwCursor {
+	if dc, ok := c.(kv.CursorDupSort); ok {
+		return db.wrapDupSortCursor(table, dc)
+	}
+	return &cursor{Cursor: c, table: table, db: db}
+}
+
+func (db *DB) wrapDupSortCursor(table string, c kv.CursorDupSort) *dupSortCursor {
+	return &dupSortCursor{cursor: &cursor{Cursor: c, table: table, db: db}, c: c}
+}
+
+// cursor - on error returns empty (not nil) key: see docs of kv.Cursor
+type cursor struct {
+	kv.Cursor
+	table string
+	db    *DB
+}
+
+func (c *cursor) move(m Method, f func() ([]byte, []byte, error)) ([]byte, []byte, error) {
+	if err := c.db.check(m, c.table); err != nil {
+		return []byte{}, nil, err
+	}
+	return f()
+}
+
+// rw - checks faults of write method `m`, then casts underlying cursor
+func (c *cursor) rw(m Method) (kv.RwCursor, error) {
+	if err := c.db.check(m, c.table); err != nil {
+		return nil, err
+	}
+	casted, ok := c.Cursor.(kv.RwCursor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s of %T", kv.ErrNotSupported, m, c.Cursor)
+	}
+	return casted, nil
+}
+
+func (c *cursor) First() ([]byte, []byte, error) { return c.move(Seek, c.Cursor.First) }
+func (c *cursor) Last() ([]byte, []byte, error)  { return c.move(Seek, c.Cursor.Last) }
+func (c *cursor) Next() ([]byte, []byte, error)  { return c.move(Next, c.Cursor.Next) }
+func (c *cursor) Prev() ([]byte, []byte, error)  { return c.move(Next, c.Cursor.Prev) }
+func (c *cursor) Seek(seek []byte) ([]byte, []byte, error) {
+	return c.move(Seek, func() ([]byte, []byte, error) { return c.Cursor.Seek(seek) })
+}
+func (c *cursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	return c.move(Seek, func() ([]byte, []byte, error) { return c.Cursor.SeekExact(key) })
+}
+
+func (c *cursor) Put(k, v []byte) error {
+	rw, err := c.rw(Put)
+	if err != nil {
+		return err
+	}
+	return rw.Put(k, v)
+}
+
+func (c *cursor) Append(k, v []byte) error {
+	rw, err := c.rw(Put)
+	if err != nil {
+		return err
+	}
+	return rw.Append(k, v)
+}
+
+func (c *cursor) Delete(k []byte) error {
+	rw, err := c.rw(Delete)
+	if err != nil {
+		return err
+	}
+	return rw.Delete(k)
+}
+
+func (c *cursor) DeleteCurrent() error {
+	rw, err := c.rw(Delete)
+	if err != nil {
+		return err
+	}
+	return rw.DeleteCurrent()
+}
+
+type dupSortCursor struct {
+	*cursor
+	c kv.CursorDupSort
+}
+
+// rwDup - checks faults of write method `m`, then casts underlying cursor
+func (c *dupSortCursor) rwDup(m Method) (kv.RwCursorDupSort, error) {
+	if err := c.db.check(m, c.table); err != nil {
+		return nil, err
+	}
+	casted, ok := c.c.(kv.RwCursorDupSort)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s of %T", kv.ErrNotSupported, m, c.c)
+	}
+	return casted, nil
+}
+
+func (c *dupSortCursor) NextDup() ([]byte, []byte, error)   { return c.move(Next, c.c.NextDup) }
+func (c *dupSortCursor) NextNoDup() ([]byte, []byte, error) { return c.move(Next, c.c.NextNoDup) }
+func (c *dupSortCursor) PrevDup() ([]byte, []byte, error)   { return c.move(Next, c.c.PrevDup) }
+func (c *dupSortCursor) PrevNoDup() ([]byte, []byte, error) { return c.move(Next, c.c.PrevNoDup) }
+func (c *dupSortCursor) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
+	return c.move(Seek, func() ([]byte, []byte, error) { return c.c.SeekBothExact(key, value) })
+}
+
+func (c *dupSortCursor) SeekBothRange(key, value []byte) ([]byte, error) {
+	if err := c.db.check(Seek, c.table); err != nil {
+		return nil, err
+	}
+	return c.c.SeekBothRange(key, value)
+}
+
+func (c *dupSortCursor) FirstDup() ([]byte, error) {
+	if err := c.db.check(Seek, c.table); err != nil {
+		return nil, err
+	}
+	return c.c.FirstDup()
+}
+
+func (c *dupSortCursor) LastDup() ([]byte, error) {
+	if err := c.db.check(Seek, c.table); err != nil {
+		return nil, err
+	}
+	return c.c.LastDup()
+}
+
+func (c *dupSortCursor) CountDuplicates() (uint64, error) { return c.c.CountDuplicates() }
+
+func (c *dupSortCursor) PutNoDupData(key, value []byte) error {
+	rw, err := c.rwDup(Put)
+	if err != nil {
+		return err
+	}
+	return rw.PutNoDupData(key, value)
+}
+
+func (c *dupSortCursor) AppendDup(key, value []byte) error {
+	rw, err := c.rwDup(Put)
+	if err != nil {
+		return err
+	}
+	return rw.AppendDup(key, value)
+}
+
+func (c *dupSortCursor) DeleteExact(k1, k2 []byte) error {
+	rw, err := c.rwDup(Delete)
+	if err != nil {
+		return err
+	}
+	return rw.DeleteExact(k1, k2)
+}
+
+func (c *dupSortCursor) DeleteCurrentDuplicates() error {
+	rw, err := c.rwDup(Delete)
+	if err != nil {
+		return err
+	}
+	return rw.DeleteCurrentDuplicates()
+}
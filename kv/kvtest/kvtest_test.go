@@ -0,0 +1,193 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package kvtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvtest"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/order"
+	"github.com/ledgerwatch/erigon-lib/kv/temporal"
+	"github.com/ledgerwatch/erigon-lib/state"
+	"github.com/stretchr/testify/require"
+)
+
+var errBroken = errors.New("broken")
+
+func fill(t *testing.T, db kv.RwDB, n int) {
+	t.Helper()
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for i := 0; i < n; i++ {
+			if err := tx.Put(kv.Headers, []byte(fmt.Sprintf("%03d", i)), []byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		db := kvtest.New(memdb.NewTestDB(t)).Inject(kvtest.Fault{Method: kvtest.Commit, Err: errBroken, Times: 1})
+		err := db.Update(ctx, func(tx kv.RwTx) error { return tx.Put(kv.Headers, []byte("a"), []byte("b")) })
+		require.ErrorIs(t, err, errBroken)
+		require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+			v, err := tx.GetOne(kv.Headers, []byte("a"))
+			require.Nil(t, v)
+			return err
+		}))
+
+		// fault fired once - next commit succeeds
+		fill(t, db, 1)
+		require.Equal(t, 1, db.Fired())
+	})
+
+	t.Run("cursor next after N", func(t *testing.T) {
+		db := kvtest.New(memdb.NewTestDB(t))
+		fill(t, db, 10)
+		db.Inject(kvtest.Fault{Method: kvtest.Next, Table: kv.Headers, After: 3, Err: errBroken})
+		var seen int
+		err := db.View(ctx, func(tx kv.Tx) error {
+			c, err := tx.Cursor(kv.Headers)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+				if err != nil {
+					return err
+				}
+				seen++
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, errBroken)
+		require.Equal(t, 4, seen) // First + 3 Next
+
+		seen = 0
+		err = db.View(ctx, func(tx kv.Tx) error {
+			return tx.ForEach(kv.Headers, nil, func(k, v []byte) error {
+				seen++
+				return nil
+			})
+		})
+		require.ErrorIs(t, err, errBroken)
+		require.Equal(t, 0, seen)
+	})
+
+	t.Run("other table is not affected", func(t *testing.T) {
+		db := kvtest.New(memdb.NewTestDB(t)).Inject(kvtest.Fault{Method: kvtest.Put, Table: kv.BlockBody, Err: errBroken})
+		fill(t, db, 3)
+		require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+			c, err := tx.RwCursorDupSort(kv.AccountChangeSet)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			return c.AppendDup([]byte("k"), []byte("v"))
+		}))
+		require.Equal(t, 0, db.Fired())
+	})
+
+	t.Run("cancel in the middle of iteration", func(t *testing.T) {
+		db := kvtest.New(memdb.NewTestDB(t))
+		fill(t, db, 10)
+		cctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		db.Inject(kvtest.Fault{Method: kvtest.Next, After: 5, Cancel: cancel})
+		var seen int
+		err := db.View(cctx, func(tx kv.Tx) error {
+			it, err := tx.Range(kv.Headers, nil, nil)
+			if err != nil {
+				return err
+			}
+			for it.HasNext() {
+				if _, _, err := it.Next(); err != nil {
+					return err
+				}
+				seen++
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.ErrorIs(t, cctx.Err(), context.Canceled)
+		require.Equal(t, 5, seen)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		db := kvtest.New(memdb.NewTestDB(t)).Inject(kvtest.Fault{Method: kvtest.BeginRo, Latency: 50 * time.Millisecond})
+		start := time.Now()
+		require.NoError(t, db.View(ctx, func(tx kv.Tx) error { return nil }))
+		require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("etl load", func(t *testing.T) {
+		db := kvtest.New(memdb.NewTestDB(t)).Inject(kvtest.Fault{Method: kvtest.Put, Table: kv.Headers, After: 2, Err: errBroken})
+		c := etl.NewCollector(t.Name(), t.TempDir(), etl.NewSortableBuffer(etl.BufferOptimalSize))
+		defer c.Close()
+		for i := 0; i < 10; i++ {
+			require.NoError(t, c.Collect([]byte{byte(i)}, []byte{byte(i)}))
+		}
+		err := db.Update(ctx, func(tx kv.RwTx) error {
+			return c.Load(tx, kv.Headers, etl.IdentityLoadFunc, etl.TransformArgs{})
+		})
+		require.ErrorIs(t, err, errBroken)
+	})
+}
+
+func TestTemporal(t *testing.T) {
+	ctx, require := context.Background(), require.New(t)
+	dir := t.TempDir()
+	mdb := memdb.NewTestDB(t)
+	agg, err := state.NewAggregatorV3(ctx, dir, dir, 16, mdb)
+	require.NoError(err)
+	t.Cleanup(agg.Close)
+	db := kvtest.NewTemporal(temporal.New(mdb, agg))
+	fill(t, db, 3)
+	db.Inject(kvtest.Fault{Method: kvtest.Get, Table: string(temporal.AccountsHistory), Err: errBroken})
+
+	// BeginRo of temporal.DB returns read-only temporal.Tx: wrapper must keep it temporal and must not require kv.RwTx
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		ttx, ok := tx.(kv.TemporalTx)
+		require.True(ok)
+		_, _, err := ttx.HistoryGet(temporal.AccountsHistory, []byte("a"), 0)
+		require.ErrorIs(err, errBroken)
+
+		st, err := kv.TableStats(tx, kv.Headers)
+		require.NoError(err)
+		require.Equal(uint64(3), st.Entries)
+
+		require.ErrorIs(tx.(kv.RwTx).Put(kv.Headers, []byte("a"), []byte("b")), kv.ErrNotSupported)
+		return nil
+	}))
+	require.NoError(db.ViewTemporal(ctx, func(tx kv.TemporalTx) error {
+		it, err := tx.IndexRange(temporal.LogAddrIdx, []byte("a"), 0, 10, order.Asc, -1)
+		require.NoError(err)
+		require.False(it.HasNext())
+		return nil
+	}))
+	require.Equal(1, db.Fired())
+}
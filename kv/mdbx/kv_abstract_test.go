@@ -18,23 +18,34 @@ package mdbx_test
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/iter"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/order"
 	"github.com/ledgerwatch/erigon-lib/kv/remotedb"
 	"github.com/ledgerwatch/erigon-lib/kv/remotedbserver"
+	"github.com/ledgerwatch/erigon-lib/kv/temporal"
+	"github.com/ledgerwatch/erigon-lib/state"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 func TestSequence(t *testing.T) {
@@ -330,6 +341,261 @@ func TestRemoteKvRange(t *testing.T) {
 	require.NoError(err)
 }
 
+func TestRemoteKvRwTx(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	ctx, writeDB := context.Background(), memdb.NewTestDB(t)
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		kvServer := remotedbserver.NewKvServer(ctx, writeDB, nil, nil).WithWritableTables(kv.HeaderNumber)
+		remote.RegisterKVServer(grpcServer, kvServer)
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+	require.NoError(t, err)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), log.New(), remote.NewKVClient(cc)).Open()
+	require.NoError(t, err)
+	require := require.New(t)
+
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		require.NoError(tx.Put(kv.HeaderNumber, []byte{1}, []byte{1}))
+		require.NoError(tx.Put(kv.HeaderNumber, []byte{2}, []byte{2}))
+		require.NoError(tx.Delete(kv.HeaderNumber, []byte{2}))
+		id, err := tx.IncrementSequence(kv.HeaderNumber, 2)
+		require.NoError(err)
+		require.Equal(uint64(0), id)
+		v, err := tx.GetOne(kv.HeaderNumber, []byte{1})
+		require.NoError(err)
+		require.Equal([]byte{1}, v)
+		return nil
+	}))
+	require.NoError(writeDB.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.HeaderNumber, []byte{1})
+		require.NoError(err)
+		require.Equal([]byte{1}, v)
+		v, err = tx.GetOne(kv.HeaderNumber, []byte{2})
+		require.NoError(err)
+		require.Nil(v)
+		seq, err := tx.ReadSequence(kv.HeaderNumber)
+		require.NoError(err)
+		require.Equal(uint64(2), seq)
+		return nil
+	}))
+
+	// rollback
+	tx, err := db.BeginRw(ctx)
+	require.NoError(err)
+	require.NoError(tx.Put(kv.HeaderNumber, []byte{3}, []byte{3}))
+	tx.Rollback()
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.HeaderNumber, []byte{3})
+		require.NoError(err)
+		require.Nil(v)
+		return nil
+	}))
+
+	// table is not in allow-list
+	err = db.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.PlainState, []byte{1}, []byte{1})
+	})
+	require.Error(err)
+
+	// read-only tx can't write
+	err = db.View(ctx, func(tx kv.Tx) error {
+		c, err := tx.Cursor(kv.HeaderNumber)
+		if err != nil {
+			return err
+		}
+		return c.(kv.RwCursor).Put([]byte{4}, []byte{4})
+	})
+	require.Error(err)
+
+	// server without read-write transactions
+	oldClient := &versionedKVClient{KVClient: remote.NewKVClient(cc), version: &types.VersionReply{Major: 6, Minor: 3}}
+	oldDB, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), log.New(), oldClient).Open()
+	require.NoError(err)
+	_, err = oldDB.BeginRw(ctx)
+	require.ErrorContains(err, "doesn't support read-write transactions")
+}
+
+type versionedKVClient struct {
+	remote.KVClient
+	version *types.VersionReply
+}
+
+func (c *versionedKVClient) Version(context.Context, *emptypb.Empty, ...grpc.CallOption) (*types.VersionReply, error) {
+	return c.version, nil
+}
+
+func TestRemoteKvTableStats(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	ctx, writeDB := context.Background(), memdb.NewTestDB(t)
+	require := require.New(t)
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(0); i < 10; i++ {
+			require.NoError(tx.Put(kv.HeaderNumber, []byte{i}, []byte{i}))
+			require.NoError(tx.Put(kv.AccountChangeSet, []byte{1}, []byte{i}))
+		}
+		return nil
+	}))
+
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		remote.RegisterKVServer(grpcServer, remotedbserver.NewKvServer(ctx, writeDB, nil, nil))
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+	require.NoError(err)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), log.New(), remote.NewKVClient(cc)).Open()
+	require.NoError(err)
+
+	local, err := writeDB.BeginRo(ctx)
+	require.NoError(err)
+	defer local.Rollback()
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		for _, table := range []string{kv.HeaderNumber, kv.AccountChangeSet} {
+			expect, err := kv.TableStats(local, table)
+			require.NoError(err)
+			got, err := kv.TableStats(tx, table)
+			require.NoError(err)
+			require.Equal(expect, got)
+			require.Equal(uint64(10), got.Entries)
+			size, err := tx.BucketSize(table)
+			require.NoError(err)
+			require.Equal(expect.Size(), size)
+		}
+		_, err := kv.TableStats(tx, "unknown")
+		require.Error(err)
+
+		expectSize, err := local.DBSize()
+		require.NoError(err)
+		size, err := tx.DBSize()
+		require.NoError(err)
+		require.Equal(expectSize, size)
+
+		keys, maxDups, err := kv.DupSortFanOut(tx, kv.AccountChangeSet)
+		require.NoError(err)
+		require.Equal(uint64(1), keys)
+		require.Equal(uint64(10), maxDups)
+		return nil
+	}))
+
+	keys, maxDups, err := kv.DupSortFanOut(local, kv.AccountChangeSet)
+	require.NoError(err)
+	require.Equal(uint64(1), keys)
+	require.Equal(uint64(10), maxDups)
+
+	report := &strings.Builder{}
+	require.NoError(kv.PrintTablesSizeReport(local, report))
+	require.Contains(report.String(), kv.HeaderNumber)
+}
+
+func TestRemoteTemporalTx(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	ctx, dir, require := context.Background(), t.TempDir(), require.New(t)
+	rawDB := memdb.NewTestDB(t)
+	agg, err := state.NewAggregatorV3(ctx, dir, dir, 16, rawDB)
+	require.NoError(err)
+	defer agg.Close()
+	writeDB := temporal.New(rawDB, agg)
+
+	addr, addr2 := make([]byte, length.Addr), make([]byte, length.Addr)
+	addr[0], addr2[0] = 1, 2
+	const logsAmount = remotedbserver.PageSizeLimit + 10 // IndexRange needs 2 pages
+	require.NoError(writeDB.Update(ctx, func(tx kv.RwTx) error {
+		agg.SetTx(tx)
+		agg.StartWrites()
+		defer agg.FinishWrites()
+		agg.SetTxNum(1)
+		require.NoError(agg.AddAccountPrev(addr, nil))
+		agg.SetTxNum(5)
+		require.NoError(agg.AddAccountPrev(addr, []byte{1}))
+		require.NoError(tx.Put(kv.PlainState, addr, []byte{2}))
+		for txNum := uint64(1); txNum <= logsAmount; txNum++ {
+			agg.SetTxNum(txNum)
+			require.NoError(agg.AddLogAddr(addr2))
+		}
+		return agg.Flush(ctx, tx)
+	}))
+
+	grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+	go func() {
+		remote.RegisterKVServer(grpcServer, remotedbserver.NewKvServer(ctx, writeDB, nil, nil))
+		if err := grpcServer.Serve(conn); err != nil {
+			log.Error("private RPC server fail", "err", err)
+		}
+	}()
+	defer grpcServer.Stop()
+	cc, err := grpc.Dial("", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+	require.NoError(err)
+	db, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), log.New(), remote.NewKVClient(cc)).Open()
+	require.NoError(err)
+
+	tx, err := db.BeginTemporalRo(ctx)
+	require.NoError(err)
+	defer tx.Rollback()
+
+	// DomainGet
+	v, ok, err := tx.DomainGet(temporal.AccountsDomain, addr, nil, 3)
+	require.NoError(err)
+	require.True(ok)
+	require.Equal([]byte{1}, v)
+	v, ok, err = tx.DomainGet(temporal.AccountsDomain, addr, nil, 6)
+	require.NoError(err)
+	require.True(ok)
+	require.Equal([]byte{2}, v)
+	_, _, err = tx.DomainGet("unknown", addr, nil, 6)
+	require.Error(err)
+
+	// IndexRange: order and page tokens
+	it, err := tx.IndexRange(temporal.LogAddrIdx, addr2, 0, -1, order.Asc, -1)
+	require.NoError(err)
+	all, err := iter.ToU64Arr(it)
+	require.NoError(err)
+	require.Equal(logsAmount, len(all))
+	for i := range all {
+		require.Equal(uint64(i+1), all[i])
+	}
+	it, err = tx.IndexRange(temporal.LogAddrIdx, addr2, -1, 0, order.Desc, -1)
+	require.NoError(err)
+	all, err = iter.ToU64Arr(it)
+	require.NoError(err)
+	require.Equal(logsAmount, len(all))
+	require.Equal(uint64(logsAmount), all[0])
+	require.Equal(uint64(1), all[len(all)-1])
+	it, err = tx.IndexRange(temporal.LogAddrIdx, addr2, logsAmount-2, 0, order.Desc, 2)
+	require.NoError(err)
+	all, err = iter.ToU64Arr(it)
+	require.NoError(err)
+	require.Equal([]uint64{logsAmount - 2, logsAmount - 3}, all)
+
+	// HistoryRange, DomainRange
+	hit, err := tx.HistoryRange(temporal.AccountsHistory, 2, 10, order.Asc, -1)
+	require.NoError(err)
+	keys, vals, err := iter.ToKVArray(hit)
+	require.NoError(err)
+	require.Equal([][]byte{addr}, keys)
+	require.Equal([][]byte{{1}}, vals)
+	dit, err := tx.DomainRange(temporal.AccountsDomain, nil, nil, 3, order.Asc, -1)
+	require.NoError(err)
+	keys, vals, err = iter.ToKVArray(dit)
+	require.NoError(err)
+	require.Equal([][]byte{addr}, keys)
+	require.Equal([][]byte{{1}}, vals)
+}
+
 func setupDatabases(t *testing.T, logger log.Logger, f mdbx.TableCfgFunc) (writeDBs []kv.RwDB, readDBs []kv.RwDB) {
 	t.Helper()
 	ctx := context.Background()
@@ -631,3 +897,86 @@ func testMultiCursor(t *testing.T, db kv.RwDB, bucket1, bucket2 string) {
 //		})
 //	}
 //}
+
+func TestParallelRange(t *testing.T) {
+	ctx, db := context.Background(), memdb.NewTestDB(t)
+	require := require.New(t)
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		for i := uint64(0); i < 10_000; i++ {
+			k := make([]byte, 8)
+			binary.BigEndian.PutUint64(k, i*i) // not uniform
+			require.NoError(tx.Put(kv.PlainState, k, k))
+		}
+		for i := byte(0); i < 100; i++ {
+			for j := byte(0); j < 3; j++ {
+				require.NoError(tx.Put(kv.AccountChangeSet, []byte{i}, []byte{j}))
+			}
+		}
+		for i := 0; i < 24; i++ {
+			require.NoError(tx.Put(kv.HashedAccounts, []byte{byte(i)}, nil))
+		}
+		for i := 0; i < 976; i++ {
+			require.NoError(tx.Put(kv.HashedAccounts, []byte{0xff, byte(i >> 8), byte(i)}, nil))
+		}
+		return nil
+	}))
+
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		splits, err := kv.RangeSplitPoints(tx, kv.PlainState, 4)
+		require.NoError(err)
+		require.Equal(3, len(splits))
+		for i, split := range splits { // keys are i*i: ranges have equal amount of keys, not equal key space
+			n := uint64(i+1) * 2500
+			require.Equal(n*n, binary.BigEndian.Uint64(split))
+		}
+
+		// few low keys, bulk of keys under 1 high prefix
+		splits, err = kv.RangeSplitPoints(tx, kv.HashedAccounts, 4)
+		require.NoError(err)
+		require.Equal([][]byte{{0xff, 0, 226}, {0xff, 1, 220}, {0xff, 2, 214}}, splits)
+
+		// DupSort: values are counted, split point is key
+		splits, err = kv.RangeSplitPoints(tx, kv.AccountChangeSet, 4)
+		require.NoError(err)
+		require.Equal([][]byte{{25}, {50}, {75}}, splits)
+
+		splits, err = kv.RangeSplitPoints(tx, kv.AccountChangeSet, 1000)
+		require.NoError(err)
+		require.Equal(99, len(splits))
+		return nil
+	}))
+
+	for _, table := range []string{kv.PlainState, kv.AccountChangeSet} {
+		var lock sync.Mutex
+		seen := map[string]int{}
+		progress := atomic.NewUint64(0)
+		err := kv.ParallelRange(ctx, db, table, 4, progress, func(tx kv.Tx, k, v []byte) error {
+			lock.Lock()
+			defer lock.Unlock()
+			seen[fmt.Sprintf("%x-%x", k, v)]++
+			return nil
+		})
+		require.NoError(err)
+		expect := 10_000
+		if table == kv.AccountChangeSet {
+			expect = 300
+		}
+		require.Equal(expect, len(seen), table)
+		require.Equal(uint64(expect), progress.Load(), table)
+		for kv, n := range seen {
+			require.Equal(1, n, kv)
+		}
+	}
+
+	errBroken := fmt.Errorf("broken")
+	err := kv.ParallelRange(ctx, db, kv.PlainState, 4, nil, func(tx kv.Tx, k, v []byte) error {
+		if binary.BigEndian.Uint64(k) == 50*50 {
+			return errBroken
+		}
+		return nil
+	})
+	require.ErrorIs(err, errBroken)
+
+	// empty table
+	require.NoError(kv.ParallelRange(ctx, db, kv.Code, 4, nil, func(tx kv.Tx, k, v []byte) error { return errBroken }))
+}
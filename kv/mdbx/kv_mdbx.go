@@ -67,6 +67,10 @@ type MdbxOpts struct {
 	verbosity      kv.DBVerbosityLvl
 	label          kv.Label // marker to distinct db instances - one process may open many databases. for example to collect metrics of only 1 database
 	inMem          bool
+
+	changeFeedTables     []string // see ChangeFeed
+	changeFeedBufferSize int
+	longTxThreshold      time.Duration // if > 0 - open transactions are tracked, see TrackTxs
 }
 
 func NewMDBX(log log.Logger) MdbxOpts {
@@ -391,6 +395,17 @@ func (opts MdbxOpts) Open() (kv.RwDB, error) {
 		}
 
 	}
+	if len(opts.changeFeedTables) > 0 {
+		if db.cdc, err = newChangeFeed(db, opts.changeFeedBufferSize, opts.changeFeedTables); err != nil {
+			env.Close()
+			return nil, err
+		}
+	}
+
+	if opts.longTxThreshold > 0 {
+		db.startTxsTracking()
+	}
+
 	db.path = opts.path
 	addToPathDbMap(opts.path, db)
 	return db, nil
@@ -414,11 +429,16 @@ type MdbxKV struct {
 	txSize       uint64
 	closed       atomic.Bool
 	path         string
+	cdc          *ChangeFeed // nil if change feed is disabled
+	txs          *txsTracker // nil if tracking of open transactions is disabled
 }
 
 func (db *MdbxKV) PageSize() uint64 { return db.opts.pageSize }
 func (db *MdbxKV) ReadOnly() bool   { return db.opts.HasFlag(mdbx.Readonly) }
 
+// ChangeFeed - returns nil if MdbxOpts.ChangeFeed was not configured
+func (db *MdbxKV) ChangeFeed() *ChangeFeed { return db.cdc }
+
 // openDBIs - first trying to open existing DBI's in RO transaction
 // otherwise re-try by RW transaction
 // it allow open DB from another process - even if main process holding long RW transaction
@@ -461,7 +481,13 @@ func (db *MdbxKV) Close() {
 	if ok := db.closed.CompareAndSwap(false, true); !ok {
 		return
 	}
+	if db.cdc != nil {
+		db.cdc.close()
+	}
 	db.wg.Wait()
+	if db.txs != nil {
+		db.txs.cancel()
+	}
 	db.env.Close()
 	db.env = nil
 
@@ -506,12 +532,14 @@ func (db *MdbxKV) BeginRo(ctx context.Context) (txn kv.Tx, err error) {
 	if err != nil {
 		return nil, fmt.Errorf("%w, label: %s, trace: %s", err, db.opts.label.String(), stack2.Trace().String())
 	}
-	return &MdbxTx{
+	res := &MdbxTx{
 		ctx:      ctx,
 		db:       db,
 		tx:       tx,
 		readOnly: true,
-	}, nil
+	}
+	db.trackTx(res)
+	return res, nil
 }
 
 func (db *MdbxKV) BeginRw(ctx context.Context) (kv.RwTx, error) {
@@ -543,11 +571,13 @@ func (db *MdbxKV) beginRw(ctx context.Context, flags uint) (txn kv.RwTx, err err
 		runtime.UnlockOSThread() // unlock only in case of error. normal flow is "defer .Rollback()"
 		return nil, fmt.Errorf("%w, lable: %s, trace: %s", err, db.opts.label.String(), stack2.Trace().String())
 	}
-	return &MdbxTx{
+	res := &MdbxTx{
 		db:  db,
 		tx:  tx,
 		ctx: ctx,
-	}, nil
+	}
+	db.trackTx(res)
+	return res, nil
 }
 
 type MdbxTx struct {
@@ -559,6 +589,9 @@ type MdbxTx struct {
 	readOnly         bool
 	cursorID         uint64
 	ctx              context.Context
+	changes          []Change            // writes to ChangeFeed tables, published after commit
+	bulkTables       map[string]struct{} // writes to this tables are not recorded, see ChangeBulk
+	trackID          uint64              // id in MdbxKV.OpenTxs
 }
 
 type MdbxCursor struct {
@@ -568,6 +601,7 @@ type MdbxCursor struct {
 	bucketCfg  kv.TableCfgItem
 	dbi        mdbx.DBI
 	id         uint64
+	watched    bool // table is in ChangeFeed
 }
 
 func (db *MdbxKV) Env() *mdbx.Env {
@@ -748,6 +782,7 @@ func (tx *MdbxTx) dropEvenIfBucketIsNotDeprecated(name string) error {
 	if err := tx.tx.Drop(mdbx.DBI(dbi), true); err != nil {
 		return err
 	}
+	tx.recordTableChange(name, ChangeDrop)
 	cnfCopy := tx.db.buckets[name]
 	cnfCopy.DBI = NonExistingDBI
 	tx.db.buckets[name] = cnfCopy
@@ -759,7 +794,11 @@ func (tx *MdbxTx) ClearBucket(bucket string) error {
 	if dbi == NonExistingDBI {
 		return nil
 	}
-	return tx.tx.Drop(mdbx.DBI(dbi), false)
+	if err := tx.tx.Drop(mdbx.DBI(dbi), false); err != nil {
+		return err
+	}
+	tx.recordTableChange(bucket, ChangeClear)
+	return nil
 }
 
 func (tx *MdbxTx) DropBucket(bucket string) error {
@@ -783,6 +822,7 @@ func (tx *MdbxTx) Commit() error {
 	}
 	defer func() {
 		tx.tx = nil
+		tx.db.untrackTx(tx)
 		tx.db.wg.Done()
 		if tx.readOnly {
 			//tx.db.roTxsLimiter.Release(1)
@@ -802,10 +842,21 @@ func (tx *MdbxTx) Commit() error {
 	//}
 	tx.CollectMetrics()
 
+	if len(tx.changes) > 0 {
+		if err := tx.db.cdc.reserve(tx.tx, mdbx.DBI(tx.db.buckets[kv.Sequence].DBI), tx.changes); err != nil {
+			tx.tx.Abort()
+			return fmt.Errorf("change feed: %w", err)
+		}
+	}
+
 	latency, err := tx.tx.Commit()
 	if err != nil {
 		return err
 	}
+	if len(tx.changes) > 0 {
+		tx.db.cdc.publish(tx.changes)
+		tx.changes = nil
+	}
 
 	if tx.db.opts.label == kv.ChainDB {
 		kv.DbCommitPreparation.Update(latency.Preparation.Seconds())
@@ -833,6 +884,7 @@ func (tx *MdbxTx) Rollback() {
 	}
 	defer func() {
 		tx.tx = nil
+		tx.db.untrackTx(tx)
 		tx.db.wg.Done()
 		if tx.readOnly {
 			//tx.db.roTxsLimiter.Release(1)
@@ -1025,6 +1077,25 @@ func (tx *MdbxTx) BucketStat(name string) (*mdbx.Stat, error) {
 	return st, nil
 }
 
+func (tx *MdbxTx) TableStats(name string) (kv.TableStat, error) {
+	if cfg, ok := tx.db.buckets[name]; !ok || cfg.DBI == NonExistingDBI {
+		return kv.TableStat{}, fmt.Errorf("table %s doesn't exist", name)
+	}
+	st, err := tx.BucketStat(name)
+	if err != nil {
+		return kv.TableStat{}, err
+	}
+	return kv.TableStat{
+		Table:         name,
+		Entries:       st.Entries,
+		Depth:         uint64(st.Depth),
+		BranchPages:   st.BranchPages,
+		LeafPages:     st.LeafPages,
+		OverflowPages: st.OverflowPages,
+		PageSize:      uint64(st.PSize),
+	}, nil
+}
+
 func (tx *MdbxTx) DBSize() (uint64, error) {
 	info, err := tx.db.env.Info(tx.tx)
 	if err != nil {
@@ -1054,6 +1125,7 @@ func (tx *MdbxTx) stdCursor(bucket string) (kv.RwCursor, error) {
 	b := tx.db.buckets[bucket]
 	c := &MdbxCursor{bucketName: bucket, tx: tx, bucketCfg: b, dbi: mdbx.DBI(tx.db.buckets[bucket].DBI), id: tx.cursorID}
 	tx.cursorID++
+	c.watched = !tx.readOnly && tx.db.cdc != nil && tx.db.cdc.watched(bucket)
 
 	var err error
 	c.c, err = tx.tx.OpenCursor(c.dbi)
@@ -1296,7 +1368,7 @@ func (c *MdbxCursor) Delete(k []byte) error {
 		return c.deleteDupSort(k)
 	}
 
-	_, _, err := c.set(k)
+	_, v, err := c.set(k)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
 			return nil
@@ -1307,6 +1379,9 @@ func (c *MdbxCursor) Delete(k []byte) error {
 	if c.bucketCfg.Flags&mdbx.DupSort != 0 {
 		return c.delAllDupData()
 	}
+	if c.watched {
+		c.tx.recordChange(c.bucketName, k, v, nil)
+	}
 	return c.delCurrent()
 }
 
@@ -1316,6 +1391,13 @@ func (c *MdbxCursor) Delete(k []byte) error {
 // Both MDB_NEXT and MDB_GET_CURRENT will return the same record after
 // this operation.
 func (c *MdbxCursor) DeleteCurrent() error {
+	if c.watched {
+		k, v, err := c.getCurrent()
+		if err != nil {
+			return err
+		}
+		c.tx.recordChange(c.bucketName, k, v, nil)
+	}
 	return c.delCurrent()
 }
 
@@ -1356,7 +1438,13 @@ func (c *MdbxCursor) PutNoOverwrite(key []byte, value []byte) error {
 		panic("not implemented")
 	}
 
-	return c.putNoOverwrite(key, value)
+	if err := c.putNoOverwrite(key, value); err != nil {
+		return err
+	}
+	if c.watched {
+		c.tx.recordChange(c.bucketName, key, nil, value)
+	}
+	return nil
 }
 
 func (c *MdbxCursor) Put(key []byte, value []byte) error {
@@ -1367,6 +1455,9 @@ func (c *MdbxCursor) Put(key []byte, value []byte) error {
 		}
 		return nil
 	}
+	if c.watched {
+		return c.putWatched(key, value)
+	}
 	if err := c.put(key, value); err != nil {
 		return fmt.Errorf("table: %s, err: %w", c.bucketName, err)
 	}
@@ -1470,6 +1561,9 @@ func (c *MdbxCursor) Append(k []byte, v []byte) error {
 	if err := c.append(k, v); err != nil {
 		return fmt.Errorf("bucket: %s, %w", c.bucketName, err)
 	}
+	if c.watched { // append fails if key exists - so no old value
+		c.tx.recordChange(c.bucketName, k, nil, v)
+	}
 	return nil
 }
 
@@ -0,0 +1,567 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package mdbx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Online backup of live MdbxKV: all data is read by 1 read transaction - so backup is consistent snapshot.
+// Backup can be written:
+//   - to another MDBX environment (BackupToPath) - it's ready to use as chaindata
+//   - to io.Writer (Backup) - as stream of blocks, every block has crc32 checksum. Use Restore/VerifyBackup to read it.
+//
+// Incremental mode: BackupOpts.Since - copy only tables which differ from manifest of previous backup. Granularity is
+// table: modified table is copied fully and replaces table of previous backup on restore. Change is detected by
+// entries and crc32c of table (mdbx-go doesn't fill Stat.LastTxId) - incremental backup reads all tables, but writes
+// only modified ones.
+//
+// MDBX doesn't store checksums of pages. VerifyBackupPath opens backup in MDBX_VALIDATION mode - MDBX checks structure
+// of every page it reads - and reads all records of tables to compare their crc32c with manifest.
+
+const (
+	BackupManifestFileName = "backup_manifest.json"
+
+	backupMagic     = "mdbxbak1"
+	mdbxValidation  = 0x2000 // MDBX_VALIDATION env flag: extra validation of DB structure and pages content (not exported by mdbx-go)
+	backupBlockSize = 1 << 20
+	maxKeySize      = 1 << 16    // MDBX key is limited by page size: max page size is 64KB
+	maxValueSize    = 0x7fff0000 // MDBX_MAXDATASIZE
+	// data block is flushed after record which reached backupBlockSize: protection from allocation of garbage size
+	maxBlockSize = backupBlockSize + 2*binary.MaxVarintLen64 + maxKeySize + maxValueSize
+
+	blockTable    byte = 1 // payload: table name. all next data blocks belong to this table
+	blockData     byte = 2 // payload: sequence of records (uvarint len, key, uvarint len, value)
+	blockManifest byte = 3 // payload: json of BackupManifest. last block of stream
+)
+
+var (
+	ErrBackupChecksum = errors.New("backup: checksum mismatch")
+	ErrBackupFormat   = errors.New("backup: unexpected format")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type BackupOpts struct {
+	Tables []string        // if empty - all tables
+	Since  *BackupManifest // if not nil - incremental backup: only tables which differ from this manifest of previous backup
+}
+
+type BackupManifest struct {
+	ViewID      uint64        // ViewID of read transaction
+	SinceViewID uint64        // ViewID of BackupOpts.Since, 0 for full backup
+	Tables      []BackupTable // sorted by name
+}
+
+func (opts BackupOpts) sinceViewID() uint64 {
+	if opts.Since == nil {
+		return 0
+	}
+	return opts.Since.ViewID
+}
+
+type BackupTable struct {
+	Name     string
+	Entries  uint64
+	Checksum uint32 // crc32 of all records
+}
+
+// Backup - writes consistent copy of db to `w`
+func (db *MdbxKV) Backup(ctx context.Context, w io.Writer, opts BackupOpts) (*BackupManifest, error) {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	tables, _, err := backupTables(ctx, tx.(*MdbxTx), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bw := &backupWriter{w: bufio.NewWriterSize(w, 4*backupBlockSize)}
+	if _, err := bw.w.WriteString(backupMagic); err != nil {
+		return nil, err
+	}
+	m := &BackupManifest{ViewID: tx.ViewID(), SinceViewID: opts.sinceViewID()}
+	for _, name := range tables {
+		if err := bw.block(blockTable, []byte(name)); err != nil {
+			return nil, err
+		}
+		t, err := forEachRecord(ctx, tx, name, func(k, v []byte) error {
+			bw.buf = appendRecord(bw.buf, k, v)
+			if len(bw.buf) < backupBlockSize {
+				return nil
+			}
+			return bw.flushData()
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := bw.flushData(); err != nil {
+			return nil, err
+		}
+		m.Tables = append(m.Tables, t)
+	}
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if err := bw.block(blockManifest, manifest); err != nil {
+		return nil, err
+	}
+	if err := bw.w.Flush(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BackupToPath - copies db to MDBX environment at `path` (creates it if not exists) and writes BackupManifestFileName there.
+// In incremental mode `path` must contain previous backup. Manifest of previous backup at `path` is merged with new one:
+// it describes all tables of `path`, not only copied ones.
+func (db *MdbxKV) BackupToPath(ctx context.Context, path string, opts BackupOpts) (*BackupManifest, error) {
+	prev, err := readBackupManifest(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	buckets := kv.TableCfg{}
+	for name, cfg := range db.buckets {
+		buckets[name] = cfg
+	}
+	dst, err := NewMDBX(db.log).Path(path).WithTableCfg(func(_ kv.TableCfg) kv.TableCfg { return buckets }).Open()
+	if err != nil {
+		return nil, err
+	}
+	defer dst.Close()
+
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	tables, unchanged, err := backupTables(ctx, tx.(*MdbxTx), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// all tables in 1 write transaction: on failure `path` keeps previous backup
+	m := &BackupManifest{ViewID: tx.ViewID(), SinceViewID: opts.sinceViewID()}
+	if err := dst.Update(ctx, func(dstTx kv.RwTx) error {
+		for _, name := range tables {
+			if err := dstTx.ClearBucket(name); err != nil {
+				return err
+			}
+			c, err := dstTx.RwCursor(name)
+			if err != nil {
+				return err
+			}
+			t, err := forEachRecord(ctx, tx, name, appendRecordTo(c))
+			c.Close()
+			if err != nil {
+				return fmt.Errorf("table %s, %w", name, err)
+			}
+			m.Tables = append(m.Tables, t)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	m.Tables = mergeBackupTables(prev, unchanged, m.Tables)
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// write+rename: manifest file is never half-written
+	tmpFile := filepath.Join(path, BackupManifestFileName+".tmp")
+	if err := os.WriteFile(tmpFile, manifest, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpFile, filepath.Join(path, BackupManifestFileName)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// mergeBackupTables - tables of previous manifest replaced by newer ones, sorted by name
+func mergeBackupTables(prev *BackupManifest, newer ...[]BackupTable) []BackupTable {
+	byName := map[string]BackupTable{}
+	if prev != nil {
+		for _, t := range prev.Tables {
+			byName[t.Name] = t
+		}
+	}
+	for _, tables := range newer {
+		for _, t := range tables {
+			byName[t.Name] = t
+		}
+	}
+	res := make([]BackupTable, 0, len(byName))
+	for _, t := range byName {
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func readBackupManifest(path string) (*BackupManifest, error) {
+	manifest, err := os.ReadFile(filepath.Join(path, BackupManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	m := &BackupManifest{}
+	if err := json.Unmarshal(manifest, m); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBackupFormat, err)
+	}
+	return m, nil
+}
+
+// appendRecordTo - records come in order of table, but Append doesn't support empty key (it's always first record)
+func appendRecordTo(c kv.RwCursor) func(k, v []byte) error {
+	return func(k, v []byte) error {
+		if len(k) == 0 {
+			return c.Put(k, v)
+		}
+		return c.Append(k, v)
+	}
+}
+
+// Restore - reads backup stream and writes it to `db` in 1 transaction. Tables of stream are cleared before write.
+// Checksums are verified, on any mismatch transaction is rolled back.
+func Restore(ctx context.Context, r io.Reader, db kv.RwDB) (*BackupManifest, error) {
+	var m *BackupManifest
+	if err := db.Update(ctx, func(tx kv.RwTx) error {
+		var c kv.RwCursor
+		defer func() {
+			if c != nil {
+				c.Close()
+			}
+		}()
+		var err error
+		m, err = readBackup(ctx, r, func(table string) error {
+			if c != nil {
+				c.Close()
+			}
+			if err := tx.ClearBucket(table); err != nil {
+				return err
+			}
+			var err error
+			c, err = tx.RwCursor(table)
+			return err
+		}, func(k, v []byte) error {
+			return appendRecordTo(c)(k, v)
+		})
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// VerifyBackup - checks checksums of backup stream and that all tables are known by `tables` (kv.ChaindataTablesCfg if nil)
+func VerifyBackup(ctx context.Context, r io.Reader, tables kv.TableCfg) (*BackupManifest, error) {
+	if tables == nil {
+		tables = kv.ChaindataTablesCfg
+	}
+	return readBackup(ctx, r, func(table string) error {
+		if _, ok := tables[table]; !ok {
+			return fmt.Errorf("%w: unknown table %s", ErrBackupFormat, table)
+		}
+		return nil
+	}, func(k, v []byte) error { return nil })
+}
+
+// VerifyBackupPath - opens backup created by BackupToPath in MDBX_VALIDATION mode, checks it by VerifyBackupDB
+// against BackupManifestFileName. `tables` - see VerifyBackup.
+func VerifyBackupPath(ctx context.Context, path string, tables kv.TableCfg) (*BackupManifest, error) {
+	if tables == nil {
+		tables = kv.ChaindataTablesCfg
+	}
+	m, err := readBackupManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	backupTables := kv.TableCfg{}
+	for _, t := range m.Tables {
+		cfg, ok := tables[t.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown table %s", ErrBackupFormat, t.Name)
+		}
+		backupTables[t.Name] = cfg
+	}
+	db, err := NewMDBX(log.Root()).Path(path).Readonly().Flags(func(f uint) uint { return f | mdbxValidation }).
+		WithTableCfg(func(_ kv.TableCfg) kv.TableCfg { return backupTables }).Open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := VerifyBackupDB(ctx, db, m, tables); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// VerifyBackupDB - checks that tables of db (for example created by BackupToPath) match manifest and are known by `tables`
+// (kv.ChaindataTablesCfg if nil)
+func VerifyBackupDB(ctx context.Context, db kv.RoDB, m *BackupManifest, tables kv.TableCfg) error {
+	if tables == nil {
+		tables = kv.ChaindataTablesCfg
+	}
+	return db.View(ctx, func(tx kv.Tx) error {
+		for _, expect := range m.Tables {
+			if _, ok := tables[expect.Name]; !ok {
+				return fmt.Errorf("%w: unknown table %s", ErrBackupFormat, expect.Name)
+			}
+			got, err := forEachRecord(ctx, tx, expect.Name, func(k, v []byte) error { return nil })
+			if err != nil {
+				return err
+			}
+			if got != expect {
+				return fmt.Errorf("%w: table %s, entries %d, expected %d", ErrBackupChecksum, expect.Name, got.Entries, expect.Entries)
+			}
+		}
+		return nil
+	})
+}
+
+// backupTables - list of tables to backup. In incremental mode reads all tables: `unchanged` - tables which match
+// opts.Since, they are not in the list.
+func backupTables(ctx context.Context, tx *MdbxTx, opts BackupOpts) (res []string, unchanged []BackupTable, err error) {
+	tables := opts.Tables
+	if len(tables) == 0 {
+		for name, cfg := range tx.db.buckets {
+			if cfg.IsDeprecated || cfg.DBI == NonExistingDBI {
+				continue
+			}
+			tables = append(tables, name)
+		}
+	}
+	prev := map[string]BackupTable{}
+	if opts.Since != nil {
+		for _, t := range opts.Since.Tables {
+			prev[t.Name] = t
+		}
+	}
+	res = make([]string, 0, len(tables))
+	for _, name := range tables {
+		if _, ok := tx.db.buckets[name]; !ok {
+			return nil, nil, fmt.Errorf("backup: unknown table %s", name)
+		}
+		if p, ok := prev[name]; ok {
+			t, err := forEachRecord(ctx, tx, name, func(k, v []byte) error { return nil })
+			if err != nil {
+				return nil, nil, err
+			}
+			if t == p {
+				unchanged = append(unchanged, t)
+				continue
+			}
+		}
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res, unchanged, nil
+}
+
+func forEachRecord(ctx context.Context, tx kv.Tx, table string, f func(k, v []byte) error) (BackupTable, error) {
+	t := BackupTable{Name: table}
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return t, err
+	}
+	defer c.Close()
+	var rec []byte
+	for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return t, err
+		}
+		if t.Entries%10_000 == 0 {
+			select {
+			case <-ctx.Done():
+				return t, ctx.Err()
+			default:
+			}
+		}
+		rec = appendRecord(rec[:0], k, v)
+		t.Checksum = crc32.Update(t.Checksum, castagnoli, rec)
+		t.Entries++
+		if err := f(k, v); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+func appendRecord(buf, k, v []byte) []byte {
+	var lenBuf [binary.MaxVarintLen64]byte
+	buf = append(buf, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(k)))]...)
+	buf = append(buf, k...)
+	buf = append(buf, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(v)))]...)
+	return append(buf, v...)
+}
+
+// block format: kind(1 byte), uvarint payload len, payload, crc32(kind+payload) 4 bytes big-endian
+type backupWriter struct {
+	w   *bufio.Writer
+	buf []byte // records of current data block
+}
+
+func (bw *backupWriter) block(kind byte, payload []byte) error {
+	var hdr [1 + binary.MaxVarintLen64]byte
+	hdr[0] = kind
+	n := binary.PutUvarint(hdr[1:], uint64(len(payload)))
+	crc := crc32.Update(crc32.Checksum(hdr[:1], castagnoli), castagnoli, payload)
+	if _, err := bw.w.Write(hdr[:1+n]); err != nil {
+		return err
+	}
+	if _, err := bw.w.Write(payload); err != nil {
+		return err
+	}
+	return binary.Write(bw.w, binary.BigEndian, crc)
+}
+
+func (bw *backupWriter) flushData() error {
+	if len(bw.buf) == 0 {
+		return nil
+	}
+	if err := bw.block(blockData, bw.buf); err != nil {
+		return err
+	}
+	bw.buf = bw.buf[:0]
+	return nil
+}
+
+func readBackup(ctx context.Context, r io.Reader, onTable func(table string) error, onRecord func(k, v []byte) error) (*BackupManifest, error) {
+	br := bufio.NewReaderSize(r, 4*backupBlockSize)
+	magic := make([]byte, len(backupMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != backupMagic {
+		return nil, fmt.Errorf("%w: magic %x", ErrBackupFormat, magic)
+	}
+
+	var tables []BackupTable
+	var payload, rec []byte
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		kind, err := br.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("%w: no manifest", ErrBackupFormat)
+			}
+			return nil, err
+		}
+		l, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if l > maxBlockSize {
+			return nil, fmt.Errorf("%w: block too big %d", ErrBackupFormat, l)
+		}
+		// buffer grows with data actually read: garbage length doesn't allocate before EOF
+		buf := bytes.NewBuffer(payload[:0])
+		if _, err := io.CopyN(buf, br, int64(l)); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		payload = buf.Bytes()
+		var crc uint32
+		if err := binary.Read(br, binary.BigEndian, &crc); err != nil {
+			return nil, err
+		}
+		if crc != crc32.Update(crc32.Checksum([]byte{kind}, castagnoli), castagnoli, payload) {
+			return nil, fmt.Errorf("%w: block kind %d, after %d tables", ErrBackupChecksum, kind, len(tables))
+		}
+
+		switch kind {
+		case blockTable:
+			tables = append(tables, BackupTable{Name: string(payload)})
+			if err := onTable(string(payload)); err != nil {
+				return nil, err
+			}
+		case blockData:
+			if len(tables) == 0 {
+				return nil, fmt.Errorf("%w: data block before table block", ErrBackupFormat)
+			}
+			t := &tables[len(tables)-1]
+			for p := payload; len(p) > 0; {
+				k, v, n, err := parseRecord(p)
+				if err != nil {
+					return nil, err
+				}
+				rec = append(rec[:0], p[:n]...)
+				p = p[n:]
+				t.Checksum = crc32.Update(t.Checksum, castagnoli, rec)
+				t.Entries++
+				if err := onRecord(k, v); err != nil {
+					return nil, fmt.Errorf("table %s: %w", t.Name, err)
+				}
+			}
+		case blockManifest:
+			m := &BackupManifest{}
+			if err := json.Unmarshal(payload, m); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrBackupFormat, err)
+			}
+			if len(m.Tables) != len(tables) {
+				return nil, fmt.Errorf("%w: manifest has %d tables, stream has %d", ErrBackupFormat, len(m.Tables), len(tables))
+			}
+			for i := range tables {
+				if m.Tables[i] != tables[i] {
+					return nil, fmt.Errorf("%w: table %s", ErrBackupChecksum, tables[i].Name)
+				}
+			}
+			return m, nil
+		default:
+			return nil, fmt.Errorf("%w: block kind %d", ErrBackupFormat, kind)
+		}
+	}
+}
+
+func parseRecord(p []byte) (k, v []byte, n int, err error) {
+	kl, n1 := binary.Uvarint(p)
+	if n1 <= 0 || uint64(len(p)-n1) < kl {
+		return nil, nil, 0, fmt.Errorf("%w: broken record", ErrBackupFormat)
+	}
+	k = p[n1 : n1+int(kl)]
+	p2 := p[n1+int(kl):]
+	vl, n2 := binary.Uvarint(p2)
+	if n2 <= 0 || uint64(len(p2)-n2) < vl {
+		return nil, nil, 0, fmt.Errorf("%w: broken record", ErrBackupFormat)
+	}
+	v = p2[n2 : n2+int(vl)]
+	return k, v, n1 + int(kl) + n2 + int(vl), nil
+}
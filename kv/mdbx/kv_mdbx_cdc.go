@@ -0,0 +1,303 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package mdbx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/torquem-ch/mdbx-go/mdbx"
+)
+
+// ChangeFeedSequence - name of sequence (in kv.Sequence table) which stores amount of published changes.
+// Sequence is updated in same RwTx as data - so Seq numbers are gap-less and survive restarts.
+const ChangeFeedSequence = "mdbx_change_feed"
+
+const DefaultChangeFeedBufferSize = 4096
+
+var (
+	ErrChangeFeedGap    = errors.New("change feed: requested seq is not in buffer anymore")
+	ErrChangeFeedClosed = errors.New("change feed: closed")
+)
+
+// ChangeKind - what happened with Table
+type ChangeKind uint8
+
+const (
+	ChangeWrite    ChangeKind = iota // Put/Delete of Key
+	ChangeClear                      // ClearBucket: all keys are deleted
+	ChangeDrop                       // DropBucket: table is deleted
+	ChangeBulk                       // tx did more writes than feed's buffer can hold: writes are not recorded one by one - re-scan the table
+	ChangeOverflow                   // last item before channel is closed: subscriber was too slow - re-subscribe from Seq
+)
+
+// Change - one committed write to watched table
+//   - OldValue is nil if key didn't exist before write
+//   - NewValue is nil if key was deleted
+//   - Key, OldValue, NewValue are nil if Kind is not ChangeWrite
+type Change struct {
+	Seq      uint64
+	Kind     ChangeKind
+	Table    string
+	Key      []byte
+	OldValue []byte
+	NewValue []byte
+}
+
+// ChangeFeed - change-data-capture of committed writes to configured tables.
+// MdbxTx does collect changes in-memory and publish them only after successful Commit (Rollback drops them).
+// Tx keeps at most `bufferSize` changes: if tx writes more - its changes are replaced by ChangeBulk of every written table.
+// Last `bufferSize` changes are kept in-memory: subscriber can resume from any Seq which is still in buffer,
+// older Seq returns ErrChangeFeedGap - then subscriber must re-scan the table.
+// Only non-DupSort tables are supported: DupSort tables can have many values per key.
+type ChangeFeed struct {
+	tables     map[string]struct{}
+	bufferSize int
+
+	lock    sync.Mutex
+	buf     []Change            // last published changes, ordered by Seq
+	nextSeq uint64              // Seq of next change to publish
+	pending map[uint64][]Change // batches committed out-of-order: BeginRw of next tx may happen before publish of previous
+	subs    map[uint64]chan Change
+	subID   uint64
+	closed  bool
+}
+
+func (opts MdbxOpts) ChangeFeed(bufferSize int, tables ...string) MdbxOpts {
+	opts.changeFeedBufferSize = bufferSize
+	opts.changeFeedTables = tables
+	return opts
+}
+
+func newChangeFeed(db *MdbxKV, bufferSize int, tables []string) (*ChangeFeed, error) {
+	if bufferSize <= 0 {
+		bufferSize = DefaultChangeFeedBufferSize
+	}
+	f := &ChangeFeed{
+		tables:     make(map[string]struct{}, len(tables)),
+		bufferSize: bufferSize,
+		pending:    map[uint64][]Change{},
+		subs:       map[uint64]chan Change{},
+	}
+	if _, ok := db.buckets[kv.Sequence]; !ok {
+		return nil, fmt.Errorf("change feed: requires %s table", kv.Sequence)
+	}
+	for _, name := range tables {
+		cfg, ok := db.buckets[name]
+		if !ok {
+			return nil, fmt.Errorf("change feed: unknown table %s", name)
+		}
+		if cfg.Flags&kv.DupSort != 0 || cfg.AutoDupSortKeysConversion {
+			return nil, fmt.Errorf("change feed: DupSort tables are not supported: %s", name)
+		}
+		if name == kv.Sequence {
+			return nil, fmt.Errorf("change feed: can't watch %s table", name)
+		}
+		f.tables[name] = struct{}{}
+	}
+	var published uint64
+	if err := db.env.View(func(txn *mdbx.Txn) error {
+		v, err := txn.Get(mdbx.DBI(db.buckets[kv.Sequence].DBI), []byte(ChangeFeedSequence))
+		if err != nil {
+			if mdbx.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		published = binary.BigEndian.Uint64(v)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	f.nextSeq = published + 1
+	return f, nil
+}
+
+func (f *ChangeFeed) watched(table string) bool {
+	_, ok := f.tables[table]
+	return ok
+}
+
+// LastSeq - Seq of last published change, 0 if nothing published yet
+func (f *ChangeFeed) LastSeq() uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.nextSeq - 1
+}
+
+// Subscribe - returns stream of changes starting from `fromSeq`. fromSeq=0 means: only new changes.
+// Already published changes (Seq >= fromSeq) are replayed from buffer first.
+// If subscriber doesn't read fast enough (channel is full) - it receives ChangeOverflow and channel is closed:
+// subscriber can re-subscribe from Seq of ChangeOverflow. Channel closed without ChangeOverflow means
+// unsubscribe or close of DB.
+func (f *ChangeFeed) Subscribe(fromSeq uint64) (ch <-chan Change, unsubscribe func(), err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return nil, nil, ErrChangeFeedClosed
+	}
+	sub := make(chan Change, len(f.buf)+f.bufferSize+1) // enough to replay buffer without blocking, +1 for ChangeOverflow
+	if fromSeq != 0 {
+		oldest := f.nextSeq - uint64(len(f.buf))
+		if fromSeq < oldest {
+			return nil, nil, fmt.Errorf("%w: seq=%d, oldest=%d", ErrChangeFeedGap, fromSeq, oldest)
+		}
+		if fromSeq > f.nextSeq {
+			return nil, nil, fmt.Errorf("change feed: seq=%d is in future, next=%d", fromSeq, f.nextSeq)
+		}
+		for _, c := range f.buf[fromSeq-oldest:] {
+			sub <- c
+		}
+	}
+	f.subID++
+	id := f.subID
+	f.subs[id] = sub
+	return sub, func() { f.unsubscribe(id) }, nil
+}
+
+func (f *ChangeFeed) unsubscribe(id uint64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if sub, ok := f.subs[id]; ok {
+		close(sub)
+		delete(f.subs, id)
+	}
+}
+
+// reserve - assigns Seq to changes of given tx and persists new value of ChangeFeedSequence.
+// Must be called inside RwTx before commit - MDBX has only 1 writer, so no concurrent reservations.
+func (f *ChangeFeed) reserve(tx *mdbx.Txn, dbi mdbx.DBI, changes []Change) error {
+	var published uint64
+	v, err := tx.Get(dbi, []byte(ChangeFeedSequence))
+	if err != nil && !mdbx.IsNotFound(err) {
+		return err
+	}
+	if len(v) > 0 {
+		published = binary.BigEndian.Uint64(v)
+	}
+	for i := range changes {
+		changes[i].Seq = published + 1 + uint64(i)
+	}
+	newV := make([]byte, 8)
+	binary.BigEndian.PutUint64(newV, published+uint64(len(changes)))
+	return tx.Put(dbi, []byte(ChangeFeedSequence), newV, 0)
+}
+
+// publish - changes of committed tx. Batches are published strictly in Seq order.
+func (f *ChangeFeed) publish(changes []Change) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return
+	}
+	f.pending[changes[0].Seq] = changes
+	for {
+		batch, ok := f.pending[f.nextSeq]
+		if !ok {
+			return
+		}
+		delete(f.pending, f.nextSeq)
+		for _, c := range batch {
+			for id, sub := range f.subs {
+				if len(sub) < cap(sub)-1 { // only this method sends to subscribers after Subscribe
+					sub <- c
+					continue
+				}
+				// slow subscriber: it will re-subscribe from Seq of ChangeOverflow
+				sub <- Change{Seq: c.Seq, Kind: ChangeOverflow}
+				close(sub)
+				delete(f.subs, id)
+			}
+		}
+		f.buf = append(f.buf, batch...)
+		if len(f.buf) > 2*f.bufferSize { // amortize trimming
+			f.buf = append(make([]Change, 0, 2*f.bufferSize), f.buf[len(f.buf)-f.bufferSize:]...)
+		}
+		f.nextSeq += uint64(len(batch))
+	}
+}
+
+func (f *ChangeFeed) close() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	for id, sub := range f.subs {
+		close(sub)
+		delete(f.subs, id)
+	}
+}
+
+func (tx *MdbxTx) recordChange(table string, k, oldV, newV []byte) {
+	if _, ok := tx.bulkTables[table]; ok {
+		return
+	}
+	if len(tx.changes) >= tx.db.cdc.bufferSize {
+		tx.recordBulk()
+		if _, ok := tx.bulkTables[table]; ok {
+			return
+		}
+	}
+	tx.changes = append(tx.changes, Change{Table: table, Key: common.Copy(k), OldValue: common.Copy(oldV), NewValue: common.Copy(newV)})
+}
+
+// recordTableChange - ChangeClear or ChangeDrop
+func (tx *MdbxTx) recordTableChange(table string, kind ChangeKind) {
+	if tx.db.cdc == nil || !tx.db.cdc.watched(table) {
+		return
+	}
+	tx.changes = append(tx.changes, Change{Table: table, Kind: kind})
+}
+
+// recordBulk - replaces recorded writes by ChangeBulk of each written table, next writes to this tables are not recorded
+func (tx *MdbxTx) recordBulk() {
+	if tx.bulkTables == nil {
+		tx.bulkTables = map[string]struct{}{}
+	}
+	bulk := tx.changes[:0]
+	for _, c := range tx.changes {
+		if _, ok := tx.bulkTables[c.Table]; ok {
+			continue
+		}
+		tx.bulkTables[c.Table] = struct{}{}
+		bulk = append(bulk, Change{Table: c.Table, Kind: ChangeBulk})
+	}
+	tx.changes = bulk
+}
+
+// putWatched - Put with recording of previous value into change feed
+func (c *MdbxCursor) putWatched(key, value []byte) error {
+	prev, err := c.tx.tx.Get(c.dbi, key)
+	if err != nil {
+		if !mdbx.IsNotFound(err) {
+			return fmt.Errorf("table: %s, err: %w", c.bucketName, err)
+		}
+		prev = nil
+	}
+	prev = common.Copy(prev) // value belongs to mdbx page, which may be changed by put
+	if err := c.put(key, value); err != nil {
+		return fmt.Errorf("table: %s, err: %w", c.bucketName, err)
+	}
+	c.tx.recordChange(c.bucketName, key, prev, value)
+	return nil
+}
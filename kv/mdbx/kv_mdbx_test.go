@@ -17,9 +17,14 @@
 package mdbx
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"io"
 	"testing"
+	"time"
 
+	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/order"
 	"github.com/ledgerwatch/log/v3"
@@ -772,3 +777,260 @@ func TestAutoConversionSeekBothRange(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, v)
 }
+
+func TestChangeFeed(t *testing.T) {
+	ctx, path, logger := context.Background(), t.TempDir(), log.New()
+	tableCfg := func(defaultBuckets kv.TableCfg) kv.TableCfg {
+		return kv.TableCfg{
+			"Table":     kv.TableCfgItem{},
+			"DupTable":  kv.TableCfgItem{Flags: kv.DupSort},
+			kv.Sequence: kv.TableCfgItem{},
+		}
+	}
+	_, err := NewMDBX(logger).Path(t.TempDir()).WithTableCfg(tableCfg).ChangeFeed(0, "DupTable").Open()
+	require.Error(t, err)
+
+	db := NewMDBX(logger).Path(path).WithTableCfg(tableCfg).ChangeFeed(0, "Table").MustOpen()
+	feed := db.(*MdbxKV).ChangeFeed()
+	ch, unsubscribe, err := feed.Subscribe(0)
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		require.NoError(t, tx.Put("Table", []byte("a"), []byte("1")))
+		require.NoError(t, tx.Put("Table", []byte("a"), []byte("2")))
+		require.NoError(t, tx.Append("Table", []byte("b"), []byte("3")))
+		require.NoError(t, tx.Put("DupTable", []byte("a"), []byte("1"))) // not watched
+		return tx.Delete("Table", []byte("a"))
+	}))
+	// rolled back changes are not published
+	tx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Put("Table", []byte("c"), []byte("4")))
+	tx.Rollback()
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error { return tx.ClearBucket("Table") }))
+
+	expect := []Change{
+		{Seq: 1, Table: "Table", Key: []byte("a"), NewValue: []byte("1")},
+		{Seq: 2, Table: "Table", Key: []byte("a"), OldValue: []byte("1"), NewValue: []byte("2")},
+		{Seq: 3, Table: "Table", Key: []byte("b"), NewValue: []byte("3")},
+		{Seq: 4, Table: "Table", Key: []byte("a"), OldValue: []byte("2")},
+		{Seq: 5, Kind: ChangeClear, Table: "Table"},
+	}
+	for _, e := range expect {
+		require.Equal(t, e, <-ch)
+	}
+	require.Equal(t, uint64(5), feed.LastSeq())
+
+	// resume from the middle of buffer
+	ch2, unsubscribe2, err := feed.Subscribe(4)
+	require.NoError(t, err)
+	require.Equal(t, expect[3], <-ch2)
+	require.Equal(t, expect[4], <-ch2)
+	unsubscribe2()
+
+	// seq survives restart, but buffer doesn't
+	db.Close()
+	_, ok := <-ch
+	require.False(t, ok)
+	db = NewMDBX(logger).Path(path).WithTableCfg(tableCfg).ChangeFeed(0, "Table").MustOpen()
+	defer db.Close()
+	feed = db.(*MdbxKV).ChangeFeed()
+	require.Equal(t, uint64(5), feed.LastSeq())
+	_, _, err = feed.Subscribe(1)
+	require.ErrorIs(t, err, ErrChangeFeedGap)
+
+	// tx with more writes than buffer can hold, slow subscriber
+	small := NewMDBX(logger).InMem(t.TempDir()).WithTableCfg(tableCfg).ChangeFeed(2, "Table").MustOpen()
+	defer small.Close()
+	feed = small.(*MdbxKV).ChangeFeed()
+	ch, _, err = feed.Subscribe(0)
+	require.NoError(t, err)
+	require.NoError(t, small.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(0); i < 3; i++ {
+			require.NoError(t, tx.Put("Table", []byte{i}, []byte{i}))
+		}
+		return nil
+	}))
+	require.Equal(t, Change{Seq: 1, Kind: ChangeBulk, Table: "Table"}, <-ch)
+	for i := byte(0); i < 3; i++ {
+		require.NoError(t, small.Update(ctx, func(tx kv.RwTx) error { return tx.Put("Table", []byte{i}, []byte{10}) }))
+	}
+	require.Equal(t, uint64(2), (<-ch).Seq)
+	require.Equal(t, uint64(3), (<-ch).Seq)
+	require.Equal(t, Change{Seq: 4, Kind: ChangeOverflow}, <-ch)
+	_, ok = <-ch
+	require.False(t, ok)
+	ch, unsubscribe, err = feed.Subscribe(4)
+	require.NoError(t, err)
+	defer unsubscribe()
+	require.Equal(t, Change{Seq: 4, Table: "Table", Key: []byte{2}, OldValue: []byte{2}, NewValue: []byte{10}}, <-ch)
+}
+
+func TestBackup(t *testing.T) {
+	ctx, logger := context.Background(), log.New()
+	src := NewMDBX(logger).InMem(t.TempDir()).MustOpen().(*MdbxKV)
+	defer src.Close()
+	require.NoError(t, src.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(0); i < 10; i++ {
+			require.NoError(t, tx.Put(kv.HeaderNumber, []byte{i}, []byte{i, i}))
+			require.NoError(t, tx.Put(kv.PlainState, bytes.Repeat([]byte{i}, 20), []byte{i}))
+		}
+		return nil
+	}))
+
+	var full bytes.Buffer
+	m, err := src.Backup(ctx, &full, BackupOpts{})
+	require.NoError(t, err)
+	_, err = VerifyBackup(ctx, bytes.NewReader(full.Bytes()), nil)
+	require.NoError(t, err)
+
+	dst := NewMDBX(logger).InMem(t.TempDir()).MustOpen()
+	defer dst.Close()
+	restored, err := Restore(ctx, bytes.NewReader(full.Bytes()), dst)
+	require.NoError(t, err)
+	require.Equal(t, m, restored)
+	require.NoError(t, VerifyBackupDB(ctx, dst, m, nil))
+
+	corrupted := common.Copy(full.Bytes())
+	corrupted[len(backupMagic)+2]++ // name of first table
+	_, err = VerifyBackup(ctx, bytes.NewReader(corrupted), nil)
+	require.ErrorIs(t, err, ErrBackupChecksum)
+
+	// incremental: only modified tables
+	require.NoError(t, src.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.HeaderNumber, []byte{100}, []byte{1})
+	}))
+	var incremental bytes.Buffer
+	m2, err := src.Backup(ctx, &incremental, BackupOpts{Since: m})
+	require.NoError(t, err)
+	require.Equal(t, m.ViewID, m2.SinceViewID)
+	require.Equal(t, 1, len(m2.Tables))
+	require.Equal(t, kv.HeaderNumber, m2.Tables[0].Name)
+	_, err = Restore(ctx, &incremental, dst)
+	require.NoError(t, err)
+	require.NoError(t, dst.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.HeaderNumber, []byte{100})
+		require.NoError(t, err)
+		require.Equal(t, []byte{1}, v)
+		c, err := tx.Cursor(kv.PlainState)
+		require.NoError(t, err)
+		defer c.Close()
+		cnt, err := c.Count()
+		require.NoError(t, err)
+		require.Equal(t, uint64(10), cnt)
+		return nil
+	}))
+
+	// to path
+	path := t.TempDir()
+	m3, err := src.BackupToPath(ctx, path, BackupOpts{Tables: []string{kv.HeaderNumber, kv.PlainState}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(m3.Tables))
+	verified, err := VerifyBackupPath(ctx, path, nil)
+	require.NoError(t, err)
+	require.Equal(t, m3, verified)
+	copied := NewMDBX(logger).Path(path).MustOpen()
+	require.NoError(t, VerifyBackupDB(ctx, copied, m3, nil))
+	copied.Close()
+	_, err = VerifyBackupPath(ctx, t.TempDir(), nil) // no manifest
+	require.Error(t, err)
+
+	// incremental to path: manifest keeps unchanged tables
+	require.NoError(t, src.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.PlainState, []byte{200}, []byte{1})
+	}))
+	m4, err := src.BackupToPath(ctx, path, BackupOpts{Tables: []string{kv.HeaderNumber, kv.PlainState}, Since: m3})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(m4.Tables))
+	require.Equal(t, m3.Tables[0], m4.Tables[0]) // HeaderNumber not changed
+	require.Equal(t, kv.PlainState, m4.Tables[1].Name)
+	require.Equal(t, m3.Tables[1].Entries+1, m4.Tables[1].Entries)
+	verified, err = VerifyBackupPath(ctx, path, nil)
+	require.NoError(t, err)
+	require.Equal(t, m4, verified)
+
+	// garbage length of block is rejected before allocation
+	blockOfLen := func(l uint64) []byte {
+		var lenBuf [binary.MaxVarintLen64]byte
+		return append(append([]byte(backupMagic), blockData), lenBuf[:binary.PutUvarint(lenBuf[:], l)]...)
+	}
+	_, err = VerifyBackup(ctx, bytes.NewReader(blockOfLen(1<<40)), nil)
+	require.ErrorIs(t, err, ErrBackupFormat)
+	_, err = VerifyBackup(ctx, bytes.NewReader(blockOfLen(1<<30)), nil) // within limit, but no data
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestBackupDupSortAndEmptyKeys(t *testing.T) {
+	ctx, logger := context.Background(), log.New()
+	src := NewMDBX(logger).InMem(t.TempDir()).MustOpen().(*MdbxKV)
+	defer src.Close()
+	storageKey := func(i byte) []byte { // address+incarnation+location, stored in PlainState as dupsort
+		k := make([]byte, 20+8+32)
+		k[0], k[len(k)-1] = 1, i
+		return k
+	}
+	require.NoError(t, src.Update(ctx, func(tx kv.RwTx) error {
+		require.NoError(t, tx.Put(kv.HeaderNumber, []byte{}, []byte{1}))
+		require.NoError(t, tx.Put(kv.HeaderNumber, []byte{1}, []byte{2}))
+		for i := byte(1); i <= 3; i++ {
+			require.NoError(t, tx.Put(kv.AccountChangeSet, []byte{1}, []byte{i}))
+			require.NoError(t, tx.Put(kv.PlainState, storageKey(i), []byte{i}))
+		}
+		return nil
+	}))
+	tables := []string{kv.AccountChangeSet, kv.HeaderNumber, kv.PlainState}
+
+	var stream bytes.Buffer
+	m, err := src.Backup(ctx, &stream, BackupOpts{Tables: tables})
+	require.NoError(t, err)
+	dst := NewMDBX(logger).InMem(t.TempDir()).MustOpen()
+	defer dst.Close()
+	_, err = Restore(ctx, &stream, dst)
+	require.NoError(t, err)
+	require.NoError(t, VerifyBackupDB(ctx, dst, m, nil))
+
+	path := t.TempDir()
+	m2, err := src.BackupToPath(ctx, path, BackupOpts{Tables: tables})
+	require.NoError(t, err)
+	require.Equal(t, m.Tables, m2.Tables)
+	_, err = VerifyBackupPath(ctx, path, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, dst.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.HeaderNumber, []byte{})
+		require.NoError(t, err)
+		require.Equal(t, []byte{1}, v)
+		c, err := tx.CursorDupSort(kv.AccountChangeSet)
+		require.NoError(t, err)
+		defer c.Close()
+		cnt, err := c.Count()
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), cnt)
+		v, err = tx.GetOne(kv.PlainState, storageKey(2))
+		require.NoError(t, err)
+		require.Equal(t, []byte{2}, v)
+		return nil
+	}))
+
+	// unknown table
+	_, err = VerifyBackupPath(ctx, path, kv.TableCfg{kv.HeaderNumber: {}})
+	require.ErrorIs(t, err, ErrBackupFormat)
+}
+
+func TestTrackTxs(t *testing.T) {
+	db := NewMDBX(log.New()).InMem(t.TempDir()).TrackTxs(time.Hour).MustOpen().(*MdbxKV)
+	defer db.Close()
+	require.Empty(t, db.OpenTxs())
+
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback() // failed assertion must not block db.Close
+	txs := db.OpenTxs()
+	require.Len(t, txs, 1)
+	require.True(t, txs[0].ReadOnly)
+	require.Equal(t, tx.ViewID(), txs[0].ViewID)
+	require.Contains(t, txs[0].Stack, "kv_mdbx_test.go")
+	tx.Rollback()
+	require.Empty(t, db.OpenTxs())
+}
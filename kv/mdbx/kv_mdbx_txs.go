@@ -0,0 +1,84 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package mdbx
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// TrackTxs - remember creation stack, time and ViewID of every open transaction (see MdbxKV.OpenTxs)
+// and log transactions older than `longTxThreshold`. Capturing stack is not free - don't enable it on hot paths without need.
+func (opts MdbxOpts) TrackTxs(longTxThreshold time.Duration) MdbxOpts {
+	opts.longTxThreshold = longTxThreshold
+	return opts
+}
+
+type txsTracker struct {
+	lock   sync.Mutex
+	txs    map[uint64]kv.TxInfo
+	idGen  uint64
+	cancel context.CancelFunc // stops watchdog
+}
+
+func (db *MdbxKV) startTxsTracking() {
+	ctx, cancel := context.WithCancel(context.Background())
+	db.txs = &txsTracker{txs: map[uint64]kv.TxInfo{}, cancel: cancel}
+	go kv.WatchLongTxs(ctx, db.opts.label.String(), db.opts.longTxThreshold, db.OpenTxs, db.log)
+}
+
+func (db *MdbxKV) trackTx(tx *MdbxTx) {
+	if db.txs == nil {
+		return
+	}
+	info := kv.TxInfo{ViewID: tx.tx.ID(), ReadOnly: tx.readOnly, Created: time.Now(), Stack: dbg.Stack()}
+	db.txs.lock.Lock()
+	defer db.txs.lock.Unlock()
+	db.txs.idGen++
+	info.ID = db.txs.idGen
+	db.txs.txs[info.ID] = info
+	tx.trackID = info.ID
+}
+
+func (db *MdbxKV) untrackTx(tx *MdbxTx) {
+	if db.txs == nil {
+		return
+	}
+	db.txs.lock.Lock()
+	defer db.txs.lock.Unlock()
+	delete(db.txs.txs, tx.trackID)
+}
+
+// OpenTxs - open transactions, oldest first. Returns nil if MdbxOpts.TrackTxs was not configured.
+func (db *MdbxKV) OpenTxs() []kv.TxInfo {
+	if db.txs == nil {
+		return nil
+	}
+	db.txs.lock.Lock()
+	res := make([]kv.TxInfo, 0, len(db.txs.txs))
+	for _, info := range db.txs.txs {
+		res = append(res, info)
+	}
+	db.txs.lock.Unlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
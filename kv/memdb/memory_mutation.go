@@ -16,7 +16,9 @@ package memdb
 import (
 	"bytes"
 	"context"
+	"fmt"
 
+	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv/iter"
 	"github.com/ledgerwatch/erigon-lib/kv/order"
 	"github.com/ledgerwatch/log/v3"
@@ -32,6 +34,11 @@ type MemoryMutation struct {
 	clearedTables    map[string]struct{}
 	db               kv.Tx
 	statelessCursors map[string]kv.RwCursor
+
+	undo       []undoEntry
+	savepoints []int // len of undo-log at moment of savepoint creation
+
+	initialSequences map[string][]byte // sequences of parent tx at moment of NewMemoryBatch, see Diff
 }
 
 // NewMemoryBatch - starts in-mem batch
@@ -42,22 +49,27 @@ type MemoryMutation struct {
 // defer batch.Rollback()
 // ... some calculations on `batch`
 // batch.Commit()
+//
+// `tx` can be another MemoryMutation - then batch is a layer on top of it. Many layers (for example: competing
+// fork branches) can share same parent, and see its later changes. Layer can be merged into parent by Collapse.
 func NewMemoryBatch(tx kv.Tx, tmpDir string) *MemoryMutation {
 	tmpDB := mdbx.NewMDBX(log.New()).InMem(tmpDir).MustOpen()
 	memTx, err := tmpDB.BeginRw(context.Background())
 	if err != nil {
 		panic(err)
 	}
-	if err := initSequences(tx, memTx); err != nil {
+	initialSequences, err := initSequences(tx, memTx)
+	if err != nil {
 		return nil
 	}
 
 	return &MemoryMutation{
-		db:             tx,
-		memDb:          tmpDB,
-		memTx:          memTx,
-		deletedEntries: make(map[string]map[string]struct{}),
-		clearedTables:  make(map[string]struct{}),
+		db:               tx,
+		memDb:            tmpDB,
+		memTx:            memTx,
+		deletedEntries:   make(map[string]map[string]struct{}),
+		clearedTables:    make(map[string]struct{}),
+		initialSequences: initialSequences,
 	}
 }
 
@@ -66,6 +78,18 @@ func (m *MemoryMutation) UpdateTxn(tx kv.Tx) {
 	m.statelessCursors = nil
 }
 
+// Parent - tx on top of which batch is built. Can be MemoryMutation (layer of stack).
+func (m *MemoryMutation) Parent() kv.Tx { return m.db }
+
+// Depth - amount of MemoryMutation layers in stack, including this one
+func (m *MemoryMutation) Depth() int {
+	depth := 1
+	for parent, ok := m.db.(*MemoryMutation); ok; parent, ok = parent.db.(*MemoryMutation) {
+		depth++
+	}
+	return depth
+}
+
 func (m *MemoryMutation) isTableCleared(table string) bool {
 	_, ok := m.clearedTables[table]
 	return ok
@@ -84,23 +108,28 @@ func (m *MemoryMutation) DBSize() (uint64, error) {
 	panic("not implemented")
 }
 
-func initSequences(db kv.Tx, memTx kv.RwTx) error {
+func initSequences(db kv.Tx, memTx kv.RwTx) (map[string][]byte, error) {
 	cursor, err := db.Cursor(kv.Sequence)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	sequences := map[string][]byte{}
 	for k, v, err := cursor.First(); k != nil; k, v, err = cursor.Next() {
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if err := memTx.Put(kv.Sequence, k, v); err != nil {
-			return err
+			return nil, err
 		}
+		sequences[string(k)] = common.Copy(v)
 	}
-	return nil
+	return sequences, nil
 }
 
 func (m *MemoryMutation) IncrementSequence(bucket string, amount uint64) (uint64, error) {
+	if err := m.saveKey(kv.Sequence, []byte(bucket)); err != nil {
+		return 0, err
+	}
 	return m.memTx.IncrementSequence(bucket, amount)
 }
 
@@ -174,10 +203,16 @@ func (m *MemoryMutation) Has(table string, key []byte) (bool, error) {
 }
 
 func (m *MemoryMutation) Put(table string, k, v []byte) error {
+	if err := m.saveKey(table, k); err != nil {
+		return err
+	}
 	return m.memTx.Put(table, k, v)
 }
 
 func (m *MemoryMutation) Append(table string, key []byte, value []byte) error {
+	if err := m.saveKey(table, key); err != nil {
+		return err
+	}
 	return m.memTx.Append(table, key, value)
 }
 
@@ -258,6 +293,9 @@ func (m *MemoryMutation) ForPrefix(bucket string, prefix []byte, walker func(k,
 }
 
 func (m *MemoryMutation) Delete(table string, k []byte) error {
+	if err := m.saveKey(table, k); err != nil {
+		return err
+	}
 	if _, ok := m.deletedEntries[table]; !ok {
 		m.deletedEntries[table] = make(map[string]struct{})
 	}
@@ -284,6 +322,11 @@ func (m *MemoryMutation) BucketSize(bucket string) (uint64, error) {
 	return m.memTx.BucketSize(bucket)
 }
 
+// TableStats - not supported: pages of overlay and of underlying db can't be merged to stats of view which Get/Cursor expose
+func (m *MemoryMutation) TableStats(bucket string) (kv.TableStat, error) {
+	return kv.TableStat{}, fmt.Errorf("%w: TableStats of table %s in MemoryMutation", kv.ErrNotSupported, bucket)
+}
+
 func (m *MemoryMutation) DropBucket(bucket string) error {
 	panic("Not implemented")
 }
@@ -297,6 +340,9 @@ func (m *MemoryMutation) ListBuckets() ([]string, error) {
 }
 
 func (m *MemoryMutation) ClearBucket(bucket string) error {
+	if err := m.saveTable(bucket); err != nil {
+		return err
+	}
 	m.clearedTables[bucket] = struct{}{}
 	return m.memTx.ClearBucket(bucket)
 }
@@ -368,6 +414,42 @@ func (m *MemoryMutation) Flush(tx kv.RwTx) error {
 	return nil
 }
 
+// Collapse - merges this layer into parent MemoryMutation and releases it. Batch can't be used after Collapse.
+// Other layers on top of same parent will see merged changes.
+// Unlike Flush - applies Diff: sequences which layer didn't change are not copied back into parent
+// (parent may increment them after layer was created).
+func (m *MemoryMutation) Collapse() error {
+	parent, ok := m.db.(*MemoryMutation)
+	if !ok {
+		return fmt.Errorf("memdb: can't collapse batch into %T, use Flush", m.db)
+	}
+	it, err := m.Diff()
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		e, err := it.Next()
+		if err != nil {
+			return err
+		}
+		switch e.Op {
+		case DiffClear:
+			err = parent.ClearBucket(e.Table)
+		case DiffDelete:
+			err = parent.Delete(e.Table, e.Key)
+		case DiffPut:
+			err = parent.Put(e.Table, e.Key, e.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	it.Close()
+	m.Rollback()
+	return nil
+}
+
 // Check if a bucket is dupsorted and has dupsort conversion off
 func isTablePurelyDupsort(bucket string) bool {
 	config, ok := kv.ChaindataTablesCfg[bucket]
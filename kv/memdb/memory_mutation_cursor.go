@@ -138,7 +138,10 @@ func (m *memoryMutationCursor) convertAutoDupsort(key []byte, value []byte) []by
 	if len(key) != config.DupToLen {
 		return key
 	}
-	return append(key, value[:config.DupFromLen-config.DupToLen]...)
+	// new slice: key may belong to cursor of parent layer
+	res := make([]byte, 0, config.DupFromLen)
+	res = append(res, key...)
+	return append(res, value[:config.DupFromLen-config.DupToLen]...)
 }
 
 // Current return the current key and values the cursor is on.
@@ -325,22 +328,51 @@ func (m *memoryMutationCursor) Append(k []byte, v []byte) error {
 }
 
 func (m *memoryMutationCursor) AppendDup(k []byte, v []byte) error {
+	if err := m.mutation.saveKey(m.table, k); err != nil {
+		return err
+	}
 	return m.memCursor.AppendDup(common.Copy(k), common.Copy(v))
 }
 
 func (m *memoryMutationCursor) PutNoDupData(key, value []byte) error {
-	panic("Not implemented")
+	if err := m.mutation.saveKey(m.table, key); err != nil {
+		return err
+	}
+	return m.memCursor.PutNoDupData(common.Copy(key), common.Copy(value))
 }
 
 func (m *memoryMutationCursor) Delete(k []byte) error {
 	return m.mutation.Delete(m.table, k)
 }
 
+// DeleteCurrent - batch can delete only all values of key of DupSort table, see DeleteCurrentDuplicates
 func (m *memoryMutationCursor) DeleteCurrent() error {
-	panic("DeleteCurrent Not implemented")
+	if isTablePurelyDupsort(m.table) {
+		return fmt.Errorf("%w: DeleteCurrent of DupSort table %s in MemoryMutation", kv.ErrNotSupported, m.table)
+	}
+	k, _, err := m.Current()
+	if err != nil {
+		return err
+	}
+	if k == nil {
+		return nil
+	}
+	return m.Delete(k)
 }
+
+// DeleteExact - batch can delete only all values of key of DupSort table, see DeleteCurrentDuplicates
 func (m *memoryMutationCursor) DeleteExact(k1, k2 []byte) error {
-	panic("DeleteExact Not implemented")
+	if isTablePurelyDupsort(m.table) {
+		return fmt.Errorf("%w: DeleteExact of DupSort table %s in MemoryMutation", kv.ErrNotSupported, m.table)
+	}
+	v, err := m.mutation.GetOne(m.table, k1)
+	if err != nil {
+		return err
+	}
+	if v == nil || !bytes.Equal(v, k2) {
+		return nil
+	}
+	return m.Delete(k1)
 }
 
 func (m *memoryMutationCursor) DeleteCurrentDuplicates() error {
@@ -0,0 +1,187 @@
+/*
+   Copyright 2023 Erigon contributors
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+       http://www.apache.org/licenses/LICENSE-2.0
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package memdb
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+type DiffOp uint8
+
+const (
+	DiffPut    DiffOp = iota + 1
+	DiffDelete        // Value is nil. For DupSort tables: all values of Key
+	DiffClear         // whole table is cleared. Key and Value are nil
+)
+
+func (op DiffOp) String() string {
+	switch op {
+	case DiffPut:
+		return "put"
+	case DiffDelete:
+		return "delete"
+	case DiffClear:
+		return "clear"
+	default:
+		return "unknown"
+	}
+}
+
+type DiffEntry struct {
+	Table string
+	Op    DiffOp
+	Key   []byte
+	Value []byte
+}
+
+// DiffIter - implements iter.Unary[DiffEntry]
+type DiffIter struct {
+	m       *MemoryMutation
+	tables  []string
+	table   string
+	pending []DiffEntry // clear and deletes of current table
+	c       kv.Cursor   // puts of current table
+	started bool
+
+	next    DiffEntry
+	hasNext bool
+	err     error
+}
+
+// Diff - all changes of batch. Tables are sorted by name, for every table: DiffClear (if table was cleared),
+// then DiffDelete (sorted by key), then DiffPut (sorted by key and value). Applying entries in this order to parent
+// gives same result as Flush (Flush does clear all tables first, but tables are independent).
+// Key and Value are valid until batch is modified. kv.Sequence table has only sequences changed by batch
+// (NewMemoryBatch copies all sequences of parent tx, unchanged ones are skipped).
+//
+//	it, err := batch.Diff()
+//	defer it.Close()
+//	for it.HasNext() {
+//	    e, err := it.Next()
+//	    ...
+//	}
+func (m *MemoryMutation) Diff() (*DiffIter, error) {
+	buckets, err := m.memTx.ListBuckets()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{}, len(buckets))
+	for _, t := range buckets {
+		seen[t] = struct{}{}
+	}
+	for t := range m.clearedTables {
+		seen[t] = struct{}{}
+	}
+	for t := range m.deletedEntries {
+		seen[t] = struct{}{}
+	}
+	it := &DiffIter{m: m, tables: make([]string, 0, len(seen))}
+	for t := range seen {
+		it.tables = append(it.tables, t)
+	}
+	sort.Strings(it.tables)
+	it.advance()
+	return it, nil
+}
+
+func (it *DiffIter) HasNext() bool { return it.hasNext }
+
+func (it *DiffIter) Next() (DiffEntry, error) {
+	if it.err != nil {
+		it.hasNext = false
+		return DiffEntry{}, it.err
+	}
+	e := it.next
+	it.advance()
+	return e, nil
+}
+
+func (it *DiffIter) Close() {
+	if it.c != nil {
+		it.c.Close()
+		it.c = nil
+	}
+}
+
+func (it *DiffIter) advance() {
+	for {
+		if len(it.pending) > 0 {
+			it.next, it.pending = it.pending[0], it.pending[1:]
+			it.hasNext = true
+			return
+		}
+		if it.c != nil {
+			var k, v []byte
+			if it.started {
+				k, v, it.err = it.c.Next()
+			} else {
+				k, v, it.err = it.c.First()
+				it.started = true
+			}
+			if it.err != nil {
+				it.hasNext = true // return error by Next
+				return
+			}
+			if k != nil {
+				if it.table == kv.Sequence && it.m.isSequenceUnchanged(k, v) {
+					continue
+				}
+				it.next = DiffEntry{Table: it.table, Op: DiffPut, Key: k, Value: v}
+				it.hasNext = true
+				return
+			}
+			it.Close()
+		}
+		if len(it.tables) == 0 {
+			it.hasNext = false
+			return
+		}
+		it.table, it.tables = it.tables[0], it.tables[1:]
+		it.pending = it.m.clearsAndDeletes(it.table)
+		it.c, it.err = it.m.memTx.Cursor(it.table)
+		if it.err != nil {
+			it.hasNext = true
+			return
+		}
+		it.started = false
+	}
+}
+
+func (m *MemoryMutation) clearsAndDeletes(table string) []DiffEntry {
+	var res []DiffEntry
+	if m.isTableCleared(table) {
+		res = append(res, DiffEntry{Table: table, Op: DiffClear})
+	}
+	deleted := make([]string, 0, len(m.deletedEntries[table]))
+	for k := range m.deletedEntries[table] {
+		deleted = append(deleted, k)
+	}
+	sort.Strings(deleted)
+	for _, k := range deleted {
+		res = append(res, DiffEntry{Table: table, Op: DiffDelete, Key: []byte(k)})
+	}
+	return res
+}
+
+// isSequenceUnchanged - sequence has same value as NewMemoryBatch did copy from parent
+func (m *MemoryMutation) isSequenceUnchanged(k, v []byte) bool {
+	if m.isTableCleared(kv.Sequence) {
+		return false
+	}
+	initial, ok := m.initialSequences[string(k)]
+	return ok && bytes.Equal(initial, v)
+}
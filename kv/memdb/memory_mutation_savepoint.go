@@ -0,0 +1,178 @@
+/*
+   Copyright 2023 Erigon contributors
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+       http://www.apache.org/licenses/LICENSE-2.0
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package memdb
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+)
+
+// Savepoints are implemented by undo-log: while at least 1 savepoint exists, every write saves previous state
+// of touched key (or of whole table - for ClearBucket). RollbackTo replays undo-log backwards.
+// Without savepoints writes have no overhead.
+
+// undoEntry - state of key (or of table, if clear=true) before write
+type undoEntry struct {
+	table      string
+	key        []byte
+	prev       [][]byte // values of key in memTx before write (many - for DupSort)
+	wasDeleted bool     // key was in deletedEntries before write
+
+	clear      bool
+	pairs      [][]byte // ClearBucket: key, value, key, value, ... of table in memTx before clear
+	wasCleared bool
+	deleted    map[string]struct{} // ClearBucket: deletedEntries of table before clear
+}
+
+// Savepoint - marks current state of batch, returns id of savepoint. Savepoints can be nested:
+//
+//	sp := batch.Savepoint()
+//	if err := applyTx(batch); err != nil {
+//	    return batch.RollbackTo(sp) // writes of failed tx are undone, earlier writes of batch are kept
+//	}
+//	batch.ReleaseSavepoint(sp)
+func (m *MemoryMutation) Savepoint() int {
+	m.savepoints = append(m.savepoints, len(m.undo))
+	return len(m.savepoints)
+}
+
+// RollbackTo - undoes all writes done after savepoint `id`. Nested savepoints (created after `id`) are released,
+// savepoint `id` itself stays valid - can rollback to it again.
+func (m *MemoryMutation) RollbackTo(id int) error {
+	if id <= 0 || id > len(m.savepoints) {
+		return fmt.Errorf("memdb: unknown savepoint %d, have %d", id, len(m.savepoints))
+	}
+	from := m.savepoints[id-1]
+	for i := len(m.undo) - 1; i >= from; i-- {
+		if err := m.applyUndo(&m.undo[i]); err != nil {
+			return err
+		}
+		m.undo[i] = undoEntry{} // release memory
+	}
+	m.undo = m.undo[:from]
+	m.savepoints = m.savepoints[:id]
+	return nil
+}
+
+// ReleaseSavepoint - forgets savepoint `id` and all nested ones. Writes are kept.
+func (m *MemoryMutation) ReleaseSavepoint(id int) {
+	if id <= 0 || id > len(m.savepoints) {
+		return
+	}
+	m.savepoints = m.savepoints[:id-1]
+	if len(m.savepoints) == 0 {
+		m.undo = nil
+	}
+}
+
+func (m *MemoryMutation) hasSavepoints() bool { return len(m.savepoints) > 0 }
+
+// saveKey - must be called before any write to `key`
+func (m *MemoryMutation) saveKey(table string, key []byte) error {
+	if !m.hasSavepoints() {
+		return nil
+	}
+	e := undoEntry{table: table, key: common.Copy(key), wasDeleted: m.isEntryDeleted(table, key)}
+	if isTablePurelyDupsort(table) {
+		c, err := m.memTx.CursorDupSort(table)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		for k, v, err := c.SeekExact(key); k != nil; k, v, err = c.NextDup() {
+			if err != nil {
+				return err
+			}
+			e.prev = append(e.prev, common.Copy(v))
+		}
+	} else {
+		v, err := m.memTx.GetOne(table, key)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			e.prev = [][]byte{common.Copy(v)}
+		}
+	}
+	m.undo = append(m.undo, e)
+	return nil
+}
+
+// saveTable - must be called before ClearBucket
+func (m *MemoryMutation) saveTable(table string) error {
+	if !m.hasSavepoints() {
+		return nil
+	}
+	e := undoEntry{table: table, clear: true, wasCleared: m.isTableCleared(table)}
+	if deleted, ok := m.deletedEntries[table]; ok {
+		e.deleted = make(map[string]struct{}, len(deleted))
+		for k := range deleted {
+			e.deleted[k] = struct{}{}
+		}
+	}
+	c, err := m.memTx.Cursor(table)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		e.pairs = append(e.pairs, common.Copy(k), common.Copy(v))
+	}
+	m.undo = append(m.undo, e)
+	return nil
+}
+
+func (m *MemoryMutation) applyUndo(e *undoEntry) error {
+	if e.clear {
+		if err := m.memTx.ClearBucket(e.table); err != nil {
+			return err
+		}
+		for i := 0; i < len(e.pairs); i += 2 {
+			if err := m.memTx.Put(e.table, e.pairs[i], e.pairs[i+1]); err != nil {
+				return err
+			}
+		}
+		if !e.wasCleared {
+			delete(m.clearedTables, e.table)
+		}
+		if e.deleted == nil {
+			delete(m.deletedEntries, e.table)
+		} else {
+			m.deletedEntries[e.table] = e.deleted
+		}
+		return nil
+	}
+
+	if err := m.memTx.Delete(e.table, e.key); err != nil {
+		return err
+	}
+	for _, v := range e.prev {
+		if err := m.memTx.Put(e.table, e.key, v); err != nil {
+			return err
+		}
+	}
+	if e.wasDeleted {
+		if _, ok := m.deletedEntries[e.table]; !ok {
+			m.deletedEntries[e.table] = make(map[string]struct{})
+		}
+		m.deletedEntries[e.table][string(e.key)] = struct{}{}
+	} else if deleted, ok := m.deletedEntries[e.table]; ok {
+		delete(deleted, string(e.key))
+	}
+	return nil
+}
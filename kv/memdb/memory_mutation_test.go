@@ -14,6 +14,8 @@
 package memdb
 
 import (
+	"encoding/binary"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -664,3 +666,244 @@ func TestAutoConversionSeekBothRange(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, v)
 }
+
+func TestSavepoints(t *testing.T) {
+	_, rwTx := NewTestTx(t)
+
+	initializeDbNonDupSort(rwTx)
+	batch := NewMemoryBatch(rwTx, "")
+	defer batch.Close()
+
+	get := func(k string) []byte {
+		v, err := batch.GetOne(kv.HashedAccounts, []byte(k))
+		require.NoError(t, err)
+		return v
+	}
+
+	require.NoError(t, batch.Put(kv.HashedAccounts, []byte("BAAA"), []byte("value4")))
+	sp1 := batch.Savepoint()
+	require.NoError(t, batch.Put(kv.HashedAccounts, []byte("CAAA"), []byte("value5")))
+	require.NoError(t, batch.Delete(kv.HashedAccounts, []byte("AAAA")))
+	require.NoError(t, batch.Put(kv.HashedAccounts, []byte("DAAA"), []byte("value6")))
+	_, err := batch.IncrementSequence(kv.HashedAccounts, 10)
+	require.NoError(t, err)
+
+	sp2 := batch.Savepoint()
+	require.NoError(t, batch.ClearBucket(kv.HashedAccounts))
+	require.NoError(t, batch.Put(kv.HashedAccounts, []byte("ZZZZ"), []byte("value7")))
+	require.NoError(t, batch.AppendDup(kv.AccountChangeSet, []byte("key1"), []byte("value1.1")))
+	require.Nil(t, get("CBAA"))
+
+	require.NoError(t, batch.RollbackTo(sp2))
+	require.Nil(t, get("ZZZZ"))
+	require.Nil(t, get("AAAA"))
+	require.Equal(t, []byte("value2"), get("CBAA"))
+	require.Equal(t, []byte("value5"), get("CAAA"))
+	require.Equal(t, []byte("value6"), get("DAAA"))
+	seq, err := batch.ReadSequence(kv.HashedAccounts)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), seq)
+	has, err := batch.Has(kv.AccountChangeSet, []byte("key1"))
+	require.NoError(t, err)
+	require.False(t, has)
+
+	// savepoint stays valid after rollback to it
+	require.NoError(t, batch.Put(kv.HashedAccounts, []byte("CAAA"), []byte("value8")))
+	require.NoError(t, batch.RollbackTo(sp2))
+	require.Equal(t, []byte("value5"), get("CAAA"))
+
+	require.NoError(t, batch.RollbackTo(sp1))
+	require.Equal(t, []byte("value"), get("AAAA"))
+	require.Equal(t, []byte("value1"), get("CAAA"))
+	require.Equal(t, []byte("value4"), get("BAAA"))
+	require.Nil(t, get("DAAA"))
+	seq, err = batch.ReadSequence(kv.HashedAccounts)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), seq)
+
+	// nested savepoint is released by rollback of outer one
+	require.Error(t, batch.RollbackTo(sp2))
+	batch.ReleaseSavepoint(sp1)
+	require.Error(t, batch.RollbackTo(sp1))
+	require.Nil(t, batch.undo)
+}
+
+func TestDiff(t *testing.T) {
+	_, rwTx := NewTestTx(t)
+
+	initializeDbNonDupSort(rwTx)
+	initializeDbDupSort(rwTx)
+	_, err := rwTx.IncrementSequence(kv.HashedAccounts, 5)
+	require.NoError(t, err)
+	_, err = rwTx.IncrementSequence(kv.AccountChangeSet, 3)
+	require.NoError(t, err)
+	batch := NewMemoryBatch(rwTx, "")
+	defer batch.Close()
+
+	_, err = batch.IncrementSequence(kv.HashedAccounts, 2)
+	require.NoError(t, err)
+	require.NoError(t, batch.Put(kv.HashedAccounts, []byte("DAAA"), []byte("value4")))
+	require.NoError(t, batch.Put(kv.HashedAccounts, []byte("BAAA"), []byte("value5")))
+	require.NoError(t, batch.Delete(kv.HashedAccounts, []byte("CAAA")))
+	require.NoError(t, batch.Delete(kv.HashedAccounts, []byte("AAAA")))
+	require.NoError(t, batch.ClearBucket(kv.AccountChangeSet))
+	require.NoError(t, batch.Put(kv.AccountChangeSet, []byte("key2"), []byte("value2.2")))
+	require.NoError(t, batch.Put(kv.AccountChangeSet, []byte("key2"), []byte("value2.1")))
+
+	it, err := batch.Diff()
+	require.NoError(t, err)
+	defer it.Close()
+	var got, sequences []string
+	for it.HasNext() {
+		e, err := it.Next()
+		require.NoError(t, err)
+		if e.Table == kv.Sequence {
+			sequences = append(sequences, fmt.Sprintf("%s %s %d", e.Op, e.Key, binary.BigEndian.Uint64(e.Value)))
+			continue
+		}
+		got = append(got, fmt.Sprintf("%s %s %s %s", e.Table, e.Op, e.Key, e.Value))
+	}
+	require.Equal(t, []string{"put HashedAccount 7"}, sequences) // unchanged sequence of AccountChangeSet is skipped
+	require.Equal(t, []string{
+		"AccountChangeSet clear  ",
+		"AccountChangeSet put key2 value2.1",
+		"AccountChangeSet put key2 value2.2",
+		"HashedAccount delete AAAA ",
+		"HashedAccount delete CAAA ",
+		"HashedAccount put BAAA value5",
+		"HashedAccount put DAAA value4",
+	}, got)
+}
+
+func TestCursorDeleteAndPutNoDupData(t *testing.T) {
+	_, rwTx := NewTestTx(t)
+
+	initializeDbNonDupSort(rwTx)
+	initializeDbDupSort(rwTx)
+	batch := NewMemoryBatch(rwTx, "")
+	defer batch.Close()
+
+	c, err := batch.RwCursor(kv.HashedAccounts)
+	require.NoError(t, err)
+	defer c.Close()
+	_, _, err = c.SeekExact([]byte("CAAA"))
+	require.NoError(t, err)
+	require.NoError(t, c.DeleteCurrent())
+	dc := c.(kv.RwCursorDupSort)
+	require.NoError(t, dc.DeleteExact([]byte("CBAA"), []byte("other")))
+	require.NoError(t, dc.DeleteExact([]byte("CCAA"), []byte("value3")))
+
+	sp := batch.Savepoint()
+	dupC, err := batch.RwCursorDupSort(kv.AccountChangeSet)
+	require.NoError(t, err)
+	defer dupC.Close()
+	require.NoError(t, dupC.PutNoDupData([]byte("key2"), []byte("value2.1")))
+	_, _, err = dupC.SeekExact([]byte("key1"))
+	require.NoError(t, err)
+	require.ErrorIs(t, dupC.DeleteCurrent(), kv.ErrNotSupported)
+	require.ErrorIs(t, dupC.DeleteExact([]byte("key1"), []byte("value1.1")), kv.ErrNotSupported)
+	has, err := batch.Has(kv.AccountChangeSet, []byte("key2"))
+	require.NoError(t, err)
+	require.True(t, has)
+	require.NoError(t, batch.RollbackTo(sp))
+	has, err = batch.Has(kv.AccountChangeSet, []byte("key2"))
+	require.NoError(t, err)
+	require.False(t, has)
+
+	var keys []string
+	require.NoError(t, batch.ForEach(kv.HashedAccounts, nil, func(k, v []byte) error {
+		keys = append(keys, string(k))
+		return nil
+	}))
+	require.Equal(t, []string{"AAAA", "CBAA"}, keys)
+}
+
+func TestLayers(t *testing.T) {
+	_, rwTx := NewTestTx(t)
+
+	initializeDbNonDupSort(rwTx)
+	initializeDbDupSort(rwTx)
+
+	all := func(tx kv.Tx, table string) []string {
+		var res []string
+		require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+			res = append(res, string(k)+"="+string(v))
+			return nil
+		}))
+		return res
+	}
+
+	parent := NewMemoryBatch(rwTx, "")
+	defer parent.Close()
+	require.NoError(t, parent.Put(kv.HashedAccounts, []byte("BAAA"), []byte("value4")))
+	require.NoError(t, parent.Delete(kv.HashedAccounts, []byte("CAAA")))
+	require.NoError(t, parent.Put(kv.AccountChangeSet, []byte("key1"), []byte("value1.2")))
+	_, err := parent.IncrementSequence(kv.HashedAccounts, 1)
+	require.NoError(t, err)
+
+	fork1 := NewMemoryBatch(parent, "")
+	fork2 := NewMemoryBatch(parent, "")
+	defer fork2.Close()
+	require.Equal(t, 2, fork1.Depth())
+
+	require.NoError(t, fork1.Delete(kv.HashedAccounts, []byte("BAAA")))
+	require.NoError(t, fork1.Put(kv.HashedAccounts, []byte("CAAA"), []byte("value5")))
+	require.NoError(t, fork1.Put(kv.AccountChangeSet, []byte("key1"), []byte("value1.0")))
+	require.NoError(t, fork1.Put(kv.AccountChangeSet, []byte("key2"), []byte("value2.1")))
+	require.NoError(t, fork1.Delete(kv.AccountChangeSet, []byte("key3")))
+
+	require.NoError(t, fork2.ClearBucket(kv.HashedAccounts))
+	require.NoError(t, fork2.Put(kv.HashedAccounts, []byte("ZZZZ"), []byte("value6")))
+
+	require.Equal(t, []string{"AAAA=value", "BAAA=value4", "CBAA=value2", "CCAA=value3"}, all(parent, kv.HashedAccounts))
+	require.Equal(t, []string{"AAAA=value", "CAAA=value5", "CBAA=value2", "CCAA=value3"}, all(fork1, kv.HashedAccounts))
+	require.Equal(t, []string{"ZZZZ=value6"}, all(fork2, kv.HashedAccounts))
+
+	require.Equal(t, []string{"key1=value1.1", "key1=value1.2", "key1=value1.3", "key3=value3.1", "key3=value3.3"}, all(parent, kv.AccountChangeSet))
+	require.Equal(t, []string{"key1=value1.0", "key1=value1.1", "key1=value1.2", "key1=value1.3", "key2=value2.1"}, all(fork1, kv.AccountChangeSet))
+	require.Equal(t, all(parent, kv.AccountChangeSet), all(fork2, kv.AccountChangeSet))
+
+	c, err := fork1.CursorDupSort(kv.AccountChangeSet)
+	require.NoError(t, err)
+	k, v, err := c.SeekExact([]byte("key1"))
+	require.NoError(t, err)
+	require.Equal(t, "key1=value1.0", string(k)+"="+string(v))
+	var dups []string
+	for k, v, err = c.NextDup(); k != nil; k, v, err = c.NextDup() {
+		require.NoError(t, err)
+		dups = append(dups, string(v))
+	}
+	require.Equal(t, []string{"value1.1", "value1.2", "value1.3"}, dups)
+	c.Close()
+
+	// collapse copies only sequences changed by layer: fork1 has stale copy of HashedAccounts sequence
+	_, err = parent.IncrementSequence(kv.HashedAccounts, 5)
+	require.NoError(t, err)
+	_, err = fork1.IncrementSequence(kv.AccountChangeSet, 3)
+	require.NoError(t, err)
+
+	// sibling sees collapsed changes of other fork
+	require.NoError(t, fork1.Collapse())
+	seq, err := parent.ReadSequence(kv.HashedAccounts)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), seq)
+	seq, err = parent.ReadSequence(kv.AccountChangeSet)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), seq)
+	require.Equal(t, []string{"AAAA=value", "CAAA=value5", "CBAA=value2", "CCAA=value3"}, all(parent, kv.HashedAccounts))
+	require.Equal(t, []string{"key1=value1.0", "key1=value1.1", "key1=value1.2", "key1=value1.3", "key2=value2.1"}, all(fork2, kv.AccountChangeSet))
+	require.Error(t, parent.Collapse())
+
+	require.NoError(t, parent.Flush(rwTx))
+	require.Equal(t, []string{"AAAA=value", "CAAA=value5", "CBAA=value2", "CCAA=value3"}, all(rwTx, kv.HashedAccounts))
+}
+
+func TestTableStatsNotSupported(t *testing.T) {
+	_, rwTx := NewTestTx(t)
+	initializeDbNonDupSort(rwTx)
+	batch := NewMemoryBatch(rwTx, "")
+	defer batch.Close()
+
+	_, err := kv.TableStats(batch, kv.HashedAccounts)
+	require.ErrorIs(t, err, kv.ErrNotSupported)
+}
@@ -0,0 +1,314 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package migrations
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Migrations - ordered list of named schema changes. State is stored in kv.Migrations table under `keyPrefix`
+// (without prefix keys belong to migrations of application), keyPrefix+name -> state:
+//   - stateApplied + unix time of applying. Applied migration is never executed again.
+//   - stateInProgress + progress of not finished migration
+//
+// Migration is executed by steps: every step is 1 RwTx. After each step progress is saved and tx is committed -
+// so interrupted migration resumes from last checkpoint. Step must be idempotent: it can be re-executed with same progress.
+//
+// How to add migration:
+//   - append it to the end of list (never re-order or rename applied migrations)
+//   - for bulk rewrites use Step.Collector: data is loaded to table at the end of step
+//   - return `done=true` from last step
+
+const (
+	stateInProgress byte = 0
+	stateApplied    byte = 1
+)
+
+// keyPrefix - prefix of keys of this package in kv.Migrations: names of migrations of application don't clash
+const keyPrefix = "schema/"
+
+func stateKey(name string) []byte { return []byte(keyPrefix + name) }
+
+// StepFunc - does 1 step of migration. s.Progress is nil on first step.
+// Returns progress to pass to next step, done=true if migration is finished.
+type StepFunc func(ctx context.Context, s *Step) (progress []byte, done bool, err error)
+
+type Migration struct {
+	Name string
+	Up   StepFunc
+}
+
+type Step struct {
+	Tx       kv.RwTx
+	Progress []byte
+	DryRun   bool // tx will be rolled back, collectors are not loaded - only counted
+
+	name       string
+	tmpdir     string
+	collectors []tableCollector
+	report     *Report
+}
+
+type tableCollector struct {
+	table     string
+	collector *etl.Collector
+}
+
+// Report - result of DryRun: amount of affected rows by table
+type Report struct {
+	Name     string
+	Affected map[string]uint64
+}
+
+// Collector - etl.Collector which will be loaded to `table` at the end of step (before saving progress)
+func (s *Step) Collector(table string) *etl.Collector {
+	c := etl.NewCollector(s.name, s.tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	s.collectors = append(s.collectors, tableCollector{table: table, collector: c})
+	return c
+}
+
+// Affected - report rows changed without Collector (for DryRun report)
+func (s *Step) Affected(table string, rows uint64) {
+	s.report.Affected[table] += rows
+}
+
+func (s *Step) close() {
+	for _, c := range s.collectors {
+		c.collector.Close()
+	}
+	s.collectors = nil
+}
+
+func (s *Step) load(ctx context.Context) error {
+	defer s.close()
+	for _, c := range s.collectors {
+		if s.DryRun {
+			var rows uint64
+			if err := c.collector.Load(s.Tx, "", func(k, v []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
+				rows++
+				return nil
+			}, etl.TransformArgs{Quit: ctx.Done()}); err != nil {
+				return err
+			}
+			s.Affected(c.table, rows)
+			continue
+		}
+		if err := c.collector.Load(s.Tx, c.table, etl.IdentityLoadFunc, etl.TransformArgs{Quit: ctx.Done()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Migrator struct {
+	migrations []Migration
+	tmpdir     string
+	logger     log.Logger
+}
+
+func NewMigrator(tmpdir string, logger log.Logger, migrations ...Migration) (*Migrator, error) {
+	seen := make(map[string]struct{}, len(migrations))
+	for _, m := range migrations {
+		if m.Name == "" || m.Up == nil {
+			return nil, fmt.Errorf("migration with empty name or Up func: %q", m.Name)
+		}
+		if _, ok := seen[m.Name]; ok {
+			return nil, fmt.Errorf("duplicated migration name: %s", m.Name)
+		}
+		seen[m.Name] = struct{}{}
+	}
+	return &Migrator{migrations: migrations, tmpdir: tmpdir, logger: logger}, nil
+}
+
+// Applied - names of applied migrations, sorted
+func Applied(tx kv.Tx) ([]string, error) {
+	var names []string
+	if err := tx.ForPrefix(kv.Migrations, []byte(keyPrefix), func(k, v []byte) error {
+		if len(v) > 0 && v[0] == stateApplied {
+			names = append(names, string(k[len(keyPrefix):]))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Pending - names of not applied migrations, in order of execution
+func (m *Migrator) Pending(tx kv.Tx) ([]string, error) {
+	var names []string
+	for _, mig := range m.migrations {
+		applied, _, err := readState(tx, mig.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !applied {
+			names = append(names, mig.Name)
+		}
+	}
+	return names, nil
+}
+
+// Apply - executes pending migrations in order
+func (m *Migrator) Apply(ctx context.Context, db kv.RwDB) error {
+	for _, mig := range m.migrations {
+		if err := m.apply(ctx, db, mig); err != nil {
+			return fmt.Errorf("migration %s: %w", mig.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, db kv.RwDB, mig Migration) error {
+	var applied bool
+	var progress []byte
+	if err := db.View(ctx, func(tx kv.Tx) (err error) {
+		applied, progress, err = readState(tx, mig.Name)
+		return err
+	}); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+	if progress != nil {
+		m.logger.Info("[migrations] resuming", "name", mig.Name, "progress", fmt.Sprintf("%x", progress))
+	} else {
+		m.logger.Info("[migrations] applying", "name", mig.Name)
+	}
+
+	for done := false; !done; {
+		if err := db.Update(ctx, func(tx kv.RwTx) error {
+			s := &Step{Tx: tx, Progress: progress, name: mig.Name, tmpdir: m.tmpdir, report: &Report{Affected: map[string]uint64{}}}
+			defer s.close()
+			var err error
+			if progress, done, err = mig.Up(ctx, s); err != nil {
+				return err
+			}
+			if err = s.load(ctx); err != nil {
+				return err
+			}
+			if !done {
+				return tx.Put(kv.Migrations, stateKey(mig.Name), append([]byte{stateInProgress}, progress...))
+			}
+			return tx.Put(kv.Migrations, stateKey(mig.Name), appliedAt())
+		}); err != nil {
+			return err
+		}
+	}
+	m.logger.Info("[migrations] applied", "name", mig.Name)
+	return nil
+}
+
+// DryRun - executes pending migrations in 1 transaction and rolls it back. Returns amount of affected rows.
+// Direct writes of earlier migrations are visible to later ones, data of Step.Collector is only counted.
+func (m *Migrator) DryRun(ctx context.Context, db kv.RwDB) ([]Report, error) {
+	tx, err := db.BeginRw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var reports []Report
+	for _, mig := range m.migrations {
+		applied, progress, err := readState(tx, mig.Name)
+		if err != nil {
+			return nil, err
+		}
+		if applied {
+			continue
+		}
+		report := Report{Name: mig.Name, Affected: map[string]uint64{}}
+		for done := false; !done; {
+			s := &Step{Tx: tx, Progress: progress, DryRun: true, name: mig.Name, tmpdir: m.tmpdir, report: &report}
+			if progress, done, err = mig.Up(ctx, s); err != nil {
+				s.close()
+				return nil, fmt.Errorf("migration %s: %w", mig.Name, err)
+			}
+			if err = s.load(ctx); err != nil {
+				return nil, fmt.Errorf("migration %s: %w", mig.Name, err)
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// DropDeprecatedTables - migration which drops existing tables from kv.ChaindataDeprecatedTables
+func DropDeprecatedTables(name string) Migration {
+	return Migration{Name: name, Up: func(ctx context.Context, s *Step) ([]byte, bool, error) {
+		for _, table := range kv.ChaindataDeprecatedTables {
+			exists, err := s.Tx.ExistsBucket(table)
+			if err != nil {
+				return nil, false, err
+			}
+			if !exists {
+				continue
+			}
+			if s.DryRun {
+				c, err := s.Tx.Cursor(table)
+				if err != nil {
+					return nil, false, err
+				}
+				cnt, err := c.Count()
+				c.Close()
+				if err != nil {
+					return nil, false, err
+				}
+				s.Affected(table, cnt)
+				continue
+			}
+			if err := s.Tx.DropBucket(table); err != nil {
+				return nil, false, err
+			}
+		}
+		return nil, true, nil
+	}}
+}
+
+// readState - progress is nil if migration is not started
+func readState(tx kv.Tx, name string) (applied bool, progress []byte, err error) {
+	v, err := tx.GetOne(kv.Migrations, stateKey(name))
+	if err != nil || len(v) == 0 {
+		return false, nil, err
+	}
+	switch v[0] {
+	case stateApplied:
+		return true, nil, nil
+	case stateInProgress:
+		return false, common.Copy(v[1:]), nil
+	default:
+		return false, nil, fmt.Errorf("migration %s: unknown state %x", name, v)
+	}
+}
+
+func appliedAt() []byte {
+	v := make([]byte, 9)
+	v[0] = stateApplied
+	binary.BigEndian.PutUint64(v[1:], uint64(time.Now().Unix()))
+	return v
+}
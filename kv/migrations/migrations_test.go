@@ -0,0 +1,117 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// rewrite - copies HeaderNumber into TxLookup, 1 key per step, fails on step `failAt`
+func rewrite(failAt int) Migration {
+	step := 0
+	return Migration{Name: "rewrite", Up: func(ctx context.Context, s *Step) ([]byte, bool, error) {
+		step++
+		if step == failAt {
+			return nil, false, errors.New("interrupted")
+		}
+		c, err := s.Tx.Cursor(kv.HeaderNumber)
+		if err != nil {
+			return nil, false, err
+		}
+		defer c.Close()
+		k, v, err := c.First()
+		if s.Progress != nil {
+			k, v, err = c.Seek(append(s.Progress, 0))
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if k == nil {
+			return nil, true, nil
+		}
+		if err := s.Collector(kv.TxLookup).Collect(k, v); err != nil {
+			return nil, false, err
+		}
+		return k, false, nil
+	}}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx, require := context.Background(), require.New(t)
+	db := memdb.NewTestDB(t)
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		for _, k := range []byte{1, 2, 3} {
+			if err := tx.Put(kv.HeaderNumber, []byte{k}, []byte{k}); err != nil {
+				return err
+			}
+		}
+		return tx.Put(kv.Migrations, []byte("app_migration"), []byte{stateApplied}) // migration of application
+	}))
+
+	_, err := NewMigrator(t.TempDir(), log.New(), rewrite(0), rewrite(0))
+	require.Error(err)
+
+	m, err := NewMigrator(t.TempDir(), log.New(), rewrite(0))
+	require.NoError(err)
+	reports, err := m.DryRun(ctx, db)
+	require.NoError(err)
+	require.Equal([]Report{{Name: "rewrite", Affected: map[string]uint64{kv.TxLookup: 3}}}, reports)
+
+	// interrupted on 3rd step: 2 keys are copied and progress is saved
+	m, err = NewMigrator(t.TempDir(), log.New(), rewrite(3))
+	require.NoError(err)
+	require.Error(m.Apply(ctx, db))
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		pending, err := m.Pending(tx)
+		require.NoError(err)
+		require.Equal([]string{"rewrite"}, pending)
+		_, v, err := readState(tx, "rewrite")
+		require.NoError(err)
+		require.Equal([]byte{2}, v)
+		return nil
+	}))
+
+	// resume
+	m, err = NewMigrator(t.TempDir(), log.New(), rewrite(0), DropDeprecatedTables("drop_deprecated"))
+	require.NoError(err)
+	require.NoError(m.Apply(ctx, db))
+	require.NoError(m.Apply(ctx, db)) // applied migrations are skipped
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		applied, err := Applied(tx)
+		require.NoError(err)
+		require.Equal([]string{"drop_deprecated", "rewrite"}, applied)
+		appKey, err := tx.Has(kv.Migrations, []byte("rewrite")) // keys without prefix belong to application
+		require.NoError(err)
+		require.False(appKey)
+		pending, err := m.Pending(tx)
+		require.NoError(err)
+		require.Empty(pending)
+		for _, k := range []byte{1, 2, 3} {
+			v, err := tx.GetOne(kv.TxLookup, []byte{k})
+			require.NoError(err)
+			require.Equal([]byte{k}, v)
+		}
+		return nil
+	}))
+}
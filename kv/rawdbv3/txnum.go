@@ -108,6 +108,8 @@ func (txNums) WriteForGenesis(tx kv.RwTx, maxTxNum uint64) (err error) {
 	binary.BigEndian.PutUint64(v[:], maxTxNum)
 	return tx.Put(kv.MaxTxNum, k[:], v[:])
 }
+
+// Truncate - deletes blocks >= blockNum. Doesn't notify TxNumsReader's: db with readers is truncated by TxNumsCache.Truncate
 func (txNums) Truncate(tx kv.RwTx, blockNum uint64) (err error) {
 	var seek [8]byte
 	binary.BigEndian.PutUint64(seek[:], blockNum)
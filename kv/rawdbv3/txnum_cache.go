@@ -0,0 +1,324 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package rawdbv3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/recsplit/eliasfano32"
+	"go.uber.org/atomic"
+)
+
+// TxNumsReader - same as TxNums, but keeps in-memory Elias-Fano copy of kv.MaxTxNum for frozen blocks
+// (blocks which will not be unwound - for example blocks in snapshots). Lookups in frozen blocks don't read db:
+// `tx` can be nil if caller knows that block is frozen. Recent blocks are read from db.
+//
+// Frozen part grows by Freeze. TxNumsCache.Truncate unfreezes truncated blocks of all readers of the cache (see Close).
+type TxNumsReader struct {
+	cache  *TxNumsCache
+	lock   sync.Mutex // serializes Freeze/unfreeze, readers use `frozen` without lock
+	frozen atomic.Pointer[frozenTxNums]
+}
+
+// frozenTxNums - immutable: changes create new object
+type frozenTxNums struct {
+	ef     *eliasfano32.EliasFano // maxTxNum of block N is ef.Get(N)
+	blocks uint64                 // amount of valid blocks in `ef`: Truncate doesn't rebuild `ef`
+
+	// txNum -> blockNum in O(1): block of txNum `j<<shift` is samples.Get(j). Step is about avg txs per block,
+	// then FindBlockNum searches only among few blocks between 2 samples.
+	samples *eliasfano32.EliasFano
+	shift   int
+}
+
+// TxNumsCache - open TxNumsReader's of one db. Truncation of kv.MaxTxNum in this db must go through
+// TxNumsCache.Truncate - it's the only way readers learn about it. Readers of other db's are not affected.
+//
+// Truncate runs before its tx is committed, then read txs opened before the commit still see truncated blocks.
+// Freeze by such tx (tx.ViewID() < ViewID of truncating tx) can't freeze truncated blocks: see truncatedBlock.
+type TxNumsCache struct {
+	lock    sync.Mutex
+	readers map[*TxNumsReader]struct{}
+
+	// blocks >= truncatedBlock are frozen only by tx with ViewID >= truncatedViewID (opened after last Truncate)
+	truncatedBlock  uint64
+	truncatedViewID uint64
+	truncated       bool
+}
+
+// NewTxNumsCache - one per db
+func NewTxNumsCache() *TxNumsCache {
+	return &TxNumsCache{readers: map[*TxNumsReader]struct{}{}}
+}
+
+// NewReader - reader must be closed
+func (c *TxNumsCache) NewReader() *TxNumsReader {
+	r := &TxNumsReader{cache: c}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.readers[r] = struct{}{}
+	return r
+}
+
+// Truncate - TxNums.Truncate, truncated blocks are also unfrozen in all open readers of the cache
+func (c *TxNumsCache) Truncate(tx kv.RwTx, blockNum uint64) error {
+	for _, r := range c.truncate(tx.ViewID(), blockNum) {
+		r.unfreeze(blockNum) // Freeze of `r` which saw no truncation yet is finished before it
+	}
+	return TxNums.Truncate(tx, blockNum)
+}
+
+// truncate - sets limit of Freeze, returns readers to unfreeze. They are unfrozen without c.lock: Freeze takes
+// c.lock under TxNumsReader.lock
+func (c *TxNumsCache) truncate(viewID, blockNum uint64) []*TxNumsReader {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.truncated || blockNum < c.truncatedBlock {
+		c.truncatedBlock = blockNum
+	}
+	c.truncatedViewID, c.truncated = viewID, true
+	readers := make([]*TxNumsReader, 0, len(c.readers))
+	for r := range c.readers {
+		readers = append(readers, r)
+	}
+	return readers
+}
+
+// freezeLimit - how many blocks Freeze can take from `tx`
+func (c *TxNumsCache) freezeLimit(tx kv.Tx, toBlock uint64) uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.truncated && tx.ViewID() < c.truncatedViewID && toBlock > c.truncatedBlock {
+		return c.truncatedBlock
+	}
+	return toBlock
+}
+
+// Close - reader doesn't follow TxNumsCache.Truncate anymore and must not be used
+func (r *TxNumsReader) Close() {
+	r.cache.lock.Lock()
+	defer r.cache.lock.Unlock()
+	delete(r.cache.readers, r)
+	r.frozen.Store(nil)
+}
+
+func (r *TxNumsReader) load() (f *frozenTxNums, blocks uint64) {
+	if f = r.frozen.Load(); f != nil {
+		return f, f.blocks
+	}
+	return nil, 0
+}
+
+// FrozenBlocks - blocks [0, FrozenBlocks) are served from memory
+func (r *TxNumsReader) FrozenBlocks() uint64 {
+	_, blocks := r.load()
+	return blocks
+}
+
+// Freeze - makes blocks [0, toBlock) frozen. Already frozen blocks are not read from db again.
+// If `tx` was opened before commit of TxNumsCache.Truncate, blocks are frozen only up to truncated block.
+func (r *TxNumsReader) Freeze(tx kv.Tx, toBlock uint64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	f, blocks := r.load()
+	if toBlock <= blocks {
+		return nil
+	}
+	if limit := r.cache.freezeLimit(tx, toBlock); limit < toBlock {
+		if limit <= blocks {
+			return nil
+		}
+		toBlock = limit
+	}
+
+	maxTxNums := make([]uint64, 0, toBlock)
+	if f != nil {
+		for it := f.ef.Iterator(); it.HasNext() && uint64(len(maxTxNums)) < blocks; {
+			v, _ := it.Next()
+			maxTxNums = append(maxTxNums, v)
+		}
+	}
+	var seek [8]byte
+	binary.BigEndian.PutUint64(seek[:], blocks)
+	c, err := tx.Cursor(kv.MaxTxNum)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, v, err := c.Seek(seek[:]); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		blockNum := binary.BigEndian.Uint64(k)
+		if blockNum >= toBlock {
+			break
+		}
+		if blockNum != uint64(len(maxTxNums)) {
+			return fmt.Errorf("TxNumsReader.Freeze: gap in %s: expected block %d, got %d", kv.MaxTxNum, len(maxTxNums), blockNum)
+		}
+		maxTxNums = append(maxTxNums, binary.BigEndian.Uint64(v))
+	}
+	if uint64(len(maxTxNums)) < toBlock {
+		return fmt.Errorf("TxNumsReader.Freeze: %s has %d blocks, can't freeze %d", kv.MaxTxNum, len(maxTxNums), toBlock)
+	}
+	r.frozen.Store(newFrozenTxNums(maxTxNums))
+	return nil
+}
+
+func newFrozenTxNums(maxTxNums []uint64) *frozenTxNums {
+	lastTxNum := maxTxNums[len(maxTxNums)-1]
+	ef := eliasfano32.NewEliasFano(uint64(len(maxTxNums)), lastTxNum)
+	for _, maxTxNum := range maxTxNums {
+		ef.AddOffset(maxTxNum)
+	}
+	ef.Build()
+
+	shift := bits.Len64((lastTxNum+1)/uint64(len(maxTxNums))) - 1
+	if shift < 0 {
+		shift = 0
+	}
+	samplesAmount := lastTxNum>>shift + 1
+	samples := eliasfano32.NewEliasFano(samplesAmount, uint64(len(maxTxNums)-1))
+	blockNum := uint64(0)
+	for j := uint64(0); j < samplesAmount; j++ {
+		for maxTxNums[blockNum] < j<<shift {
+			blockNum++
+		}
+		samples.AddOffset(blockNum)
+	}
+	samples.Build()
+	return &frozenTxNums{ef: ef, blocks: uint64(len(maxTxNums)), samples: samples, shift: shift}
+}
+
+// unfreeze - blocks >= blockNum are not frozen anymore
+func (r *TxNumsReader) unfreeze(blockNum uint64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	f, blocks := r.load()
+	if blockNum >= blocks {
+		return
+	}
+	if blockNum == 0 {
+		r.frozen.Store(nil)
+		return
+	}
+	truncated := *f
+	truncated.blocks = blockNum
+	r.frozen.Store(&truncated)
+}
+
+func errNotFrozen(method string, blockNum, blocks uint64) error {
+	return fmt.Errorf("TxNumsReader.%s: nil tx, but block %d is not frozen (frozen blocks: %d)", method, blockNum, blocks)
+}
+
+// Max - see TxNums.Max
+func (r *TxNumsReader) Max(tx kv.Tx, blockNum uint64) (maxTxNum uint64, err error) {
+	f, blocks := r.load()
+	if blockNum < blocks {
+		return f.ef.Get(blockNum), nil
+	}
+	if tx == nil {
+		return 0, errNotFrozen("Max", blockNum, blocks)
+	}
+	return TxNums.Max(tx, blockNum)
+}
+
+// Min - see TxNums.Min
+func (r *TxNumsReader) Min(tx kv.Tx, blockNum uint64) (minTxNum uint64, err error) {
+	if blockNum == 0 {
+		return 0, nil
+	}
+	f, blocks := r.load()
+	if blockNum-1 < blocks {
+		return f.ef.Get(blockNum-1) + 1, nil
+	}
+	if tx == nil {
+		return 0, errNotFrozen("Min", blockNum, blocks)
+	}
+	return TxNums.Min(tx, blockNum)
+}
+
+// FindBlockNum - see TxNums.FindBlockNum. Frozen blocks are found in O(1) (see frozenTxNums.samples), binary search
+// over db is done only if `endTxNumMinimax` is not in frozen blocks.
+func (r *TxNumsReader) FindBlockNum(tx kv.Tx, endTxNumMinimax uint64) (ok bool, blockNum uint64, err error) {
+	f, blocks := r.load()
+	if blocks > 0 && endTxNumMinimax <= f.ef.Get(blocks-1) {
+		return true, f.findBlockNum(endTxNumMinimax), nil
+	}
+	if tx == nil {
+		return false, 0, fmt.Errorf("TxNumsReader.FindBlockNum: nil tx, but txNum %d is not in frozen blocks (frozen blocks: %d)", endTxNumMinimax, blocks)
+	}
+	return findBlockNum(tx, blocks, endTxNumMinimax)
+}
+
+// findBlockNum - first block with maxTxNum >= `txNum`, it's between blocks of samples around `txNum`
+func (f *frozenTxNums) findBlockNum(txNum uint64) uint64 {
+	j := txNum >> f.shift
+	from, to := f.samples.Get(j), f.ef.Count()-1
+	if j+1 < f.samples.Count() {
+		to = f.samples.Get(j + 1)
+	}
+	return from + uint64(sort.Search(int(to-from), func(i int) bool {
+		return f.ef.Get(from+uint64(i)) >= txNum
+	}))
+}
+
+// findBlockNum - binary search over blocks [fromBlock, lastBlock] of db
+func findBlockNum(tx kv.Tx, fromBlock, endTxNumMinimax uint64) (ok bool, blockNum uint64, err error) {
+	c, err := tx.Cursor(kv.MaxTxNum)
+	if err != nil {
+		return false, 0, err
+	}
+	defer c.Close()
+	cnt, err := c.Count()
+	if err != nil {
+		return false, 0, err
+	}
+	if fromBlock >= cnt {
+		return false, 0, nil
+	}
+
+	var seek [8]byte
+	i := sort.Search(int(cnt-fromBlock), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		binary.BigEndian.PutUint64(seek[:], fromBlock+uint64(i))
+		var v []byte
+		if _, v, err = c.SeekExact(seek[:]); err != nil {
+			return true
+		}
+		if len(v) == 0 {
+			err = fmt.Errorf("TxNumsReader.FindBlockNum: %s has no block %d", kv.MaxTxNum, fromBlock+uint64(i))
+			return true
+		}
+		return binary.BigEndian.Uint64(v) >= endTxNumMinimax
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	blockNum = fromBlock + uint64(i)
+	if blockNum == cnt {
+		return false, 0, nil
+	}
+	return true, blockNum, nil
+}
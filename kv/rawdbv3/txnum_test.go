@@ -0,0 +1,153 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package rawdbv3_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/rawdbv3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxNumsReader(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require := require.New(t)
+	// block N has N+2 txs
+	var maxTxNum uint64
+	for blockNum := uint64(0); blockNum < 1000; blockNum++ {
+		maxTxNum += blockNum + 2
+		require.NoError(rawdbv3.TxNums.Append(tx, blockNum, maxTxNum-1))
+	}
+
+	cache := rawdbv3.NewTxNumsCache()
+	r := cache.NewReader()
+	defer r.Close()
+	require.Error(r.Freeze(tx, 1001))
+	require.NoError(r.Freeze(tx, 300))
+	require.NoError(r.Freeze(tx, 600))
+	require.Equal(uint64(600), r.FrozenBlocks())
+
+	for blockNum := uint64(0); blockNum < 1000; blockNum++ {
+		expectMax, err := rawdbv3.TxNums.Max(tx, blockNum)
+		require.NoError(err)
+		max, err := r.Max(tx, blockNum)
+		require.NoError(err)
+		require.Equal(expectMax, max)
+
+		expectMin, err := rawdbv3.TxNums.Min(tx, blockNum)
+		require.NoError(err)
+		min, err := r.Min(tx, blockNum)
+		require.NoError(err)
+		require.Equal(expectMin, min)
+
+		for _, txNum := range []uint64{min, max, (min + max) / 2} {
+			ok, foundBlock, err := r.FindBlockNum(tx, txNum)
+			require.NoError(err)
+			require.True(ok)
+			require.Equal(blockNum, foundBlock, txNum)
+		}
+	}
+
+	// frozen blocks don't need tx
+	max, err := r.Max(nil, 599)
+	require.NoError(err)
+	ok, blockNum, err := r.FindBlockNum(nil, max)
+	require.NoError(err)
+	require.True(ok)
+	require.Equal(uint64(599), blockNum)
+	_, _, err = r.FindBlockNum(nil, max+1)
+	require.Error(err)
+	_, err = r.Max(nil, 600)
+	require.Error(err)
+	_, err = r.Min(nil, 601)
+	require.Error(err)
+
+	_, lastTxNum, err := rawdbv3.TxNums.Last(tx)
+	require.NoError(err)
+	ok, _, err = r.FindBlockNum(tx, lastTxNum+1)
+	require.NoError(err)
+	require.False(ok)
+
+	// truncate unfreezes blocks of all readers of the cache and next lookups go to db
+	r2 := cache.NewReader()
+	defer r2.Close()
+	require.NoError(r2.Freeze(tx, 700))
+	other := rawdbv3.NewTxNumsCache().NewReader() // reader of other db
+	defer other.Close()
+	require.NoError(other.Freeze(tx, 700))
+	require.NoError(cache.Truncate(tx, 500))
+	require.Equal(uint64(500), r.FrozenBlocks())
+	require.Equal(uint64(500), r2.FrozenBlocks())
+	require.Equal(uint64(700), other.FrozenBlocks())
+	ok, _, err = r.FindBlockNum(tx, max)
+	require.NoError(err)
+	require.False(ok)
+	require.NoError(rawdbv3.TxNums.Append(tx, 500, max+1000))
+	require.NoError(r.Freeze(tx, 501))
+	max2, err := r.Max(nil, 500)
+	require.NoError(err)
+	require.Equal(max+1000, max2)
+}
+
+func TestTxNumsReaderFreezeBeforeTruncateCommit(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	require := require.New(t)
+	ctx := context.Background()
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		for blockNum := uint64(0); blockNum < 100; blockNum++ {
+			if err := rawdbv3.TxNums.Append(tx, blockNum, blockNum*10+9); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	cache := rawdbv3.NewTxNumsCache()
+	r := cache.NewReader()
+	defer r.Close()
+	oldTx, err := db.BeginRo(ctx)
+	require.NoError(err)
+	defer oldTx.Rollback()
+
+	// truncate is not committed yet: oldTx still sees blocks 50..99, but can't freeze them
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		if err := cache.Truncate(tx, 50); err != nil {
+			return err
+		}
+		require.NoError(r.Freeze(oldTx, 100))
+		require.Equal(uint64(50), r.FrozenBlocks())
+		return nil
+	}))
+
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		require.NoError(r.Freeze(tx, 50))
+		require.Error(r.Freeze(tx, 51))
+		return nil
+	}))
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		return rawdbv3.TxNums.Append(tx, 50, 1000)
+	}))
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		return r.Freeze(tx, 51)
+	}))
+	max, err := r.Max(nil, 50)
+	require.NoError(err)
+	require.Equal(uint64(1000), max)
+}
@@ -26,10 +26,12 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/iter"
 	"github.com/ledgerwatch/erigon-lib/kv/order"
 	"github.com/ledgerwatch/log/v3"
+	"go.uber.org/atomic"
 	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/grpcutil"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
@@ -52,8 +54,17 @@ type RemoteKV struct {
 	buckets      kv.TableCfg
 	roTxsLimiter *semaphore.Weighted
 	opts         remoteOpts
+
+	rwTxsSupported atomic.Bool // server version is checked once, see BeginRw
 }
 
+// rwTxsVersion - first KvServiceAPIVersion of server with read-write transactions (remote.Op_BEGIN_RW)
+var rwTxsVersion = gointerfaces.Version{Major: 6, Minor: 4, Patch: 0}
+
+var _ kv.TemporalRoDb = (*RemoteKV)(nil) // compile-time interface check
+var _ kv.TemporalTx = (*remoteTx)(nil)   // compile-time interface check
+var _ kv.RwTx = (*remoteTx)(nil)         // compile-time interface check
+
 type remoteTx struct {
 	stream             remote.KV_TxClient
 	ctx                context.Context
@@ -64,6 +75,7 @@ type remoteTx struct {
 	streams            []kv.Closer
 	viewID, id         uint64
 	streamingRequested bool
+	rw                 bool // server did begin read-write tx: only stream-based methods are available
 }
 
 type remoteCursor struct {
@@ -178,11 +190,57 @@ func (db *RemoteKV) BeginRo(ctx context.Context) (txn kv.Tx, err error) {
 	return &remoteTx{ctx: ctx, db: db, stream: stream, streamCancelFn: streamCancelFn, viewID: msg.ViewID, id: msg.TxID}, nil
 }
 
+func (db *RemoteKV) BeginTemporalRo(ctx context.Context) (kv.TemporalTx, error) {
+	t, err := db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.(kv.TemporalTx), nil
+}
+
+// BeginRw - server must allow writes by remotedbserver.KvServer.WithWritableTables.
+// Remote RwTx can use only Cursors and Tx methods built on them: Range, Prefix, ForEach, DBSize, TableStats and temporal
+// methods return kv.ErrNotSupported.
+// It holds DB's write lock on server - keep it short. Servers older than `rwTxsVersion` are refused without opening tx.
 func (db *RemoteKV) BeginRw(ctx context.Context) (kv.RwTx, error) {
-	return nil, fmt.Errorf("remote db provider doesn't support .BeginRw method")
+	if err := db.ensureRwTxsSupported(ctx); err != nil {
+		return nil, err
+	}
+	txn, err := db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx := txn.(*remoteTx)
+	if err := tx.stream.Send(&remote.Cursor{Op: remote.Op_BEGIN_RW}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	msg, err := tx.stream.Recv()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx.viewID, tx.rw = msg.ViewID, true
+	return tx, nil
 }
+
+func (db *RemoteKV) ensureRwTxsSupported(ctx context.Context) error {
+	if db.rwTxsSupported.Load() {
+		return nil
+	}
+	v, err := db.remoteKV.Version(ctx, &emptypb.Empty{})
+	if err != nil {
+		return err
+	}
+	if serverVersion := gointerfaces.VersionFromProto(v); serverVersion.Less(rwTxsVersion) {
+		return fmt.Errorf("remote KV server %s doesn't support read-write transactions, need %s or newer", serverVersion, rwTxsVersion)
+	}
+	db.rwTxsSupported.Store(true)
+	return nil
+}
+
 func (db *RemoteKV) BeginRwNosync(ctx context.Context) (kv.RwTx, error) {
-	return nil, fmt.Errorf("remote db provider doesn't support .BeginRw method")
+	return db.BeginRw(ctx)
 }
 
 func (db *RemoteKV) View(ctx context.Context, f func(tx kv.Tx) error) (err error) {
@@ -195,29 +253,130 @@ func (db *RemoteKV) View(ctx context.Context, f func(tx kv.Tx) error) (err error
 	return f(tx)
 }
 
+func (db *RemoteKV) ViewTemporal(ctx context.Context, f func(tx kv.TemporalTx) error) (err error) {
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	return f(tx)
+}
+
 func (db *RemoteKV) Update(ctx context.Context, f func(tx kv.RwTx) error) (err error) {
-	return fmt.Errorf("remote db provider doesn't support .Update method")
+	tx, err := db.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 func (db *RemoteKV) UpdateNosync(ctx context.Context, f func(tx kv.RwTx) error) (err error) {
-	return fmt.Errorf("remote db provider doesn't support .Update method")
+	return db.Update(ctx, f)
 }
 
 func (tx *remoteTx) ViewID() uint64  { return tx.viewID }
 func (tx *remoteTx) CollectMetrics() {}
 func (tx *remoteTx) IncrementSequence(bucket string, amount uint64) (uint64, error) {
-	panic("not implemented yet")
+	return tx.sequence(remote.Op_INCREMENT_SEQUENCE, bucket, hexutility.EncodeTs(amount))
 }
 func (tx *remoteTx) ReadSequence(bucket string) (uint64, error) {
-	panic("not implemented yet")
+	return tx.sequence(remote.Op_READ_SEQUENCE, bucket, nil)
+}
+func (tx *remoteTx) sequence(op remote.Op, bucket string, amount []byte) (uint64, error) {
+	if err := tx.stream.Send(&remote.Cursor{Op: op, BucketName: bucket, V: amount}); err != nil {
+		return 0, err
+	}
+	pair, err := tx.stream.Recv()
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(pair.V), nil
+}
+
+func (tx *remoteTx) Put(bucket string, k, v []byte) error {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return err
+	}
+	return c.(kv.RwCursor).Put(k, v)
+}
+func (tx *remoteTx) Delete(bucket string, k []byte) error {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return err
+	}
+	return c.(kv.RwCursor).Delete(k)
+}
+func (tx *remoteTx) Append(bucket string, k, v []byte) error {
+	c, err := tx.statelessCursor(bucket)
+	if err != nil {
+		return err
+	}
+	return c.(kv.RwCursor).Append(k, v)
+}
+func (tx *remoteTx) AppendDup(bucket string, k, v []byte) error {
+	c, err := tx.CursorDupSort(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.(kv.RwCursorDupSort).AppendDup(k, v)
+}
+
+func (tx *remoteTx) RwCursor(bucket string) (kv.RwCursor, error) {
+	c, err := tx.Cursor(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return c.(kv.RwCursor), nil
+}
+func (tx *remoteTx) RwCursorDupSort(bucket string) (kv.RwCursorDupSort, error) {
+	c, err := tx.CursorDupSort(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return c.(kv.RwCursorDupSort), nil
+}
+
+func (tx *remoteTx) DropBucket(bucket string) error {
+	return fmt.Errorf("remoteTx.DropBucket: %w", kv.ErrNotSupported)
+}
+func (tx *remoteTx) CreateBucket(bucket string) error {
+	return fmt.Errorf("remoteTx.CreateBucket: %w", kv.ErrNotSupported)
+}
+func (tx *remoteTx) ExistsBucket(bucket string) (bool, error) {
+	return false, fmt.Errorf("remoteTx.ExistsBucket: %w", kv.ErrNotSupported)
+}
+func (tx *remoteTx) ClearBucket(bucket string) error {
+	return fmt.Errorf("remoteTx.ClearBucket: %w", kv.ErrNotSupported)
 }
-func (tx *remoteTx) Append(bucket string, k, v []byte) error    { panic("no write methods") }
-func (tx *remoteTx) AppendDup(bucket string, k, v []byte) error { panic("no write methods") }
 
 func (tx *remoteTx) Commit() error {
-	panic("remote db is read-only")
+	if !tx.rw {
+		return fmt.Errorf("remoteTx.Commit: can't commit read-only txn")
+	}
+	if tx.stream == nil {
+		return fmt.Errorf("remoteTx.Commit: txn already closed")
+	}
+	defer tx.Rollback() // server does finish stream after commit, just release resources
+	if err := tx.stream.Send(&remote.Cursor{Op: remote.Op_COMMIT}); err != nil {
+		return err
+	}
+	if _, err := tx.stream.Recv(); err != nil {
+		return err
+	}
+	return nil
 }
 
 func (tx *remoteTx) Rollback() {
+	if tx.stream == nil { // already committed or rolled back
+		return
+	}
 	// don't close opened cursors - just close stream, server will cleanup everything well
 	tx.closeGrpcStream()
 	tx.db.roTxsLimiter.Release(1)
@@ -225,7 +384,25 @@ func (tx *remoteTx) Rollback() {
 		c.Close()
 	}
 }
-func (tx *remoteTx) DBSize() (uint64, error) { panic("not implemented") }
+
+// readOnly - server serves unary requests only in read-only txn, read-write txn has only stream of cursor ops
+func (tx *remoteTx) readOnly(method string) error {
+	if tx.rw {
+		return fmt.Errorf("remoteTx.%s: %w in read-write txn, use cursors", method, kv.ErrNotSupported)
+	}
+	return nil
+}
+
+func (tx *remoteTx) DBSize() (uint64, error) {
+	if err := tx.readOnly("DBSize"); err != nil {
+		return 0, err
+	}
+	reply, err := tx.db.remoteKV.DBSize(tx.ctx, &remote.DBSizeReq{TxId: tx.id})
+	if err != nil {
+		return 0, err
+	}
+	return reply.Size, nil
+}
 
 func (tx *remoteTx) statelessCursor(bucket string) (kv.Cursor, error) {
 	if tx.statelessCursors == nil {
@@ -243,7 +420,24 @@ func (tx *remoteTx) statelessCursor(bucket string) (kv.Cursor, error) {
 	return c, nil
 }
 
-func (tx *remoteTx) BucketSize(name string) (uint64, error) { panic("not implemented") }
+func (tx *remoteTx) BucketSize(name string) (uint64, error) {
+	st, err := tx.TableStats(name)
+	if err != nil {
+		return 0, err
+	}
+	return st.Size(), nil
+}
+
+func (tx *remoteTx) TableStats(name string) (kv.TableStat, error) {
+	if err := tx.readOnly("TableStats"); err != nil {
+		return kv.TableStat{}, err
+	}
+	reply, err := tx.db.remoteKV.TableStats(tx.ctx, &remote.TableStatsReq{TxId: tx.id, Table: name})
+	if err != nil {
+		return kv.TableStat{}, err
+	}
+	return kv.TableStat{Table: name, Entries: reply.Entries, Depth: reply.Depth, BranchPages: reply.BranchPages, LeafPages: reply.LeafPages, OverflowPages: reply.OverflowPages, PageSize: reply.PageSize}, nil
+}
 
 func (tx *remoteTx) ForEach(bucket string, fromPrefix []byte, walker func(k, v []byte) error) error {
 	it, err := tx.Range(bucket, fromPrefix, nil)
@@ -350,11 +544,26 @@ func (tx *remoteTx) ListBuckets() ([]string, error) {
 	return nil, fmt.Errorf("function ListBuckets is not implemented for remoteTx")
 }
 
-func (c *remoteCursor) Put(k []byte, v []byte) error            { panic("not supported") }
-func (c *remoteCursor) PutNoOverwrite(k []byte, v []byte) error { panic("not supported") }
-func (c *remoteCursor) Append(k []byte, v []byte) error         { panic("not supported") }
-func (c *remoteCursor) Delete(k []byte) error                   { panic("not supported") }
-func (c *remoteCursor) DeleteCurrent() error                    { panic("not supported") }
+func (c *remoteCursor) Put(k []byte, v []byte) error { return c.write(remote.Op_PUT, k, v) }
+func (c *remoteCursor) PutNoOverwrite(k []byte, v []byte) error {
+	return errNotSupported("PutNoOverwrite")
+}
+func (c *remoteCursor) Append(k []byte, v []byte) error { return c.write(remote.Op_APPEND, k, v) }
+func (c *remoteCursor) Delete(k []byte) error           { return c.write(remote.Op_DELETE, k, nil) }
+func (c *remoteCursor) DeleteCurrent() error            { return c.write(remote.Op_DELETE_CURRENT, nil, nil) }
+
+func errNotSupported(method string) error {
+	return fmt.Errorf("remote cursor: %s %w", method, kv.ErrNotSupported)
+}
+
+// write - server does check that tx is read-write and table is writable
+func (c *remoteCursor) write(op remote.Op, k, v []byte) error {
+	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: op, K: k, V: v}); err != nil {
+		return err
+	}
+	_, err := c.stream.Recv()
+	return err
+}
 func (c *remoteCursor) Count() (uint64, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_COUNT}); err != nil {
 		return 0, err
@@ -609,11 +818,21 @@ func (c *remoteCursorDupSort) SeekBothRange(k, v []byte) ([]byte, error) {
 	return c.getBothRange(k, v)
 }
 
-func (c *remoteCursorDupSort) DeleteExact(k1, k2 []byte) error    { panic("not supported") }
-func (c *remoteCursorDupSort) AppendDup(k []byte, v []byte) error { panic("not supported") }
-func (c *remoteCursorDupSort) PutNoDupData(k, v []byte) error     { panic("not supported") }
-func (c *remoteCursorDupSort) DeleteCurrentDuplicates() error     { panic("not supported") }
-func (c *remoteCursorDupSort) CountDuplicates() (uint64, error)   { panic("not supported") }
+func (c *remoteCursorDupSort) DeleteExact(k1, k2 []byte) error {
+	return c.write(remote.Op_DELETE_EXACT, k1, k2)
+}
+func (c *remoteCursorDupSort) AppendDup(k []byte, v []byte) error {
+	return c.write(remote.Op_APPEND_DUP, k, v)
+}
+func (c *remoteCursorDupSort) PutNoDupData(k, v []byte) error {
+	return c.write(remote.Op_PUT_NO_DUP_DATA, k, v)
+}
+func (c *remoteCursorDupSort) DeleteCurrentDuplicates() error {
+	return c.write(remote.Op_DELETE_CURRENT_DUPLICATES, nil, nil)
+}
+func (c *remoteCursorDupSort) CountDuplicates() (uint64, error) {
+	return 0, errNotSupported("CountDuplicates")
+}
 
 func (c *remoteCursorDupSort) FirstDup() ([]byte, error)          { return c.firstDup() }
 func (c *remoteCursorDupSort) NextDup() ([]byte, []byte, error)   { return c.nextDup() }
@@ -623,7 +842,21 @@ func (c *remoteCursorDupSort) PrevNoDup() ([]byte, []byte, error) { return c.pre
 func (c *remoteCursorDupSort) LastDup() ([]byte, error)           { return c.lastDup() }
 
 // Temporal Methods
+func (tx *remoteTx) DomainGet(name kv.Domain, k, k2 []byte, ts uint64) (v []byte, ok bool, err error) {
+	if err := tx.readOnly("DomainGet"); err != nil {
+		return nil, false, err
+	}
+	reply, err := tx.db.remoteKV.DomainGet(tx.ctx, &remote.DomainGetReq{TxId: tx.id, Table: string(name), K: k, K2: k2, Ts: ts})
+	if err != nil {
+		return nil, false, err
+	}
+	return reply.V, reply.Ok, nil
+}
+
 func (tx *remoteTx) HistoryGet(name kv.History, k []byte, ts uint64) (v []byte, ok bool, err error) {
+	if err := tx.readOnly("HistoryGet"); err != nil {
+		return nil, false, err
+	}
 	reply, err := tx.db.remoteKV.HistoryGet(tx.ctx, &remote.HistoryGetReq{TxId: tx.id, Table: string(name), K: k, Ts: ts})
 	if err != nil {
 		return nil, false, err
@@ -631,9 +864,12 @@ func (tx *remoteTx) HistoryGet(name kv.History, k []byte, ts uint64) (v []byte,
 	return reply.V, reply.Ok, nil
 }
 
-func (tx *remoteTx) IndexRange(name kv.InvertedIdx, k []byte, fromTs, toTs, limit int) (timestamps iter.U64, err error) {
+func (tx *remoteTx) IndexRange(name kv.InvertedIdx, k []byte, fromTs, toTs int, asc order.By, limit int) (timestamps iter.U64, err error) {
+	if err := tx.readOnly("IndexRange"); err != nil {
+		return nil, err
+	}
 	return iter.PaginateU64(func(pageToken string) (arr []uint64, nextPageToken string, err error) {
-		req := &remote.IndexRangeReq{TxId: tx.id, Table: string(name), K: k, FromTs: int64(fromTs), ToTs: int64(toTs), Limit: int64(limit)}
+		req := &remote.IndexRangeReq{TxId: tx.id, Table: string(name), K: k, FromTs: int64(fromTs), ToTs: int64(toTs), OrderAscend: bool(asc), Limit: int64(limit), PageToken: pageToken}
 		reply, err := tx.db.remoteKV.IndexRange(tx.ctx, req)
 		if err != nil {
 			return nil, "", err
@@ -642,6 +878,34 @@ func (tx *remoteTx) IndexRange(name kv.InvertedIdx, k []byte, fromTs, toTs, limi
 	}), nil
 }
 
+func (tx *remoteTx) HistoryRange(name kv.History, fromTs, toTs int, asc order.By, limit int) (it iter.KV, err error) {
+	if err := tx.readOnly("HistoryRange"); err != nil {
+		return nil, err
+	}
+	return iter.PaginateKV(func(pageToken string) (keys, vals [][]byte, nextPageToken string, err error) {
+		req := &remote.HistoryRangeReq{TxId: tx.id, Table: string(name), FromTs: int64(fromTs), ToTs: int64(toTs), OrderAscend: bool(asc), Limit: int64(limit), PageToken: pageToken}
+		reply, err := tx.db.remoteKV.HistoryRange(tx.ctx, req)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return reply.Keys, reply.Values, reply.NextPageToken, nil
+	}), nil
+}
+
+func (tx *remoteTx) DomainRange(name kv.Domain, k1, k2 []byte, asOfTs uint64, asc order.By, limit int) (it iter.KV, err error) {
+	if err := tx.readOnly("DomainRange"); err != nil {
+		return nil, err
+	}
+	return iter.PaginateKV(func(pageToken string) (keys, vals [][]byte, nextPageToken string, err error) {
+		req := &remote.DomainRangeReq{TxId: tx.id, Table: string(name), FromKey: k1, ToKey: k2, Ts: asOfTs, OrderAscend: bool(asc), Limit: int64(limit), PageToken: pageToken}
+		reply, err := tx.db.remoteKV.DomainRange(tx.ctx, req)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return reply.Keys, reply.Values, reply.NextPageToken, nil
+	}), nil
+}
+
 func (tx *remoteTx) Prefix(table string, prefix []byte) (iter.KV, error) {
 	nextPrefix, ok := kv.NextSubtree(prefix)
 	if !ok {
@@ -688,8 +952,11 @@ func (tx *remoteTx) StreamDescend(table string, fromPrefix, toPrefix []byte, lim
 */
 
 func (tx *remoteTx) rangeOrderLimit(table string, fromPrefix, toPrefix []byte, asc order.By, limit int) (iter.KV, error) {
+	if err := tx.readOnly("Range"); err != nil {
+		return nil, err
+	}
 	return iter.PaginateKV(func(pageToken string) (keys [][]byte, values [][]byte, nextPageToken string, err error) {
-		req := &remote.RangeReq{TxId: tx.id, Table: table, FromPrefix: fromPrefix, ToPrefix: toPrefix, OrderAscend: bool(asc), Limit: int64(limit)}
+		req := &remote.RangeReq{TxId: tx.id, Table: table, FromPrefix: fromPrefix, ToPrefix: toPrefix, OrderAscend: bool(asc), Limit: int64(limit), PageToken: pageToken}
 		reply, err := tx.db.remoteKV.Range(tx.ctx, req)
 		if err != nil {
 			return nil, nil, "", err
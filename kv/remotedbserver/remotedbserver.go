@@ -19,10 +19,12 @@ package remotedbserver
 import (
 	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -30,12 +32,14 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/order"
 	"github.com/ledgerwatch/log/v3"
 	"go.uber.org/atomic"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/dbg"
 	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/types"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -64,7 +68,11 @@ const MaxTxTTL = 60 * time.Second
 // 6.0.0 - Blocks now have system-txs - in the begin/end of block
 // 6.1.0 - Add methods Range, IndexRange, HistoryGet, HistoryRange
 // 6.2.0 - Add HistoryFiles to reply of Snapshots() method
-var KvServiceAPIVersion = &types.VersionReply{Major: 6, Minor: 2, Patch: 0}
+// 6.3.0 - Add methods HistoryRange, DomainRange with pagination
+// 6.4.0 - Add read-write transactions to Tx stream: ops BEGIN_RW, COMMIT, PUT, DELETE, ..., INCREMENT_SEQUENCE
+// 6.5.0 - Add method TableStats
+// 6.6.0 - Add method DBSize
+var KvServiceAPIVersion = &types.VersionReply{Major: 6, Minor: 6, Patch: 0}
 
 type KvServer struct {
 	remote.UnimplementedKVServer // must be embedded to have forward compatible implementations.
@@ -81,12 +89,68 @@ type KvServer struct {
 	txs        map[uint64]*threadSafeTx
 
 	trace     bool
-	rangeStep int // make sure `s.with` has limited time
+	rangeStep int           // make sure `s.with` has limited time
+	txTTL     time.Duration // MaxTxTTL
+
+	writableTables map[string]struct{} // read-write transactions are allowed only if not empty
 }
 
 type threadSafeTx struct {
 	kv.Tx
 	sync.Mutex
+	rw   bool // read-write tx is bound to goroutine (and OS thread) of it's Tx stream - `with` method refuses to use it
+	info kv.TxInfo
+
+	ranges map[string]*parkedRange // see HistoryRange
+}
+
+// parkedRange - iterator of paginated range, open between pages. `k`, `v` - first pair of next page (already read from `it`)
+type parkedRange struct {
+	it      iter.KV
+	k, v    []byte
+	pending bool
+}
+
+// maxParkedRanges - limit of open paginated ranges per tx: client may never read last page
+const maxParkedRanges = 16
+
+// park - must be called under tx lock
+func (tx *threadSafeTx) park(key string, r *parkedRange) {
+	if tx.ranges == nil {
+		tx.ranges = map[string]*parkedRange{}
+	}
+	if old, ok := tx.ranges[key]; ok {
+		iter.Close(old.it)
+	} else if len(tx.ranges) >= maxParkedRanges {
+		for k, old := range tx.ranges { // evict any
+			iter.Close(old.it)
+			delete(tx.ranges, k)
+			break
+		}
+	}
+	tx.ranges[key] = r
+}
+
+// unpark - must be called under tx lock, returns nil if there is no such range
+func (tx *threadSafeTx) unpark(key string) *parkedRange {
+	r, ok := tx.ranges[key]
+	if !ok {
+		return nil
+	}
+	delete(tx.ranges, key)
+	return r
+}
+
+// closeRanges - must be called under tx lock, before Rollback
+func (tx *threadSafeTx) closeRanges() {
+	for k, r := range tx.ranges {
+		iter.Close(r.it)
+		delete(tx.ranges, k)
+	}
+}
+
+func newThreadSafeTx(id uint64, tx kv.Tx, rw bool, peerAddr string) *threadSafeTx {
+	return &threadSafeTx{Tx: tx, rw: rw, info: kv.TxInfo{ID: id, ViewID: tx.ViewID(), ReadOnly: !rw, Created: time.Now(), Peer: peerAddr}}
 }
 
 type Snapsthots interface {
@@ -97,12 +161,50 @@ func NewKvServer(ctx context.Context, db kv.RoDB, snapshots Snapsthots, historyS
 	return &KvServer{
 		trace:     false,
 		rangeStep: 1024,
+		txTTL:     MaxTxTTL,
 		kv:        db, stateChangeStreams: newStateChangeStreams(), ctx: ctx,
 		blockSnapshots: snapshots, historySnapshots: historySnapshots,
 		txs: map[uint64]*threadSafeTx{}, txsMapLock: &sync.RWMutex{},
 	}
 }
 
+// WithWritableTables - enables read-write transactions (Op_BEGIN_RW) in Tx stream. Clients can write only to given tables.
+// Remote read-write tx holds write lock of DB: all other writers (including node itself) will wait for it's Commit/Rollback.
+// Read-write tx can't be renewed: it's rolled back if client doesn't send requests longer than MaxTxTTL.
+// Must be called before serving requests.
+func (s *KvServer) WithWritableTables(tables ...string) *KvServer {
+	s.writableTables = make(map[string]struct{}, len(tables))
+	for _, table := range tables {
+		s.writableTables[table] = struct{}{}
+	}
+	return s
+}
+
+// WithLongTxsWatchdog - log remote transactions older than `threshold`, see kv.WatchLongTxs. Stops when server's ctx is done.
+func (s *KvServer) WithLongTxsWatchdog(threshold time.Duration) *KvServer {
+	go kv.WatchLongTxs(s.ctx, "remote_kv", threshold, s.OpenTxs, log.Root())
+	return s
+}
+
+// OpenTxs - transactions opened by remote clients, oldest first. TxInfo.ID is id of tx in Tx stream.
+func (s *KvServer) OpenTxs() []kv.TxInfo {
+	s.txsMapLock.RLock()
+	res := make([]kv.TxInfo, 0, len(s.txs))
+	for _, tx := range s.txs {
+		res = append(res, tx.info)
+	}
+	s.txsMapLock.RUnlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].Created.Before(res[j].Created) })
+	return res
+}
+
+func peerFromContext(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
 // Version returns the service-side interface version number
 func (s *KvServer) Version(context.Context, *emptypb.Empty) (*types.VersionReply, error) {
 	dbSchemaVersion := &kv.DBSchemaVersion
@@ -132,7 +234,7 @@ func (s *KvServer) begin(ctx context.Context) (id uint64, err error) {
 		return 0, errBegin
 	}
 	id = s.txIdGen.Inc()
-	s.txs[id] = &threadSafeTx{Tx: tx}
+	s.txs[id] = newThreadSafeTx(id, tx, false, peerFromContext(ctx))
 	return id, nil
 }
 
@@ -147,13 +249,69 @@ func (s *KvServer) renew(ctx context.Context, id uint64) (err error) {
 	if ok {
 		tx.Lock()
 		defer tx.Unlock()
+		tx.closeRanges()
 		tx.Rollback()
 	}
 	newTx, errBegin := s.kv.BeginRo(ctx)
 	if errBegin != nil {
 		return err
 	}
-	s.txs[id] = &threadSafeTx{Tx: newTx}
+	renewed := newThreadSafeTx(id, newTx, false, peerFromContext(ctx))
+	if ok { // for client it's same tx: keep age of it, watchdog must see long-living stream
+		renewed.info.Created, renewed.info.Peer = tx.info.Created, tx.info.Peer
+	}
+	s.txs[id] = renewed
+	return nil
+}
+
+// beginRw - rollback read-only tx and begin read-write tx without changing it's `id`
+func (s *KvServer) beginRw(ctx context.Context, id uint64) (kv.RwTx, error) {
+	if s.trace {
+		log.Info(fmt.Sprintf("[kv_server] beginRw %d %s\n", id, dbg.Stack()[:2]))
+	}
+	if len(s.writableTables) == 0 {
+		return nil, fmt.Errorf("read-write transactions are disabled on server")
+	}
+	db, ok := s.kv.(kv.RwDB)
+	if !ok {
+		return nil, fmt.Errorf("server DB doesn't implement kv.RwDB interface")
+	}
+	s.rollback(id)
+	tx, err := db.BeginRw(ctx) // may wait for other writers, so don't hold txsMapLock here
+	if err != nil {
+		return nil, err
+	}
+	s.txsMapLock.Lock()
+	defer s.txsMapLock.Unlock()
+	s.txs[id] = newThreadSafeTx(id, tx, true, peerFromContext(ctx))
+	return tx, nil
+}
+
+// commit - must be called from goroutine which did call `beginRw`
+func (s *KvServer) commit(id uint64) error {
+	if s.trace {
+		log.Info(fmt.Sprintf("[kv_server] commit %d %s\n", id, dbg.Stack()[:2]))
+	}
+	s.txsMapLock.Lock()
+	tx, ok := s.txs[id]
+	if ok && tx.rw {
+		delete(s.txs, id)
+	}
+	s.txsMapLock.Unlock()
+	if !ok || !tx.rw {
+		return fmt.Errorf("txn %d is not read-write", id)
+	}
+	return tx.Tx.(kv.RwTx).Commit()
+}
+
+// checkWritable - server-side allow-list of tables which remote clients can modify
+func (s *KvServer) checkWritable(rwTx kv.RwTx, table string) error {
+	if rwTx == nil {
+		return fmt.Errorf("can't write to %s: txn is read-only, send %s first", table, remote.Op_BEGIN_RW)
+	}
+	if _, ok := s.writableTables[table]; !ok {
+		return fmt.Errorf("can't write to %s: table is not writable for remote clients", table)
+	}
 	return nil
 }
 
@@ -167,6 +325,7 @@ func (s *KvServer) rollback(id uint64) {
 	if ok {
 		tx.Lock()
 		defer tx.Unlock()
+		tx.closeRanges()
 		tx.Rollback()
 		delete(s.txs, id)
 	}
@@ -181,12 +340,20 @@ func (s *KvServer) rollback(id uint64) {
 //	client, portion of data it to client, then read next portion in another `with` call.
 //	It will allow cooperative access to `tx` object
 func (s *KvServer) with(id uint64, f func(kv.Tx) error) error {
+	return s.withThreadSafeTx(id, func(tx *threadSafeTx) error { return f(tx.Tx) })
+}
+
+// withThreadSafeTx - same as `with`, but gives access to server-side state of tx
+func (s *KvServer) withThreadSafeTx(id uint64, f func(*threadSafeTx) error) error {
 	s.txsMapLock.RLock()
 	tx, ok := s.txs[id]
 	s.txsMapLock.RUnlock()
 	if !ok {
 		return fmt.Errorf("txn %d already rollback", id)
 	}
+	if tx.rw {
+		return fmt.Errorf("txn %d is read-write, it can be used only by own Tx stream", id)
+	}
 
 	if s.trace {
 		log.Info(fmt.Sprintf("[kv_server] with %d try lock %s\n", id, dbg.Stack()[:2]))
@@ -201,7 +368,7 @@ func (s *KvServer) with(id uint64, f func(kv.Tx) error) error {
 			log.Info(fmt.Sprintf("[kv_server] with %d unlock %s\n", id, dbg.Stack()[:2]))
 		}
 	}()
-	return f(tx.Tx)
+	return f(tx)
 }
 
 func (s *KvServer) Tx(stream remote.KV_TxServer) error {
@@ -229,24 +396,62 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 		k, v   []byte //fields to save current position of cursor - used when Tx reopen
 	}
 	cursors := map[uint32]*CursorInfo{}
+	var rwTx kv.RwTx // not nil after Op_BEGIN_RW, used only by this stream
 
-	txTicker := time.NewTicker(MaxTxTTL)
+	txTicker := time.NewTicker(s.txTTL)
 	defer txTicker.Stop()
+	var rwIdle *time.Timer // started by Op_BEGIN_RW, reset by every request: client which doesn't send any requests must not hold write lock of DB
+	var rwIdleC <-chan time.Time
 
-	// send all items to client, if k==nil - still send it to client and break loop
-	for {
-		in, recvErr := stream.Recv()
-		if recvErr != nil {
-			if errors.Is(recvErr, io.EOF) { // termination
-				return nil
+	// Recv in separate goroutine: TTL of txn must work even if client doesn't send any requests.
+	// Read-write txn is bound to goroutine of this stream, so it's rolled back here (by defer).
+	type recvResult struct {
+		in  *remote.Cursor
+		err error
+	}
+	recvCh, done := make(chan recvResult), make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			in, err := stream.Recv()
+			select {
+			case recvCh <- recvResult{in: in, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
 			}
-			return fmt.Errorf("server-side error: %w", recvErr)
 		}
+	}()
 
-		//TODO: protect against client - which doesn't send any requests
+	// send all items to client, if k==nil - still send it to client and break loop
+	for {
+		var in *remote.Cursor
 		select {
-		default:
+		case r := <-recvCh:
+			if r.err != nil {
+				if errors.Is(r.err, io.EOF) { // termination
+					return nil
+				}
+				return fmt.Errorf("server-side error: %w", r.err)
+			}
+			in = r.in
+			if rwIdle != nil {
+				if !rwIdle.Stop() {
+					select { // fired, but not received yet
+					case <-rwIdle.C:
+					default:
+					}
+				}
+				rwIdle.Reset(s.txTTL)
+			}
+		case <-rwIdleC: // can't renew read-write tx without losing changes
+			return fmt.Errorf("server-side error: read-write txn %d is idle longer than %s, rolled back", id, s.txTTL)
 		case <-txTicker.C:
+			if rwTx != nil {
+				continue
+			}
 			for _, c := range cursors { // save positions of cursor, will restore after Tx reopening
 				k, v, err := c.c.Current()
 				if err != nil {
@@ -288,27 +493,68 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 			}); err != nil {
 				return err
 			}
+			continue
+		}
+
+		switch in.Op {
+		case remote.Op_BEGIN_RW:
+			if rwTx != nil || len(cursors) > 0 {
+				return fmt.Errorf("server-side error: %s must be first operation of txn", in.Op)
+			}
+			var err error
+			if rwTx, err = s.beginRw(stream.Context(), id); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			rwIdle = time.NewTimer(s.txTTL)
+			defer rwIdle.Stop()
+			rwIdleC = rwIdle.C
+			if err := stream.Send(&remote.Pair{ViewID: rwTx.ViewID(), TxID: id}); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			continue
+		case remote.Op_COMMIT:
+			if rwTx == nil {
+				return fmt.Errorf("server-side error: can't commit read-only txn %d", id)
+			}
+			for _, c := range cursors {
+				c.c.Close()
+			}
+			if err := s.commit(id); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			if err := stream.Send(&remote.Pair{}); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			return nil
+		case remote.Op_READ_SEQUENCE, remote.Op_INCREMENT_SEQUENCE:
+			if err := s.handleSequenceOp(id, rwTx, stream, in); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			continue
 		}
 
 		var c kv.Cursor
+		var bucket string
 		if in.BucketName == "" {
 			cInfo, ok := cursors[in.Cursor]
 			if !ok {
 				return fmt.Errorf("server-side error: unknown Cursor=%d, Op=%s", in.Cursor, in.Op)
 			}
-			c = cInfo.c
+			c, bucket = cInfo.c, cInfo.bucket
 		}
 		switch in.Op {
 		case remote.Op_OPEN:
 			CursorID++
 			var err error
-			if err := s.with(id, func(tx kv.Tx) error {
-				c, err = tx.Cursor(in.BucketName)
-				if err != nil {
+			if rwTx != nil {
+				c, err = rwTx.RwCursor(in.BucketName)
+			} else {
+				err = s.with(id, func(tx kv.Tx) error {
+					c, err = tx.Cursor(in.BucketName)
 					return err
-				}
-				return nil
-			}); err != nil {
+				})
+			}
+			if err != nil {
 				return err
 			}
 			cursors[CursorID] = &CursorInfo{
@@ -322,13 +568,15 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 		case remote.Op_OPEN_DUP_SORT:
 			CursorID++
 			var err error
-			if err := s.with(id, func(tx kv.Tx) error {
-				c, err = tx.CursorDupSort(in.BucketName)
-				if err != nil {
+			if rwTx != nil {
+				c, err = rwTx.RwCursorDupSort(in.BucketName)
+			} else {
+				err = s.with(id, func(tx kv.Tx) error {
+					c, err = tx.CursorDupSort(in.BucketName)
 					return err
-				}
-				return nil
-			}); err != nil {
+				})
+			}
+			if err != nil {
 				return err
 			}
 			cursors[CursorID] = &CursorInfo{
@@ -350,6 +598,15 @@ func (s *KvServer) Tx(stream remote.KV_TxServer) error {
 				return fmt.Errorf("server-side error: %w", err)
 			}
 			continue
+		case remote.Op_PUT, remote.Op_APPEND, remote.Op_DELETE, remote.Op_DELETE_CURRENT,
+			remote.Op_PUT_NO_DUP_DATA, remote.Op_APPEND_DUP, remote.Op_DELETE_EXACT, remote.Op_DELETE_CURRENT_DUPLICATES:
+			if err := s.checkWritable(rwTx, bucket); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			if err := handleWriteOp(c, stream, in); err != nil {
+				return fmt.Errorf("server-side error: %w", err)
+			}
+			continue
 		default:
 		}
 
@@ -419,6 +676,75 @@ func handleOp(c kv.Cursor, stream remote.KV_TxServer, in *remote.Cursor) error {
 	return nil
 }
 
+func handleWriteOp(c kv.Cursor, stream remote.KV_TxServer, in *remote.Cursor) error {
+	rwC, ok := c.(kv.RwCursor)
+	if !ok {
+		return fmt.Errorf("%s: cursor %T doesn't support write operations", in.Op, c)
+	}
+	var err error
+	switch in.Op {
+	case remote.Op_PUT:
+		err = rwC.Put(in.K, in.V)
+	case remote.Op_APPEND:
+		err = rwC.Append(in.K, in.V)
+	case remote.Op_DELETE:
+		err = rwC.Delete(in.K)
+	case remote.Op_DELETE_CURRENT:
+		err = rwC.DeleteCurrent()
+	default:
+		rwDupC, ok := c.(kv.RwCursorDupSort)
+		if !ok {
+			return fmt.Errorf("%s: cursor %T is not dupsort", in.Op, c)
+		}
+		switch in.Op {
+		case remote.Op_PUT_NO_DUP_DATA:
+			err = rwDupC.PutNoDupData(in.K, in.V)
+		case remote.Op_APPEND_DUP:
+			err = rwDupC.AppendDup(in.K, in.V)
+		case remote.Op_DELETE_EXACT:
+			err = rwDupC.DeleteExact(in.K, in.V)
+		case remote.Op_DELETE_CURRENT_DUPLICATES:
+			err = rwDupC.DeleteCurrentDuplicates()
+		default:
+			return fmt.Errorf("unknown operation: %s", in.Op)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return stream.Send(&remote.Pair{})
+}
+
+func (s *KvServer) handleSequenceOp(id uint64, rwTx kv.RwTx, stream remote.KV_TxServer, in *remote.Cursor) error {
+	var v uint64
+	var err error
+	switch in.Op {
+	case remote.Op_READ_SEQUENCE:
+		if rwTx != nil {
+			v, err = rwTx.ReadSequence(in.BucketName)
+		} else {
+			err = s.with(id, func(tx kv.Tx) error {
+				v, err = tx.ReadSequence(in.BucketName)
+				return err
+			})
+		}
+	case remote.Op_INCREMENT_SEQUENCE:
+		if err = s.checkWritable(rwTx, in.BucketName); err != nil {
+			return err
+		}
+		if len(in.V) != 8 {
+			return fmt.Errorf("%s: expected 8 bytes amount, got %d", in.Op, len(in.V))
+		}
+		v, err = rwTx.IncrementSequence(in.BucketName, binary.BigEndian.Uint64(in.V))
+	default:
+		return fmt.Errorf("unknown operation: %s", in.Op)
+	}
+	if err != nil {
+		return err
+	}
+	return stream.Send(&remote.Pair{V: hexutility.EncodeTs(v)})
+}
+
 func bytesCopy(b []byte) []byte {
 	if b == nil {
 		return nil
@@ -598,24 +924,24 @@ func (s *KvServer) IndexRange(ctx context.Context, req *remote.IndexRangeReq) (*
 		if err != nil {
 			return err
 		}
+		// client may go away (RPC timeout) in the middle of long scan
+		it = iter.WithContextU64(ctx, it)
+		defer iter.Close(it)
 		for it.HasNext() {
 			v, err := it.Next()
 			if err != nil {
 				return err
 			}
+			if len(reply.Timestamps) == int(req.PageSize) {
+				reply.NextPageToken, err = marshalPagination(&remote.IndexPagination{NextTimeStamp: int64(v), Limit: int64(limit)})
+				if err != nil {
+					return err
+				}
+				break
+			}
 			reply.Timestamps = append(reply.Timestamps, v)
 			limit--
 		}
-		if len(reply.Timestamps) == PageSizeLimit && it.HasNext() {
-			next, err := it.Next()
-			if err != nil {
-				return err
-			}
-			reply.NextPageToken, err = marshalPagination(&remote.IndexPagination{NextTimeStamp: int64(next), Limit: int64(limit)})
-			if err != nil {
-				return err
-			}
-		}
 		return nil
 	}); err != nil {
 		return nil, err
@@ -763,6 +1089,167 @@ func (s *KvServer) Range(ctx context.Context, req *remote.RangeReq) (*remote.Pai
 	return reply, nil
 }
 
+// HistoryRange - temporal history iterators can't seek to arbitrary key, so iterator of range stays open in server-side
+// tx between pages (see parkedRange) and next page continues it. Page is also capped by `s.rangeStep` to make sure
+// `s.with` has limited time. Page token expires when tx is renewed or rolled back.
+func (s *KvServer) HistoryRange(ctx context.Context, req *remote.HistoryRangeReq) (*remote.Pairs, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = -1
+	}
+	if req.PageToken != "" {
+		var pagination remote.ParisPagination
+		if err := unmarshalPagination(req.PageToken, &pagination); err != nil {
+			return nil, err
+		}
+		limit = int(pagination.Limit)
+	}
+	rangeKey := func(pageToken string) string {
+		return fmt.Sprintf("%s/%d/%d/%t/%s", req.Table, req.FromTs, req.ToTs, req.OrderAscend, pageToken)
+	}
+	var reply *remote.Pairs
+	if err := s.withThreadSafeTx(req.TxId, func(tx *threadSafeTx) error {
+		var r *parkedRange
+		if req.PageToken != "" {
+			if r = tx.unpark(rangeKey(req.PageToken)); r == nil {
+				return fmt.Errorf("HistoryRange: page token expired (txn was renewed or has too many open ranges), restart range")
+			}
+		} else {
+			ttx, ok := tx.Tx.(kv.TemporalTx)
+			if !ok {
+				return fmt.Errorf("server DB doesn't implement kv.Temporal interface")
+			}
+			it, err := ttx.HistoryRange(kv.History(req.Table), int(req.FromTs), int(req.ToTs), order.By(req.OrderAscend), -1)
+			if err != nil {
+				return err
+			}
+			r = &parkedRange{it: it}
+		}
+		var err error
+		if reply, err = s.pairsPage(ctx, r, limit, int(req.PageSize)); err != nil {
+			iter.Close(r.it)
+			return err
+		}
+		if reply.NextPageToken == "" {
+			iter.Close(r.it)
+			return nil
+		}
+		tx.park(rangeKey(reply.NextPageToken), r)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// DomainRange - next page is new range started from `NextKey`.
+func (s *KvServer) DomainRange(ctx context.Context, req *remote.DomainRangeReq) (*remote.Pairs, error) {
+	from, limit := req.FromKey, int(req.Limit)
+	if limit <= 0 {
+		limit = -1
+	}
+	if req.PageToken != "" {
+		var pagination remote.ParisPagination
+		if err := unmarshalPagination(req.PageToken, &pagination); err != nil {
+			return nil, err
+		}
+		from, limit = domainRangeSeekKey(kv.Domain(req.Table), req.FromKey, pagination.NextKey), int(pagination.Limit)
+	}
+	var reply *remote.Pairs
+	if err := s.with(req.TxId, func(tx kv.Tx) error {
+		ttx, ok := tx.(kv.TemporalTx)
+		if !ok {
+			return fmt.Errorf("server DB doesn't implement kv.Temporal interface")
+		}
+		it, err := ttx.DomainRange(kv.Domain(req.Table), from, req.ToKey, req.Ts, order.By(req.OrderAscend), -1)
+		if err != nil {
+			return err
+		}
+		defer iter.Close(it)
+		reply, err = s.pairsPage(ctx, &parkedRange{it: it}, limit, int(req.PageSize))
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// storageDomain - same as temporal.StorageDomain (server doesn't depend on temporal package)
+const storageDomain kv.Domain = "StorageDomain"
+
+// domainRangeSeekKey - DomainRange takes `fromKey` of StorageDomain in PlainState format (address+incarnation+location),
+// but returns keys as address+location. Incarnation is taken from `fromKey` of request: range belongs to one account.
+func domainRangeSeekKey(domain kv.Domain, fromKey, nextKey []byte) []byte {
+	if domain != storageDomain || len(fromKey) < length.Addr+length.Incarnation || len(nextKey) < length.Addr {
+		return nextKey
+	}
+	k := make([]byte, 0, len(nextKey)+length.Incarnation)
+	k = append(k, nextKey[:length.Addr]...)
+	k = append(k, fromKey[length.Addr:length.Addr+length.Incarnation]...)
+	return append(k, nextKey[length.Addr:]...)
+}
+
+func (s *KvServer) TableStats(ctx context.Context, req *remote.TableStatsReq) (*remote.TableStatsReply, error) {
+	var st kv.TableStat
+	if err := s.with(req.TxId, func(tx kv.Tx) (err error) {
+		st, err = kv.TableStats(tx, req.Table)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return &remote.TableStatsReply{Entries: st.Entries, Depth: st.Depth, BranchPages: st.BranchPages, LeafPages: st.LeafPages, OverflowPages: st.OverflowPages, PageSize: st.PageSize}, nil
+}
+
+func (s *KvServer) DBSize(ctx context.Context, req *remote.DBSizeReq) (*remote.DBSizeReply, error) {
+	var size uint64
+	if err := s.with(req.TxId, func(tx kv.Tx) (err error) {
+		size, err = tx.DBSize()
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return &remote.DBSizeReply{Size: size}, nil
+}
+
+// pairsPage - reads one page from `r`: stops after `limit` (-1 means no limit) or `pageSize` items.
+// If range still has items - reply has NextPageToken and `r` holds first pair of next page.
+func (s *KvServer) pairsPage(ctx context.Context, r *parkedRange, limit, pageSize int) (*remote.Pairs, error) {
+	if pageSize <= 0 || pageSize > s.rangeStep {
+		pageSize = s.rangeStep
+	}
+	reply := &remote.Pairs{}
+	for limit != 0 {
+		// client may go away (RPC timeout) in the middle of long scan
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !r.pending {
+			if !r.it.HasNext() {
+				break
+			}
+			k, v, err := r.it.Next()
+			if err != nil {
+				return nil, err
+			}
+			r.k, r.v, r.pending = k, v, true
+		}
+		if len(reply.Keys) == pageSize {
+			r.k, r.v = common.Copy(r.k), common.Copy(r.v) // `r` outlives current position of iterator
+			var err error
+			reply.NextPageToken, err = marshalPagination(&remote.ParisPagination{NextKey: r.k, Limit: int64(limit)})
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		reply.Keys = append(reply.Keys, common.Copy(r.k))
+		reply.Values = append(reply.Values, common.Copy(r.v))
+		r.k, r.v, r.pending = nil, nil, false
+		limit--
+	}
+	return reply, nil
+}
+
 // see: https://cloud.google.com/apis/design/design_patterns
 func marshalPagination(m proto.Message) (string, error) {
 	pageToken, err := proto.Marshal(m)
@@ -18,13 +18,22 @@ package remotedbserver
 
 import (
 	"context"
+	"net"
 	"runtime"
 	"testing"
+	"time"
 
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/temporal"
+	"github.com/ledgerwatch/erigon-lib/state"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
 )
 
 func TestKvServer_renew(t *testing.T) {
@@ -94,3 +103,189 @@ func TestKvServer_renew(t *testing.T) {
 	}
 	require.NoError(g.Wait())
 }
+
+func TestKvServer_HistoryRangeDomainRange(t *testing.T) {
+	require, ctx, dir := require.New(t), context.Background(), t.TempDir()
+	rawDB := memdb.NewTestDB(t)
+	agg, err := state.NewAggregatorV3(ctx, dir, dir, 16, rawDB)
+	require.NoError(err)
+	defer agg.Close()
+	db := temporal.New(rawDB, agg)
+
+	var addrs, locs [][]byte
+	storagePrefix := make([]byte, length.Addr+length.Incarnation) // address+incarnation of first account
+	storagePrefix[0], storagePrefix[length.Addr+length.Incarnation-1] = 1, 1
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		agg.SetTx(tx)
+		agg.StartWrites()
+		defer agg.FinishWrites()
+		agg.SetTxNum(5)
+		for i := byte(1); i <= 3; i++ {
+			addr := make([]byte, length.Addr)
+			addr[0] = i
+			addrs = append(addrs, addr)
+			if err := agg.AddAccountPrev(addr, []byte{i}); err != nil {
+				return err
+			}
+			if err := tx.Put(kv.PlainState, addr, []byte{10 + i}); err != nil {
+				return err
+			}
+			loc := make([]byte, length.Hash)
+			loc[0] = i
+			locs = append(locs, loc)
+			if err := tx.Put(kv.PlainState, append(common.Copy(storagePrefix), loc...), []byte{20 + i}); err != nil {
+				return err
+			}
+		}
+		return agg.Flush(ctx, tx)
+	}))
+
+	s := NewKvServer(ctx, db, nil, nil)
+	s.rangeStep = 1 // 1 item per page
+	id, err := s.begin(ctx)
+	require.NoError(err)
+	defer s.rollback(id)
+
+	readAll := func(page func(pageToken string) (*remote.Pairs, error)) (keys, vals [][]byte, pages int) {
+		var pageToken string
+		for {
+			reply, err := page(pageToken)
+			require.NoError(err)
+			keys, vals, pages = append(keys, reply.Keys...), append(vals, reply.Values...), pages+1
+			if reply.NextPageToken == "" {
+				return keys, vals, pages
+			}
+			pageToken = reply.NextPageToken
+		}
+	}
+
+	t.Run("history range", func(t *testing.T) {
+		keys, vals, pages := readAll(func(pageToken string) (*remote.Pairs, error) {
+			return s.HistoryRange(ctx, &remote.HistoryRangeReq{TxId: id, Table: string(temporal.AccountsHistory), FromTs: 0, ToTs: 10, OrderAscend: true, Limit: -1, PageToken: pageToken})
+		})
+		require.Equal(addrs, keys)
+		require.Equal([][]byte{{1}, {2}, {3}}, vals)
+		require.Equal(3, pages)
+
+		keys, _, _ = readAll(func(pageToken string) (*remote.Pairs, error) {
+			return s.HistoryRange(ctx, &remote.HistoryRangeReq{TxId: id, Table: string(temporal.AccountsHistory), FromTs: 0, ToTs: 10, OrderAscend: true, Limit: 2, PageToken: pageToken})
+		})
+		require.Equal(addrs[:2], keys)
+
+		keys, _, _ = readAll(func(pageToken string) (*remote.Pairs, error) { // limit 0 means no limit
+			return s.HistoryRange(ctx, &remote.HistoryRangeReq{TxId: id, Table: string(temporal.AccountsHistory), FromTs: 0, ToTs: 10, OrderAscend: true, PageToken: pageToken})
+		})
+		require.Equal(addrs, keys)
+	})
+	t.Run("history range: page token expires on renew", func(t *testing.T) {
+		req := &remote.HistoryRangeReq{TxId: id, Table: string(temporal.AccountsHistory), FromTs: 0, ToTs: 10, OrderAscend: true, Limit: -1}
+		reply, err := s.HistoryRange(ctx, req)
+		require.NoError(err)
+		require.NotEmpty(reply.NextPageToken)
+		require.NoError(s.renew(ctx, id))
+		req.PageToken = reply.NextPageToken
+		_, err = s.HistoryRange(ctx, req)
+		require.Error(err)
+	})
+	t.Run("domain range", func(t *testing.T) {
+		keys, vals, _ := readAll(func(pageToken string) (*remote.Pairs, error) {
+			return s.DomainRange(ctx, &remote.DomainRangeReq{TxId: id, Table: string(temporal.AccountsDomain), Ts: 3, OrderAscend: true, Limit: -1, PageToken: pageToken})
+		})
+		require.Equal(addrs, keys)
+		require.Equal([][]byte{{1}, {2}, {3}}, vals)
+
+		keys, vals, _ = readAll(func(pageToken string) (*remote.Pairs, error) {
+			return s.DomainRange(ctx, &remote.DomainRangeReq{TxId: id, Table: string(temporal.AccountsDomain), FromKey: addrs[1], Ts: 6, OrderAscend: true, Limit: -1, PageToken: pageToken})
+		})
+		require.Equal(addrs[1:], keys)
+		require.Equal([][]byte{{12}, {13}}, vals)
+	})
+	t.Run("storage domain range", func(t *testing.T) {
+		keys, vals, pages := readAll(func(pageToken string) (*remote.Pairs, error) {
+			return s.DomainRange(ctx, &remote.DomainRangeReq{TxId: id, Table: string(temporal.StorageDomain), FromKey: storagePrefix, Ts: 6, OrderAscend: true, PageToken: pageToken})
+		})
+		require.Equal(3, pages)
+		require.Equal(3, len(keys))
+		for i := range keys {
+			require.Equal(append(common.Copy(addrs[0]), locs[i]...), keys[i]) // address+location
+		}
+		require.Equal([][]byte{{21}, {22}, {23}}, vals)
+	})
+}
+
+func TestKvServer_OpenTxs(t *testing.T) {
+	require, ctx, db := require.New(t), context.Background(), memdb.NewTestDB(t)
+	s := NewKvServer(ctx, db, nil, nil)
+
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9090}
+	id, err := s.begin(peer.NewContext(ctx, &peer.Peer{Addr: addr}))
+	require.NoError(err)
+	txs := s.OpenTxs()
+	require.Len(txs, 1)
+	require.Equal(id, txs[0].ID)
+	require.Equal(addr.String(), txs[0].Peer)
+	require.True(txs[0].ReadOnly)
+
+	// renew (by TTL ticker) must not reset age and peer of tx
+	require.NoError(s.renew(ctx, id))
+	renewed := s.OpenTxs()
+	require.Len(renewed, 1)
+	require.Equal(txs[0].Created, renewed[0].Created)
+	require.Equal(addr.String(), renewed[0].Peer)
+
+	s.rollback(id)
+	require.Empty(s.OpenTxs())
+}
+
+// silentTxStream - client which sends only ops from `in`
+type silentTxStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	in   chan *remote.Cursor
+	sent chan *remote.Pair
+}
+
+func (s *silentTxStream) Context() context.Context      { return s.ctx }
+func (s *silentTxStream) Send(reply *remote.Pair) error { s.sent <- reply; return nil }
+func (s *silentTxStream) Recv() (*remote.Cursor, error) {
+	select {
+	case in := <-s.in:
+		return in, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func TestKvServer_RwTxTTL(t *testing.T) {
+	require, db := require.New(t), memdb.NewTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewKvServer(ctx, db, nil, nil).WithWritableTables(kv.HeaderNumber)
+	s.txTTL = 100 * time.Millisecond
+
+	stream := &silentTxStream{ctx: ctx, in: make(chan *remote.Cursor, 1), sent: make(chan *remote.Pair, 2)}
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Tx(stream) }()
+	<-stream.sent // txID of read-only tx
+	stream.in <- &remote.Cursor{Op: remote.Op_BEGIN_RW}
+	<-stream.sent // txID of read-write tx
+
+	// busy client keeps txn longer than TTL
+	for i := 0; i < 5; i++ {
+		time.Sleep(s.txTTL / 2)
+		stream.in <- &remote.Cursor{Op: remote.Op_READ_SEQUENCE, BucketName: kv.HeaderNumber}
+		<-stream.sent
+	}
+	require.Len(s.OpenTxs(), 1)
+
+	// client doesn't send anything
+	select {
+	case err := <-errCh:
+		require.ErrorContains(err, "idle longer than")
+	case <-time.After(10 * time.Second):
+		t.Fatal("read-write txn was not rolled back")
+	}
+	require.Empty(s.OpenTxs())
+	// write lock of DB is released
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error { return tx.Put(kv.HeaderNumber, []byte{1}, []byte{1}) }))
+}
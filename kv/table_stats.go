@@ -0,0 +1,128 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package kv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+)
+
+// TableStat - space accounting of 1 table. Page counts are B-tree pages of the table.
+type TableStat struct {
+	Table         string
+	Entries       uint64 // amount of key-value pairs. for DupSort tables: all values of all keys
+	Depth         uint64 // depth of B-tree
+	BranchPages   uint64
+	LeafPages     uint64
+	OverflowPages uint64 // pages of values which don't fit into leaf page
+	PageSize      uint64
+}
+
+func (s TableStat) Size() uint64 { return (s.BranchPages + s.LeafPages + s.OverflowPages) * s.PageSize }
+
+// TableStatsReader - implemented by transactions which can report space usage of table
+type TableStatsReader interface {
+	TableStats(table string) (TableStat, error)
+}
+
+// TableStats - cheap: doesn't read table's data, only B-tree metadata
+func TableStats(tx Tx, table string) (TableStat, error) {
+	casted, ok := tx.(TableStatsReader)
+	if !ok {
+		return TableStat{}, fmt.Errorf("%w: TableStats of %T", ErrNotSupported, tx)
+	}
+	return casted.TableStats(table)
+}
+
+// DupSortFanOut - amount of unique keys and max amount of values per key of DupSort table.
+// Entries/keys is average fan-out. Expensive: walks over all keys of table.
+func DupSortFanOut(tx Tx, table string) (keys, maxDups uint64, err error) {
+	c, err := tx.CursorDupSort(table)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer c.Close()
+	for k, _, err := c.First(); k != nil; k, _, err = c.NextNoDup() {
+		if err != nil {
+			return 0, 0, err
+		}
+		dups, err := c.CountDuplicates()
+		if errors.Is(err, ErrNotSupported) { // remote cursors can't count - walk over values of key
+			dups, err = countDupsByWalk(c)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		keys++
+		if dups > maxDups {
+			maxDups = dups
+		}
+	}
+	return keys, maxDups, nil
+}
+
+// countDupsByWalk - amount of values of current key, leaves cursor on last value of key
+func countDupsByWalk(c CursorDupSort) (dups uint64, err error) {
+	for dups = 1; ; dups++ {
+		k, _, err := c.NextDup()
+		if err != nil {
+			return 0, err
+		}
+		if k == nil {
+			return dups, nil
+		}
+	}
+}
+
+// ChaindataTablesStats - stats of all existing ChaindataTables, sorted by size (biggest first)
+func ChaindataTablesStats(tx Tx) ([]TableStat, error) {
+	res := make([]TableStat, 0, len(ChaindataTables))
+	for _, table := range ChaindataTables {
+		if ChaindataTablesCfg[table].IsDeprecated {
+			continue
+		}
+		st, err := TableStats(tx, table)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", table, err)
+		}
+		res = append(res, st)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Size() > res[j].Size() })
+	return res, nil
+}
+
+// PrintTablesSizeReport - human-readable report of ChaindataTablesStats
+func PrintTablesSizeReport(tx Tx, w io.Writer) error {
+	stats, err := ChaindataTablesStats(tx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "table\tsize\tentries\tdepth\tbranch\tleaf\toverflow\t\n")
+	var total uint64
+	for _, st := range stats {
+		total += st.Size()
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t\n", st.Table, common.ByteCount(st.Size()), st.Entries, st.Depth, st.BranchPages, st.LeafPages, st.OverflowPages)
+	}
+	fmt.Fprintf(tw, "total\t%s\t\t\t\t\t\t\n", common.ByteCount(total))
+	return tw.Flush()
+}
@@ -0,0 +1,341 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package temporal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/iter"
+	"github.com/ledgerwatch/erigon-lib/kv/order"
+	"github.com/ledgerwatch/erigon-lib/state"
+)
+
+//Abstraction Layers:
+// LowLevel:
+//      1. DB/Tx - low-level key-value database (MDBX). Stores Hot/Recent data and latest state (PlainState, PlainCodeHash, Code)
+//      2. Snapshots/Freeze - immutable files with historical data. Managed by state.AggregatorV3
+//
+// MediumLevel:
+//      1. TemporalDB - abstracting DB+Snapshots. Target is:
+//              - provide 'time-travel' API for data: consistent snapshot of data as of given Timestamp.
+//              - to keep DB small - only for Hot/Recent data (can be update/delete by re-org).
+//              - using next entities (see kv_interface.go):
+//                      - InvertedIndex: supports range-scans
+//                      - History: can return value of key K as of given TimeStamp. Doesn't know about latest/current
+//                          value of key K. Returns NIL if K not changed after TimeStamp.
+//                      - Domain: as History but also aware about latest/current value of key K.
+//
+// HighLevel:
+//      1. Application - rely on TemporalDB (Ex: ExecutionLayer) or just DB (Ex: TxPool, Sentry, Downloader).
+
+const (
+	AccountsDomain kv.Domain = "AccountsDomain"
+	StorageDomain  kv.Domain = "StorageDomain"
+	CodeDomain     kv.Domain = "CodeDomain"
+)
+
+const (
+	AccountsHistory kv.History = "AccountsHistory"
+	StorageHistory  kv.History = "StorageHistory"
+	CodeHistory     kv.History = "CodeHistory"
+)
+
+const (
+	AccountsHistoryIdx kv.InvertedIdx = "AccountsHistoryIdx"
+	StorageHistoryIdx  kv.InvertedIdx = "StorageHistoryIdx"
+	CodeHistoryIdx     kv.InvertedIdx = "CodeHistoryIdx"
+
+	LogTopicIdx   kv.InvertedIdx = "LogTopicIdx"
+	LogAddrIdx    kv.InvertedIdx = "LogAddrIdx"
+	TracesFromIdx kv.InvertedIdx = "TracesFromIdx"
+	TracesToIdx   kv.InvertedIdx = "TracesToIdx"
+)
+
+// DB - implements kv.TemporalRwDB on top of kv.RwDB (usually mdbx.MdbxKV) and state.AggregatorV3
+//
+//   - latest state is read from kv.PlainState, kv.PlainContractCode and kv.Code tables of underlying DB
+//   - history and inverted indices are read from AggregatorV3 (recent part from DB, frozen part from files)
+//
+// Values are returned as they are stored: latest values in PlainState encoding,
+// historical values in encoding of AggregatorV3 - converting them is responsibility of caller.
+type DB struct {
+	kv.RwDB
+	agg *state.AggregatorV3
+}
+
+var _ kv.TemporalRwDB = (*DB)(nil) // compile-time interface check
+var _ kv.TemporalTx = (*Tx)(nil)   // compile-time interface check
+
+func New(db kv.RwDB, agg *state.AggregatorV3) *DB {
+	return &DB{RwDB: db, agg: agg}
+}
+
+func (db *DB) Agg() *state.AggregatorV3 { return db.agg }
+
+func (db *DB) BeginTemporalRo(ctx context.Context) (kv.TemporalTx, error) {
+	kvTx, err := db.RwDB.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: kvTx, db: db, agg: db.agg.MakeContext()}, nil
+}
+func (db *DB) ViewTemporal(ctx context.Context, f func(tx kv.TemporalTx) error) error {
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+// BeginRo - returns kv.TemporalTx. It allows pass DB to code which knows only kv.RoDB (for example remotedbserver.KvServer),
+// and use type-assertion to kv.TemporalTx there.
+func (db *DB) BeginRo(ctx context.Context) (kv.Tx, error) {
+	return db.BeginTemporalRo(ctx)
+}
+func (db *DB) View(ctx context.Context, f func(tx kv.Tx) error) error {
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+type Tx struct {
+	kv.Tx
+	db               *DB
+	agg              *state.AggregatorV3Context
+	resourcesToClose []kv.Closer
+}
+
+func (tx *Tx) AggCtx() *state.AggregatorV3Context { return tx.agg }
+func (tx *Tx) Agg() *state.AggregatorV3           { return tx.db.agg }
+
+// TableStats - kv.Tx is embedded as interface: forward optional kv.TableStatsReader explicitly
+func (tx *Tx) TableStats(table string) (kv.TableStat, error) { return kv.TableStats(tx.Tx, table) }
+
+func (tx *Tx) Rollback() {
+	tx.closeResources()
+	tx.Tx.Rollback()
+}
+func (tx *Tx) Commit() error {
+	tx.closeResources()
+	return tx.Tx.Commit()
+}
+func (tx *Tx) closeResources() {
+	for _, closer := range tx.resourcesToClose {
+		closer.Close()
+	}
+	tx.resourcesToClose = nil
+	if tx.agg != nil {
+		tx.agg.Close()
+		tx.agg = nil
+	}
+}
+func (tx *Tx) autoClose(it any) {
+	if closer, ok := it.(kv.Closer); ok {
+		tx.resourcesToClose = append(tx.resourcesToClose, closer)
+	}
+}
+
+// DomainGet - returns value of key as of given `ts`. Returns ok=false if key doesn't exist.
+//
+//	AccountsDomain: k - address
+//	StorageDomain:  k - address+incarnation, k2 - storage location
+//	CodeDomain:     k - address+incarnation
+func (tx *Tx) DomainGet(name kv.Domain, k, k2 []byte, ts uint64) (v []byte, ok bool, err error) {
+	switch name {
+	case AccountsDomain:
+		v, ok, err = tx.HistoryGet(AccountsHistory, k, ts)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return v, len(v) > 0, nil
+		}
+		v, err = tx.GetOne(kv.PlainState, k)
+		return v, v != nil, err
+	case StorageDomain:
+		if len(k) != length.Addr+length.Incarnation {
+			return nil, false, fmt.Errorf("%s: expected key len %d, got %d", name, length.Addr+length.Incarnation, len(k))
+		}
+		v, ok, err = tx.HistoryGet(StorageHistory, append(common.Copy(k[:length.Addr]), k2...), ts)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return v, len(v) > 0, nil
+		}
+		v, err = tx.GetOne(kv.PlainState, append(common.Copy(k), k2...))
+		return v, v != nil, err
+	case CodeDomain:
+		if len(k) != length.Addr+length.Incarnation {
+			return nil, false, fmt.Errorf("%s: expected key len %d, got %d", name, length.Addr+length.Incarnation, len(k))
+		}
+		v, ok, err = tx.HistoryGet(CodeHistory, k[:length.Addr], ts)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return v, len(v) > 0, nil
+		}
+		codeHash, err := tx.GetOne(kv.PlainContractCode, k)
+		if err != nil {
+			return nil, false, err
+		}
+		if codeHash == nil {
+			return nil, false, nil
+		}
+		v, err = tx.GetOne(kv.Code, codeHash)
+		return v, v != nil, err
+	default:
+		return nil, false, fmt.Errorf("unexpected domain name: %s", name)
+	}
+}
+
+// HistoryGet - returns value of key before first change after `ts`. Returns ok=false if key was not changed after `ts`.
+func (tx *Tx) HistoryGet(name kv.History, k []byte, ts uint64) (v []byte, ok bool, err error) {
+	switch name {
+	case AccountsHistory:
+		return tx.agg.ReadAccountDataNoStateWithRecent(k, ts, tx.Tx)
+	case StorageHistory:
+		return tx.agg.ReadAccountStorageNoStateWithRecent2(k, ts, tx.Tx)
+	case CodeHistory:
+		return tx.agg.ReadAccountCodeNoStateWithRecent(k, ts, tx.Tx)
+	default:
+		return nil, false, fmt.Errorf("unexpected history name: %s", name)
+	}
+}
+
+func (tx *Tx) IndexRange(name kv.InvertedIdx, k []byte, fromTs, toTs int, asc order.By, limit int) (timestamps iter.U64, err error) {
+	switch name {
+	case AccountsHistoryIdx:
+		timestamps, err = tx.agg.AccountHistoyIdxIterator(k, fromTs, toTs, asc, limit, tx)
+	case StorageHistoryIdx:
+		timestamps, err = tx.agg.StorageHistoyIdxIterator(k, fromTs, toTs, asc, limit, tx)
+	case CodeHistoryIdx:
+		timestamps, err = tx.agg.CodeHistoyIdxIterator(k, fromTs, toTs, asc, limit, tx)
+	case LogTopicIdx:
+		timestamps, err = tx.agg.LogTopicIterator(k, fromTs, toTs, asc, limit, tx)
+	case LogAddrIdx:
+		timestamps, err = tx.agg.LogAddrIterator(k, fromTs, toTs, asc, limit, tx)
+	case TracesFromIdx:
+		timestamps, err = tx.agg.TraceFromIterator(k, fromTs, toTs, asc, limit, tx)
+	case TracesToIdx:
+		timestamps, err = tx.agg.TraceToIterator(k, fromTs, toTs, asc, limit, tx)
+	default:
+		return nil, fmt.Errorf("unexpected inverted index name: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tx.autoClose(timestamps)
+	return timestamps, nil
+}
+
+// HistoryRange - returns keys changed in [fromTs, toTs) and their values before the first change in this range.
+// Only order.Asc and unlimited (-1) ranges are supported for now.
+func (tx *Tx) HistoryRange(name kv.History, fromTs, toTs int, asc order.By, limit int) (it iter.KV, err error) {
+	if asc == order.Desc {
+		return nil, fmt.Errorf("HistoryRange: %w: order.Desc", kv.ErrNotSupported)
+	}
+	if limit >= 0 {
+		return nil, fmt.Errorf("HistoryRange: %w: limit", kv.ErrNotSupported)
+	}
+	if fromTs < 0 || toTs < 0 {
+		return nil, fmt.Errorf("HistoryRange: %w: unbounded range", kv.ErrNotSupported)
+	}
+	switch name {
+	case AccountsHistory:
+		it, err = tx.agg.AccountHistoryIterateChanged(fromTs, toTs, asc, limit, tx)
+	case StorageHistory:
+		it, err = tx.agg.StorageHistoryIterateChanged(fromTs, toTs, asc, limit, tx)
+	case CodeHistory:
+		it, err = tx.agg.CodeHistoryIterateChanged(fromTs, toTs, asc, limit, tx)
+	default:
+		return nil, fmt.Errorf("unexpected history name: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tx.autoClose(it)
+	return it, nil
+}
+
+// DomainRange - returns state as of `asOfTs` in [fromKey, toKey). Empty value means: key didn't exist at `asOfTs`.
+//
+//	AccountsDomain: keys are addresses
+//	StorageDomain:  fromKey/toKey are address+incarnation+location (PlainState format) and must belong to same account,
+//	                toKey=nil means end of account's storage. Returned keys are address+location.
+//
+// Only order.Asc is supported for now.
+func (tx *Tx) DomainRange(name kv.Domain, fromKey, toKey []byte, asOfTs uint64, asc order.By, limit int) (it iter.KV, err error) {
+	if asc == order.Desc {
+		return nil, fmt.Errorf("DomainRange: %w: order.Desc", kv.ErrNotSupported)
+	}
+	switch name {
+	case AccountsDomain:
+		histStateIt := tx.agg.AccountHistoricalStateRange(asOfTs, fromKey, toKey, limit, tx)
+		lastestStateIt, err := tx.RangeAscend(kv.PlainState, fromKey, toKey, -1) // don't apply limit, because need filter
+		if err != nil {
+			return nil, err
+		}
+		// TODO: instead of iterate over whole storage, need implement iterator which does cursor.Seek(nextAccount)
+		latestStateIt := iter.FilterKV(lastestStateIt, func(k, v []byte) bool {
+			return len(k) == length.Addr
+		})
+		it = iter.UnionKV(histStateIt, latestStateIt, limit)
+	case StorageDomain:
+		const prefixLen = length.Addr + length.Incarnation
+		if len(fromKey) < prefixLen {
+			return nil, fmt.Errorf("%s: expected fromKey len >= %d, got %d", name, prefixLen, len(fromKey))
+		}
+		if toKey != nil && (len(toKey) < prefixLen || string(toKey[:prefixLen]) != string(fromKey[:prefixLen])) {
+			return nil, fmt.Errorf("%s: fromKey and toKey must belong to same account", name)
+		}
+		histFrom := append(common.Copy(fromKey[:length.Addr]), fromKey[prefixLen:]...)
+		var histTo []byte
+		if toKey != nil {
+			histTo = append(common.Copy(toKey[:length.Addr]), toKey[prefixLen:]...)
+		} else {
+			histTo, _ = kv.NextSubtree(fromKey[:length.Addr])
+		}
+		histStateIt := tx.agg.StorageHistoricalStateRange(asOfTs, histFrom, histTo, limit, tx)
+
+		latestTo := toKey
+		if latestTo == nil {
+			latestTo, _ = kv.NextSubtree(fromKey[:prefixLen])
+		}
+		lastestStateIt, err := tx.RangeAscend(kv.PlainState, fromKey, latestTo, limit)
+		if err != nil {
+			return nil, err
+		}
+		latestStateIt := iter.TransformKV(lastestStateIt, func(k, v []byte) ([]byte, []byte, error) {
+			return append(common.Copy(k[:length.Addr]), k[prefixLen:]...), v, nil
+		})
+		it = iter.UnionKV(histStateIt, latestStateIt, limit)
+	default:
+		return nil, fmt.Errorf("DomainRange: %w: %s", kv.ErrNotSupported, name)
+	}
+	tx.autoClose(it)
+	return it, nil
+}
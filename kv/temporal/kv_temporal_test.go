@@ -0,0 +1,159 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package temporal
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/iter"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/order"
+	"github.com/ledgerwatch/erigon-lib/state"
+	"github.com/stretchr/testify/require"
+)
+
+func testDbAndAggregatorV3(t *testing.T) *DB {
+	t.Helper()
+	dir := t.TempDir()
+	db := memdb.NewTestDB(t)
+	agg, err := state.NewAggregatorV3(context.Background(), dir, dir, 16, db)
+	require.NoError(t, err)
+	t.Cleanup(agg.Close)
+	return New(db, agg)
+}
+
+func TestTemporalTx(t *testing.T) {
+	ctx, require := context.Background(), require.New(t)
+	db := testDbAndAggregatorV3(t)
+
+	addr := make([]byte, length.Addr)
+	addr[0] = 1
+	inc := make([]byte, length.Incarnation)
+	binary.BigEndian.PutUint64(inc, 1)
+	loc := make([]byte, length.Hash)
+	loc[0] = 2
+	accKey := append(append([]byte{}, addr...), inc...)
+	storageKey := append(append([]byte{}, accKey...), loc...)
+
+	tx, err := db.BeginRw(ctx)
+	require.NoError(err)
+	defer tx.Rollback()
+	agg := db.Agg()
+	agg.SetTx(tx)
+	agg.StartWrites()
+	// txNum=1: create account and storage
+	agg.SetTxNum(1)
+	require.NoError(agg.AddAccountPrev(addr, nil))
+	require.NoError(agg.AddStoragePrev(addr, loc, nil))
+	require.NoError(agg.AddLogAddr(addr))
+	// txNum=5: update account and storage
+	agg.SetTxNum(5)
+	require.NoError(agg.AddAccountPrev(addr, []byte{1}))
+	require.NoError(agg.AddStoragePrev(addr, loc, []byte{3}))
+	require.NoError(agg.AddLogAddr(addr))
+	require.NoError(tx.Put(kv.PlainState, addr, []byte{2}))
+	require.NoError(tx.Put(kv.PlainState, storageKey, []byte{4}))
+	require.NoError(agg.Flush(ctx, tx))
+	agg.FinishWrites()
+	require.NoError(tx.Commit())
+
+	roTx, err := db.BeginTemporalRo(ctx)
+	require.NoError(err)
+	defer roTx.Rollback()
+
+	t.Run("domain get", func(t *testing.T) {
+		v, ok, err := roTx.DomainGet(AccountsDomain, addr, nil, 0)
+		require.NoError(err)
+		require.False(ok)
+		require.Empty(v)
+		v, ok, err = roTx.DomainGet(AccountsDomain, addr, nil, 3)
+		require.NoError(err)
+		require.True(ok)
+		require.Equal([]byte{1}, v)
+		v, ok, err = roTx.DomainGet(AccountsDomain, addr, nil, 6)
+		require.NoError(err)
+		require.True(ok)
+		require.Equal([]byte{2}, v)
+
+		v, ok, err = roTx.DomainGet(StorageDomain, accKey, loc, 3)
+		require.NoError(err)
+		require.True(ok)
+		require.Equal([]byte{3}, v)
+		v, ok, err = roTx.DomainGet(StorageDomain, accKey, loc, 6)
+		require.NoError(err)
+		require.True(ok)
+		require.Equal([]byte{4}, v)
+
+		_, _, err = roTx.DomainGet(StorageDomain, addr, loc, 6)
+		require.Error(err)
+	})
+	t.Run("history get", func(t *testing.T) {
+		v, ok, err := roTx.HistoryGet(AccountsHistory, addr, 2)
+		require.NoError(err)
+		require.True(ok)
+		require.Equal([]byte{1}, v)
+		_, ok, err = roTx.HistoryGet(AccountsHistory, addr, 6)
+		require.NoError(err)
+		require.False(ok)
+	})
+	t.Run("index range", func(t *testing.T) {
+		it, err := roTx.IndexRange(LogAddrIdx, addr, 0, 10, order.Asc, -1)
+		require.NoError(err)
+		require.Equal([]uint64{1, 5}, iter.ToArrU64Must(it))
+		it, err = roTx.IndexRange(LogAddrIdx, addr, 10, 0, order.Desc, -1)
+		require.NoError(err)
+		require.Equal([]uint64{5, 1}, iter.ToArrU64Must(it))
+		it, err = roTx.IndexRange(AccountsHistoryIdx, addr, 2, 10, order.Asc, -1)
+		require.NoError(err)
+		require.Equal([]uint64{5}, iter.ToArrU64Must(it))
+
+		_, err = roTx.IndexRange("unknown", addr, 0, 10, order.Asc, -1)
+		require.Error(err)
+	})
+	t.Run("history range", func(t *testing.T) {
+		it, err := roTx.HistoryRange(AccountsHistory, 2, 10, order.Asc, -1)
+		require.NoError(err)
+		keys, vals, err := iter.ToKVArray(it)
+		require.NoError(err)
+		require.Equal([][]byte{addr}, keys)
+		require.Equal([][]byte{{1}}, vals)
+	})
+	t.Run("domain range", func(t *testing.T) {
+		it, err := roTx.DomainRange(AccountsDomain, nil, nil, 3, order.Asc, -1)
+		require.NoError(err)
+		keys, vals, err := iter.ToKVArray(it)
+		require.NoError(err)
+		require.Equal([][]byte{addr}, keys)
+		require.Equal([][]byte{{1}}, vals)
+
+		it, err = roTx.DomainRange(StorageDomain, accKey, nil, 6, order.Asc, -1)
+		require.NoError(err)
+		keys, vals, err = iter.ToKVArray(it)
+		require.NoError(err)
+		require.Equal([][]byte{append(append([]byte{}, addr...), loc...)}, keys)
+		require.Equal([][]byte{{4}}, vals)
+	})
+	t.Run("table stats", func(t *testing.T) {
+		st, err := kv.TableStats(roTx, kv.PlainState)
+		require.NoError(err)
+		require.Equal(uint64(2), st.Entries)
+	})
+}
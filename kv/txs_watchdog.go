@@ -0,0 +1,81 @@
+/*
+   Copyright 2023 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// TxInfo - open transaction. Forgotten read transaction stalls MDBX garbage collection and grows db file,
+// TxInfo helps to find who holds it.
+type TxInfo struct {
+	ID       uint64 // unique id of tracked tx. It's not ViewID: many read transactions may have same ViewID
+	ViewID   uint64
+	ReadOnly bool
+	Created  time.Time
+	Stack    string // where tx was created, empty for remote transactions
+	Peer     string // remote client which did open tx, empty for local transactions
+}
+
+func (i TxInfo) Age() time.Duration { return time.Since(i.Created) }
+
+// WatchLongTxs - periodically logs transactions older than `threshold` (every tx - only once)
+// and updates metrics `db_long_txs{db="<name>"}` and `db_oldest_tx_seconds{db="<name>"}`. Blocks until ctx is done.
+func WatchLongTxs(ctx context.Context, name string, threshold time.Duration, openTxs func() []TxInfo, logger log.Logger) {
+	longTxs := metrics.GetOrCreateCounter(fmt.Sprintf(`db_long_txs{db="%s"}`, name))
+	oldestTx := metrics.GetOrCreateCounter(fmt.Sprintf(`db_oldest_tx_seconds{db="%s"}`, name))
+
+	every := threshold / 2
+	if every < time.Second {
+		every = time.Second
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	reported := map[uint64]struct{}{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var amount, oldest uint64
+		stillOpen := make(map[uint64]struct{}, len(reported))
+		for _, tx := range openTxs() {
+			age := tx.Age()
+			if uint64(age.Seconds()) > oldest {
+				oldest = uint64(age.Seconds())
+			}
+			if age < threshold {
+				continue
+			}
+			amount++
+			stillOpen[tx.ID] = struct{}{}
+			if _, ok := reported[tx.ID]; ok {
+				continue
+			}
+			logger.Warn(fmt.Sprintf("[%s] long transaction", name), "id", tx.ID, "view_id", tx.ViewID, "ro", tx.ReadOnly, "age", age, "peer", tx.Peer, "stack", tx.Stack)
+		}
+		reported = stillOpen
+		longTxs.Set(amount)
+		oldestTx.Set(oldest)
+	}
+}
@@ -82,6 +82,17 @@ func (g *GolombRice) appendFixed(v uint64, log2golomb int) {
 	g.bitCount += log2golomb
 }
 
+// appendAll adds the encoding `g2` to the end of the current encoding
+func (g *GolombRice) appendAll(g2 *GolombRice) {
+	for i, n := 0, g2.bitCount; n > 0; i, n = i+1, n-64 {
+		if n < 64 {
+			g.appendFixed(g2.data[i], n)
+			break
+		}
+		g.appendFixed(g2.data[i], 64)
+	}
+}
+
 // Bits returns currrent number of bits in the compact encoding of the hash function representation
 func (g *GolombRice) Bits() int {
 	return g.bitCount
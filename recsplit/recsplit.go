@@ -18,8 +18,11 @@ package recsplit
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -61,25 +64,20 @@ func remix(z uint64) uint64 {
 // Recsplit: Minimal perfect hashing via recursive splitting. In 2020 Proceedings of the Symposium on Algorithm Engineering and Experiments (ALENEX),
 // pages 175−185. SIAM, 2020.
 type RecSplit struct {
-	hasher            murmur3.Hash128 // Salted hash function to use for splitting into initial buckets and mapping to 64-bit fingerprints
-	offsetCollector   *etl.Collector  // Collector that sorts by offsets
-	indexW            *bufio.Writer
-	indexF            *os.File
-	offsetEf          *eliasfano32.EliasFano // Elias Fano instance for encoding the offsets
-	bucketCollector   *etl.Collector         // Collector that sorts by buckets
-	indexFileName     string
-	indexFile         string
-	tmpDir            string
-	gr                GolombRice // Helper object to encode the tree of hash function salts using Golomb-Rice code.
-	bucketPosAcc      []uint64   // Accumulator for position of every bucket in the encoding of the hash function
-	startSeed         []uint64
-	count             []uint16
-	currentBucket     []uint64 // 64-bit fingerprints of keys in the current bucket accumulated before the recsplit is performed for that bucket
-	currentBucketOffs []uint64 // Index offsets for the current bucket
-	offsetBuffer      []uint64
-	buffer            []uint64
-	golombRice        []uint32
-	bucketSizeAcc     []uint64 // Bucket size accumulator
+	hasher          murmur3.Hash128 // Salted hash function to use for splitting into initial buckets and mapping to 64-bit fingerprints
+	offsetCollector *etl.Collector  // Collector that sorts by offsets
+	indexW          *bufio.Writer
+	indexF          *os.File
+	offsetEf        *eliasfano32.EliasFano // Elias Fano instance for encoding the offsets
+	bucketCollector *etl.Collector         // Collector that sorts by buckets
+	indexFileName   string
+	indexFile       string
+	tmpDir          string
+	gr              GolombRice // Helper object to encode the tree of hash function salts using Golomb-Rice code.
+	bucketPosAcc    []uint64   // Accumulator for position of every bucket in the encoding of the hash function
+	startSeed       []uint64
+	golombRice      []uint32
+	bucketSizeAcc   []uint64 // Bucket size accumulator
 	// Helper object to encode the sequence of cumulative number of keys in the buckets
 	// and the sequence of of cumulative bit offsets of buckets in the Golomb-Rice code.
 	ef                 eliasfano16.DoubleEliasFano
@@ -91,10 +89,10 @@ type RecSplit struct {
 	keyExpectedCount   uint64 // Number of keys in the hash table
 	keysAdded          uint64 // Number of keys actually added to the recSplit (to check the match with keyExpectedCount)
 	maxOffset          uint64 // Maximum value of index offset to later decide how many bytes to use for the encoding
-	currentBucketIdx   uint64 // Current bucket being accumulated
 	baseDataID         uint64 // Minimal app-specific ID of entries of this index - helps app understand what data stored in given shard - persistent field
 	bucketCount        uint64 // Number of buckets
 	etlBufLimit        datasize.ByteSize
+	workers            int    // Goroutines building hash functions of buckets, see Build
 	salt               uint32 // Murmur3 hash used for converting keys to 64-bit values and assigning to buckets
 	leafSize           uint16 // Leaf size for recursive split algorithm
 	secondaryAggrBound uint16 // The lower bound for secondary key aggregation (computed from leadSize)
@@ -122,6 +120,7 @@ type RecSplitArgs struct {
 	EtlBufLimit datasize.ByteSize
 	Salt        uint32 // Hash seed (salt) for the hash function used for allocating the initial buckets - need to be generated randomly
 	LeafSize    uint16
+	Workers     int // Goroutines building hash functions of buckets on Build, 0 means 1
 }
 
 // NewRecSplit creates a new RecSplit instance with given number of keys and given bucket size
@@ -161,8 +160,7 @@ func NewRecSplit(args RecSplitArgs) (*RecSplit, error) {
 		rs.offsetCollector = etl.NewCollector(RecSplitLogPrefix+" "+fname, rs.tmpDir, etl.NewSortableBuffer(rs.etlBufLimit))
 		rs.offsetCollector.LogLvl(log.LvlDebug)
 	}
-	rs.currentBucket = make([]uint64, 0, args.BucketSize)
-	rs.currentBucketOffs = make([]uint64, 0, args.BucketSize)
+	rs.workers = args.Workers
 	rs.maxOffset = 0
 	rs.bucketSizeAcc = make([]uint64, 1, bucketCount+1)
 	rs.bucketPosAcc = make([]uint64, 1, bucketCount+1)
@@ -177,7 +175,6 @@ func NewRecSplit(args RecSplitArgs) (*RecSplit, error) {
 		rs.secondaryAggrBound = rs.primaryAggrBound * uint16(math.Ceil(0.21*float64(rs.leafSize)+9./10.))
 	}
 	rs.startSeed = args.StartSeed
-	rs.count = make([]uint16, rs.secondaryAggrBound)
 	return rs, nil
 }
 
@@ -230,8 +227,6 @@ func (rs *RecSplit) ResetNextSalt() {
 		rs.offsetCollector.Close()
 		rs.offsetCollector = etl.NewCollector(RecSplitLogPrefix+" "+rs.indexFileName, rs.tmpDir, etl.NewSortableBuffer(rs.etlBufLimit))
 	}
-	rs.currentBucket = rs.currentBucket[:0]
-	rs.currentBucketOffs = rs.currentBucketOffs[:0]
 	rs.maxOffset = 0
 	rs.bucketSizeAcc = rs.bucketSizeAcc[:1] // First entry is always zero
 	rs.bucketPosAcc = rs.bucketPosAcc[:1]   // First entry is always zero
@@ -289,23 +284,25 @@ func computeGolombRice(m uint16, table []uint32, leafSize, primaryAggrBound, sec
 // golombParam returns the optimal Golomb parameter to use for encoding
 // salt for the part of the hash function separating m elements. It is based on
 // calculations with assumptions that we draw hash functions at random
-func (rs *RecSplit) golombParam(m uint16) int {
-	s := uint16(len(rs.golombRice))
+func (bs *bucketSplitter) golombParam(m uint16) int {
+	s := uint16(len(bs.golombRice))
 	for m >= s {
-		rs.golombRice = append(rs.golombRice, 0)
+		bs.golombRice = append(bs.golombRice, 0)
 		// For the case where bucket is larger than planned
 		if s == 0 {
-			rs.golombRice[0] = (bijMemo[0] << 27) | bijMemo[0]
-		} else if s <= rs.leafSize {
-			rs.golombRice[s] = (bijMemo[s] << 27) | (uint32(1) << 16) | bijMemo[s]
+			bs.golombRice[0] = (bijMemo[0] << 27) | bijMemo[0]
+		} else if s <= bs.rs.leafSize {
+			bs.golombRice[s] = (bijMemo[s] << 27) | (uint32(1) << 16) | bijMemo[s]
 		} else {
-			computeGolombRice(s, rs.golombRice, rs.leafSize, rs.primaryAggrBound, rs.secondaryAggrBound)
+			computeGolombRice(s, bs.golombRice, bs.rs.leafSize, bs.rs.primaryAggrBound, bs.rs.secondaryAggrBound)
 		}
 		s++
 	}
-	return int(rs.golombRice[m] >> 27)
+	return int(bs.golombRice[m] >> 27)
 }
 
+var _ etl.KeyIndexer = &RecSplit{} // see etl.Collector.LoadToIndex
+
 // Add key to the RecSplit. There can be many more keys than what fits in RAM, and RecSplit
 // spills data onto disk to accomodate that. The key gets copied by the collector, therefore
 // the slice underlying key is not getting accessed by RecSplit after this invocation.
@@ -347,66 +344,95 @@ func (rs *RecSplit) AddKey(key []byte, offset uint64) error {
 	return nil
 }
 
-func (rs *RecSplit) recsplitCurrentBucket() error {
-	// Extend rs.bucketSizeAcc to accomodate current bucket index + 1
-	for len(rs.bucketSizeAcc) <= int(rs.currentBucketIdx)+1 {
-		rs.bucketSizeAcc = append(rs.bucketSizeAcc, rs.bucketSizeAcc[len(rs.bucketSizeAcc)-1])
+// splitRangeBuckets - average amount of buckets in range of bucketCollector, see Build
+const splitRangeBuckets = 64
+
+// bucketSplitter - builds hash functions of consecutive buckets: scratch space of recsplit and its output. Build
+// runs splitter per range of buckets concurrently, outputs are appended to index in order of buckets (see appendSplit)
+type bucketSplitter struct {
+	rs           *RecSplit // parameters of hash function, not modified by splitter
+	gr           GolombRice
+	golombRice   []uint32
+	count        []uint16
+	buffer       []uint64
+	offsetBuffer []uint64
+	unary        []uint64
+	index        []byte // Index records of buckets in order of hash function
+	buckets      []splitBucket
+	numBuf       [8]byte
+}
+
+// splitBucket - bucket in output of bucketSplitter
+type splitBucket struct {
+	idx  uint64
+	size int
+	bits int // Size of golomb-rice code of splitter after this bucket
+}
+
+func newBucketSplitter(rs *RecSplit) *bucketSplitter {
+	return &bucketSplitter{rs: rs, count: make([]uint16, rs.secondaryAggrBound)}
+}
+
+// splitRange - builds hash functions of all buckets of range loaded by etl.Collector.LoadRanges
+func (bs *bucketSplitter) splitRange(keys, vals [][]byte) error {
+	// k is the BigEndian encoding of the bucket number followed by 64-bit fingerprint of the key, v is the offset
+	bucket, offsets := make([]uint64, 0, bs.rs.bucketSize), make([]uint64, 0, bs.rs.bucketSize)
+	for i, k := range keys {
+		bucket = append(bucket, binary.BigEndian.Uint64(k[8:]))
+		offsets = append(offsets, binary.BigEndian.Uint64(vals[i]))
+		if i+1 < len(keys) && bytes.Equal(k[:8], keys[i+1][:8]) {
+			continue
+		}
+		if err := bs.split(binary.BigEndian.Uint64(k), bucket, offsets); err != nil {
+			return err
+		}
+		bucket, offsets = bucket[:0], offsets[:0]
 	}
-	rs.bucketSizeAcc[int(rs.currentBucketIdx)+1] += uint64(len(rs.currentBucket))
+	return nil
+}
+
+// split - builds hash function of bucket `idx`: 64-bit fingerprints of keys in the bucket and their index offsets
+func (bs *bucketSplitter) split(idx uint64, bucket, offsets []uint64) error {
 	// Sets of size 0 and 1 are not further processed, just write them to index
-	if len(rs.currentBucket) > 1 {
-		for i, key := range rs.currentBucket[1:] {
-			if key == rs.currentBucket[i] {
-				rs.collision = true
+	if len(bucket) > 1 {
+		for i, key := range bucket[1:] {
+			if key == bucket[i] {
 				return fmt.Errorf("%w: %x", ErrCollision, key)
 			}
 		}
-		bitPos := rs.gr.bitCount
-		if rs.buffer == nil {
-			rs.buffer = make([]uint64, len(rs.currentBucket))
-			rs.offsetBuffer = make([]uint64, len(rs.currentBucketOffs))
-		} else {
-			for len(rs.buffer) < len(rs.currentBucket) {
-				rs.buffer = append(rs.buffer, 0)
-				rs.offsetBuffer = append(rs.offsetBuffer, 0)
-			}
-		}
-		unary, err := rs.recsplit(0 /* level */, rs.currentBucket, rs.currentBucketOffs, nil /* unary */)
-		if err != nil {
-			return err
+		bitPos := bs.gr.bitCount
+		for len(bs.buffer) < len(bucket) {
+			bs.buffer = append(bs.buffer, 0)
+			bs.offsetBuffer = append(bs.offsetBuffer, 0)
 		}
-		rs.gr.appendUnaryAll(unary)
-		if rs.trace {
-			fmt.Printf("recsplitBucket(%d, %d, bitsize = %d)\n", rs.currentBucketIdx, len(rs.currentBucket), rs.gr.bitCount-bitPos)
+		bs.unary = bs.recsplit(0 /* level */, bucket, offsets, bs.unary[:0])
+		bs.gr.appendUnaryAll(bs.unary)
+		if bs.rs.trace {
+			fmt.Printf("recsplitBucket(%d, %d, bitsize = %d)\n", idx, len(bucket), bs.gr.bitCount-bitPos)
 		}
 	} else {
-		for _, offset := range rs.currentBucketOffs {
-			binary.BigEndian.PutUint64(rs.numBuf[:], offset)
-			if _, err := rs.indexW.Write(rs.numBuf[8-rs.bytesPerRec:]); err != nil {
-				return err
-			}
+		for _, offset := range offsets {
+			bs.appendOffset(offset)
 		}
 	}
-	// Extend rs.bucketPosAcc to accomodate current bucket index + 1
-	for len(rs.bucketPosAcc) <= int(rs.currentBucketIdx)+1 {
-		rs.bucketPosAcc = append(rs.bucketPosAcc, rs.bucketPosAcc[len(rs.bucketPosAcc)-1])
-	}
-	rs.bucketPosAcc[int(rs.currentBucketIdx)+1] = uint64(rs.gr.Bits())
-	// clear for the next buckey
-	rs.currentBucket = rs.currentBucket[:0]
-	rs.currentBucketOffs = rs.currentBucketOffs[:0]
+	bs.buckets = append(bs.buckets, splitBucket{idx: idx, size: len(bucket), bits: bs.gr.Bits()})
 	return nil
 }
 
+func (bs *bucketSplitter) appendOffset(offset uint64) {
+	binary.BigEndian.PutUint64(bs.numBuf[:], offset)
+	bs.index = append(bs.index, bs.numBuf[8-bs.rs.bytesPerRec:]...)
+}
+
 // recsplit applies recSplit algorithm to the given bucket
-func (rs *RecSplit) recsplit(level int, bucket []uint64, offsets []uint64, unary []uint64) ([]uint64, error) {
-	if rs.trace {
+func (bs *bucketSplitter) recsplit(level int, bucket []uint64, offsets []uint64, unary []uint64) []uint64 {
+	if bs.rs.trace {
 		fmt.Printf("recsplit(%d, %d, %x)\n", level, len(bucket), bucket)
 	}
 	// Pick initial salt for this level of recursive split
-	salt := rs.startSeed[level]
+	salt := bs.rs.startSeed[level]
 	m := uint16(len(bucket))
-	if m <= rs.leafSize {
+	if m <= bs.rs.leafSize {
 		// No need to build aggregation levels - just find find bijection
 		var mask uint32
 		for {
@@ -427,24 +453,21 @@ func (rs *RecSplit) recsplit(level int, bucket []uint64, offsets []uint64, unary
 		}
 		for i := uint16(0); i < m; i++ {
 			j := remap16(remix(bucket[i]+salt), m)
-			rs.offsetBuffer[j] = offsets[i]
+			bs.offsetBuffer[j] = offsets[i]
 		}
-		for _, offset := range rs.offsetBuffer[:m] {
-			binary.BigEndian.PutUint64(rs.numBuf[:], offset)
-			if _, err := rs.indexW.Write(rs.numBuf[8-rs.bytesPerRec:]); err != nil {
-				return nil, err
-			}
+		for _, offset := range bs.offsetBuffer[:m] {
+			bs.appendOffset(offset)
 		}
-		salt -= rs.startSeed[level]
-		log2golomb := rs.golombParam(m)
-		if rs.trace {
-			fmt.Printf("encode bij %d with log2golomn %d at p = %d\n", salt, log2golomb, rs.gr.bitCount)
+		salt -= bs.rs.startSeed[level]
+		log2golomb := bs.golombParam(m)
+		if bs.rs.trace {
+			fmt.Printf("encode bij %d with log2golomn %d at p = %d\n", salt, log2golomb, bs.gr.bitCount)
 		}
-		rs.gr.appendFixed(salt, log2golomb)
+		bs.gr.appendFixed(salt, log2golomb)
 		unary = append(unary, salt>>log2golomb)
 	} else {
-		fanout, unit := splitParams(m, rs.leafSize, rs.primaryAggrBound, rs.secondaryAggrBound)
-		count := rs.count
+		fanout, unit := splitParams(m, bs.rs.leafSize, bs.rs.primaryAggrBound, bs.rs.secondaryAggrBound)
+		count := bs.count
 		for {
 			for i := uint16(0); i < fanout-1; i++ {
 				count[i] = 0
@@ -467,54 +490,53 @@ func (rs *RecSplit) recsplit(level int, bucket []uint64, offsets []uint64, unary
 		}
 		for i := uint16(0); i < m; i++ {
 			j := remap16(remix(bucket[i]+salt), m) / unit
-			rs.buffer[count[j]] = bucket[i]
-			rs.offsetBuffer[count[j]] = offsets[i]
+			bs.buffer[count[j]] = bucket[i]
+			bs.offsetBuffer[count[j]] = offsets[i]
 			count[j]++
 		}
-		copy(bucket, rs.buffer)
-		copy(offsets, rs.offsetBuffer)
-		salt -= rs.startSeed[level]
-		log2golomb := rs.golombParam(m)
-		if rs.trace {
-			fmt.Printf("encode fanout %d: %d with log2golomn %d at p = %d\n", fanout, salt, log2golomb, rs.gr.bitCount)
+		copy(bucket, bs.buffer)
+		copy(offsets, bs.offsetBuffer)
+		salt -= bs.rs.startSeed[level]
+		log2golomb := bs.golombParam(m)
+		if bs.rs.trace {
+			fmt.Printf("encode fanout %d: %d with log2golomn %d at p = %d\n", fanout, salt, log2golomb, bs.gr.bitCount)
 		}
-		rs.gr.appendFixed(salt, log2golomb)
+		bs.gr.appendFixed(salt, log2golomb)
 		unary = append(unary, salt>>log2golomb)
-		var err error
 		var i uint16
 		for i = 0; i < m-unit; i += unit {
-			if unary, err = rs.recsplit(level+1, bucket[i:i+unit], offsets[i:i+unit], unary); err != nil {
-				return nil, err
-			}
+			unary = bs.recsplit(level+1, bucket[i:i+unit], offsets[i:i+unit], unary)
 		}
 		if m-i > 1 {
-			if unary, err = rs.recsplit(level+1, bucket[i:], offsets[i:], unary); err != nil {
-				return nil, err
-			}
+			unary = bs.recsplit(level+1, bucket[i:], offsets[i:], unary)
 		} else if m-i == 1 {
-			binary.BigEndian.PutUint64(rs.numBuf[:], offsets[i])
-			if _, err := rs.indexW.Write(rs.numBuf[8-rs.bytesPerRec:]); err != nil {
-				return nil, err
-			}
+			bs.appendOffset(offsets[i])
 		}
 	}
-	return unary, nil
+	return unary
 }
 
-// loadFuncBucket is required to satisfy the type etl.LoadFunc type, to use with collector.Load
-func (rs *RecSplit) loadFuncBucket(k, v []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
-	// k is the BigEndian encoding of the bucket number, and the v is the key that is assigned into that bucket
-	bucketIdx := binary.BigEndian.Uint64(k)
-	if rs.currentBucketIdx != bucketIdx {
-		if rs.currentBucketIdx != math.MaxUint64 {
-			if err := rs.recsplitCurrentBucket(); err != nil {
-				return err
-			}
+// appendSplit - appends hash functions and index records built by splitter, must be called in order of buckets
+func (rs *RecSplit) appendSplit(bs *bucketSplitter) error {
+	if _, err := rs.indexW.Write(bs.index); err != nil {
+		return err
+	}
+	bitPos := rs.gr.Bits()
+	rs.gr.appendAll(&bs.gr)
+	for _, b := range bs.buckets {
+		// Extend rs.bucketSizeAcc and rs.bucketPosAcc to accomodate bucket index + 1
+		for len(rs.bucketSizeAcc) <= int(b.idx)+1 {
+			rs.bucketSizeAcc = append(rs.bucketSizeAcc, rs.bucketSizeAcc[len(rs.bucketSizeAcc)-1])
+		}
+		rs.bucketSizeAcc[int(b.idx)+1] += uint64(b.size)
+		for len(rs.bucketPosAcc) <= int(b.idx)+1 {
+			rs.bucketPosAcc = append(rs.bucketPosAcc, rs.bucketPosAcc[len(rs.bucketPosAcc)-1])
 		}
-		rs.currentBucketIdx = bucketIdx
+		rs.bucketPosAcc[int(b.idx)+1] = uint64(bitPos + b.bits)
+	}
+	if len(bs.golombRice) > len(rs.golombRice) {
+		rs.golombRice = bs.golombRice // Table doesn't depend on order of buckets, only its size is written
 	}
-	rs.currentBucket = append(rs.currentBucket, binary.BigEndian.Uint64(k[8:]))
-	rs.currentBucketOffs = append(rs.currentBucketOffs, binary.BigEndian.Uint64(v))
 	return nil
 }
 
@@ -560,18 +582,22 @@ func (rs *RecSplit) Build() error {
 		return fmt.Errorf("write bytes per record: %w", err)
 	}
 
-	rs.currentBucketIdx = math.MaxUint64 // To make sure 0 bucket is detected
 	defer rs.bucketCollector.Close()
 	if rs.lvl < log.LvlTrace {
 		log.Log(rs.lvl, "[index] calculating", "file", rs.indexFileName)
 	}
-	if err := rs.bucketCollector.Load(nil, "", rs.loadFuncBucket, etl.TransformArgs{}); err != nil {
-		return err
-	}
-	if len(rs.currentBucket) > 0 {
-		if err := rs.recsplitCurrentBucket(); err != nil {
-			return err
+	// Ranges of buckets are split concurrently, results are written in order of buckets
+	if err := rs.bucketCollector.LoadRanges(context.Background(), rs.workers, splitRangeBuckets*rs.bucketSize, 8, func(_ int, keys, vals [][]byte) (func() error, error) {
+		bs := newBucketSplitter(rs)
+		if err := bs.splitRange(keys, vals); err != nil {
+			return nil, err
 		}
+		return func() error { return rs.appendSplit(bs) }, nil
+	}); err != nil {
+		if errors.Is(err, ErrCollision) {
+			rs.collision = true
+		}
+		return err
 	}
 
 	if assert.Enable {
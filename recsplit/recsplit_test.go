@@ -17,7 +17,9 @@
 package recsplit
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 )
@@ -165,3 +167,49 @@ func TestTwoLayerIndex(t *testing.T) {
 		}
 	}
 }
+
+func TestParallelBuild(t *testing.T) {
+	tmpDir := t.TempDir()
+	build := func(workers int) []byte {
+		indexFile := filepath.Join(tmpDir, fmt.Sprintf("index-%d", workers))
+		rs, err := NewRecSplit(RecSplitArgs{
+			KeyCount:    10_000,
+			BucketSize:  100,
+			Salt:        1,
+			TmpDir:      tmpDir,
+			IndexFile:   indexFile,
+			LeafSize:    8,
+			EtlBufLimit: 16 * 1024, // many files
+			Workers:     workers,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rs.Close()
+		for i := 0; i < 10_000; i++ {
+			if err = rs.AddKey([]byte(fmt.Sprintf("key %d", i)), uint64(i*17)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := rs.Build(); err != nil {
+			t.Fatal(err)
+		}
+		idx := MustOpen(indexFile)
+		defer idx.Close()
+		reader := NewIndexReader(idx)
+		for i := 0; i < 10_000; i++ {
+			offset := reader.Lookup([]byte(fmt.Sprintf("key %d", i)))
+			if offset != uint64(i*17) {
+				t.Fatalf("expected offset: %d, looked up: %d", i*17, offset)
+			}
+		}
+		data, err := os.ReadFile(indexFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	if !bytes.Equal(build(1), build(4)) {
+		t.Errorf("index built by 4 workers differs from index built by 1 worker")
+	}
+}
@@ -637,6 +637,7 @@ type BtIndexWriter struct {
 	keyCount        uint64
 	etlBufLimit     datasize.ByteSize
 	bytesPerRec     int
+	workers         int
 }
 
 type BtIndexWriterArgs struct {
@@ -644,10 +645,14 @@ type BtIndexWriterArgs struct {
 	TmpDir      string
 	KeyCount    int
 	EtlBufLimit datasize.ByteSize
+	Workers     int // Goroutines encoding records on Build, 0 means 1
 }
 
 const BtreeLogPrefix = "btree"
 
+// btreeLoadRangeSize - keys in range loaded by one worker of BtIndexWriter.Build
+const btreeLoadRangeSize = 64 * 1024
+
 // NewBtIndexWriter creates a new BtIndexWriter instance with given number of keys
 // Typical bucket size is 100 - 2048, larger bucket sizes result in smaller representations of hash functions, at a cost of slower access
 // salt parameters is used to randomise the hash function construction, to ensure that different Erigon instances (nodes)
@@ -660,6 +665,7 @@ func NewBtIndexWriter(args BtIndexWriterArgs) (*BtIndexWriter, error) {
 	_, fname := filepath.Split(btw.indexFile)
 	btw.indexFileName = fname
 	btw.etlBufLimit = args.EtlBufLimit
+	btw.workers = args.Workers
 	if btw.etlBufLimit == 0 {
 		btw.etlBufLimit = etl.BufferOptimalSize
 	}
@@ -671,25 +677,18 @@ func NewBtIndexWriter(args BtIndexWriterArgs) (*BtIndexWriter, error) {
 	return btw, nil
 }
 
-// loadFuncBucket is required to satisfy the type etl.LoadFunc type, to use with collector.Load
-func (btw *BtIndexWriter) loadFuncBucket(k, v []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
-	// k is the BigEndian encoding of the bucket number, and the v is the key that is assigned into that bucket
-	//if uint64(len(btw.vals)) >= btw.batchSizeLimit {
-	//	if err := btw.drainBatch(); err != nil {
-	//		return err
-	//	}
-	//}
-
-	// if _, err := btw.indexW.Write(k); err != nil {
-	// 	return err
-	// }
-	if _, err := btw.indexW.Write(v[8-btw.bytesPerRec:]); err != nil {
-		return err
+// loadRange is required to satisfy the type etl.LoadRangeFunc type, to use with collector.LoadRanges: records of
+// range are encoded concurrently with other ranges and written to index in order of keys
+func (btw *BtIndexWriter) loadRange(_ int, _, vals [][]byte) (func() error, error) {
+	// v is the BigEndian encoding of the offset of the key
+	records := make([]byte, 0, len(vals)*btw.bytesPerRec)
+	for _, v := range vals {
+		records = append(records, v[8-btw.bytesPerRec:]...)
 	}
-
-	//btw.keys = append(btw.keys, binary.BigEndian.Uint64(k), binary.BigEndian.Uint64(k[8:]))
-	//btw.vals = append(btw.vals, binary.BigEndian.Uint64(v))
-	return nil
+	return func() error {
+		_, err := btw.indexW.Write(records)
+		return err
+	}, nil
 }
 
 // Build has to be called after all the keys have been added, and it initiates the process
@@ -725,7 +724,7 @@ func (btw *BtIndexWriter) Build() error {
 
 	defer btw.bucketCollector.Close()
 	log.Log(btw.lvl, "[index] calculating", "file", btw.indexFileName)
-	if err := btw.bucketCollector.Load(nil, "", btw.loadFuncBucket, etl.TransformArgs{}); err != nil {
+	if err := btw.bucketCollector.LoadRanges(context.Background(), btw.workers, btreeLoadRangeSize, 0, btw.loadRange); err != nil {
 		return err
 	}
 
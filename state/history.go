@@ -1589,7 +1589,8 @@ func (hc *HistoryContext) WalkAsOf(startTxNum uint64, from, to []byte, roTx kv.T
 		}
 		binary.BigEndian.PutUint64(dbi.startTxKey[:], startTxNum)
 		if err := dbi.advanceInDb(); err != nil {
-			panic(err)
+			dbi.Close()
+			dbi.err = err // returned by Next
 		}
 		dbit = dbi
 	} else {
@@ -1605,11 +1606,12 @@ func (hc *HistoryContext) WalkAsOf(startTxNum uint64, from, to []byte, roTx kv.T
 		}
 		binary.BigEndian.PutUint64(dbi.startTxKey[:], startTxNum)
 		if err := dbi.advanceInDb(); err != nil {
-			panic(err)
+			dbi.Close()
+			dbi.err = err // returned by Next
 		}
 		dbit = dbi
 	}
-	return iter.UnionKV(hi, dbit)
+	return iter.UnionKV(hi, dbit, -1)
 }
 
 // StateAsOfIter - returns state range at given time in history
@@ -1726,12 +1728,15 @@ type StateAsOfIterDb struct {
 	err                    error
 }
 
+// Close - idempotent, also called by advance when stream is exhausted: releases cursors even if user forgot Close
 func (hi *StateAsOfIterDb) Close() {
 	if hi.valsC != nil {
 		hi.valsC.Close()
+		hi.valsC = nil
 	}
 	if hi.txNum2kCursor != nil {
 		hi.txNum2kCursor.Close()
+		hi.txNum2kCursor = nil
 	}
 }
 
@@ -1751,6 +1756,7 @@ func (hi *StateAsOfIterDb) advanceInDb() error {
 		}
 		if firstKey == nil {
 			hi.nextKey = nil
+			hi.Close()
 			return nil
 		}
 		seek = append(common.Copy(firstKey[:len(firstKey)-8]), hi.startTxKey[:]...)
@@ -1758,6 +1764,7 @@ func (hi *StateAsOfIterDb) advanceInDb() error {
 		next, ok := kv.NextSubtree(hi.nextKey)
 		if !ok {
 			hi.nextKey = nil
+			hi.Close()
 			return nil
 		}
 
@@ -1779,6 +1786,7 @@ func (hi *StateAsOfIterDb) advanceInDb() error {
 		return nil
 	}
 	hi.nextKey = nil
+	hi.Close()
 	return nil
 }
 
@@ -1826,12 +1834,15 @@ type StateAsOfIterDbDup struct {
 	err                    error
 }
 
+// Close - idempotent, see StateAsOfIterDb.Close
 func (hi *StateAsOfIterDbDup) Close() {
 	if hi.valsC != nil {
 		hi.valsC.Close()
+		hi.valsC = nil
 	}
 	if hi.txNum2kCursor != nil {
 		hi.txNum2kCursor.Close()
+		hi.txNum2kCursor = nil
 	}
 }
 
@@ -1850,6 +1861,7 @@ func (hi *StateAsOfIterDbDup) advanceInDb() error {
 		next, ok := kv.NextSubtree(hi.nextKey)
 		if !ok {
 			hi.nextKey = nil
+			hi.Close()
 			return nil
 		}
 		seek = next
@@ -1873,6 +1885,7 @@ func (hi *StateAsOfIterDbDup) advanceInDb() error {
 		return nil
 	}
 	hi.nextKey = nil
+	hi.Close()
 	return nil
 }
 
@@ -1950,6 +1963,7 @@ func (hc *HistoryContext) iterateChangedRecent(fromTxNum, toTxNum int, asc order
 		}
 		binary.BigEndian.PutUint64(dbi.startTxKey[:], startTxNum)
 		if err := dbi.advance(); err != nil {
+			dbi.Close()
 			return nil, err
 		}
 		return dbi, nil
@@ -1966,6 +1980,7 @@ func (hc *HistoryContext) iterateChangedRecent(fromTxNum, toTxNum int, asc order
 	}
 	binary.BigEndian.PutUint64(dbi.startTxKey[:], startTxNum)
 	if err := dbi.advance(); err != nil {
+		dbi.Close()
 		return nil, err
 	}
 	return dbi, nil
@@ -1990,10 +2005,11 @@ func (hc *HistoryContext) IterateChanged(fromTxNum, toTxNum int, asc order.By, l
 	}
 	itOnDB, err := hc.iterateChangedRecent(fromTxNum, toTxNum, asc, limit, roTx)
 	if err != nil {
+		iter.Close(itOnFiles)
 		return nil, err
 	}
 
-	return iter.UnionKV(itOnFiles, itOnDB), nil
+	return iter.UnionKV(itOnFiles, itOnDB, -1), nil
 }
 
 type HistoryChangesIterF struct {
@@ -2112,12 +2128,15 @@ type HistoryChangesIterDB struct {
 	err                    error
 }
 
+// Close - idempotent, see StateAsOfIterDb.Close
 func (hi *HistoryChangesIterDB) Close() {
 	if hi.idxCursor != nil {
 		hi.idxCursor.Close()
+		hi.idxCursor = nil
 	}
 	if hi.txNum2kCursor != nil {
 		hi.txNum2kCursor.Close()
+		hi.txNum2kCursor = nil
 	}
 }
 
@@ -2169,6 +2188,7 @@ func (hi *HistoryChangesIterDB) advance() (err error) {
 		return err
 	}
 	hi.nextKey = nil
+	hi.Close()
 	return nil
 }
 
@@ -2215,12 +2235,15 @@ type HistoryChangesIterDBDup struct {
 	err              error
 }
 
+// Close - idempotent, see StateAsOfIterDb.Close
 func (hi *HistoryChangesIterDBDup) Close() {
 	if hi.valsCursor != nil {
 		hi.valsCursor.Close()
+		hi.valsCursor = nil
 	}
 	if hi.txNum2kCursor != nil {
 		hi.txNum2kCursor.Close()
+		hi.txNum2kCursor = nil
 	}
 }
 
@@ -2262,6 +2285,7 @@ func (hi *HistoryChangesIterDBDup) advance() (err error) {
 		return nil
 	}
 	hi.nextKey = nil
+	hi.Close()
 	return nil
 }
 
@@ -2330,6 +2354,7 @@ func (hc *HistoryContext) IterateRecentlyChangedUnordered(startTxNum, endTxNum u
 	}
 	binary.BigEndian.PutUint64(hi.startTxKey[:], startTxNum)
 	if err := hi.advanceInDb(); err != nil {
+		hi.Close()
 		return nil, err
 	}
 	return &hi, nil
@@ -2353,12 +2378,15 @@ type HistoryDBIterator struct {
 	searchBuf []byte
 }
 
+// Close - idempotent, see StateAsOfIterDb.Close
 func (hi *HistoryDBIterator) Close() {
 	if hi.txNum2kCursor != nil {
 		hi.txNum2kCursor.Close()
+		hi.txNum2kCursor = nil
 	}
 	if hi.valsCDup != nil {
 		hi.valsCDup.Close()
+		hi.valsCDup = nil
 	}
 }
 
@@ -2392,6 +2420,7 @@ func (hi *HistoryDBIterator) advanceInDb() (err error) {
 	}
 	if k == nil {
 		hi.nextKey = nil
+		hi.Close()
 		return nil
 	}
 	hi.nextKey = v
@@ -711,6 +711,7 @@ func (it *FrozenInvertedIdxIter) Close() {
 	for _, item := range it.stack {
 		item.reader.Close()
 	}
+	it.stack = nil
 }
 
 func (it *FrozenInvertedIdxIter) advance() {
@@ -831,6 +832,7 @@ func (it *RecentInvertedIdxIter) Close() {
 		it.cursor.Close()
 	}
 	bitmapdb.ReturnToPool64(it.bm)
+	it.bm = nil // Close may be called many times: by user and by tx.Rollback
 }
 
 func (it *RecentInvertedIdxIter) advanceInDb() {
@@ -148,6 +148,7 @@ func (li *LocalityIndex) openFiles() (err error) {
 	if li.bm == nil {
 		dataPath := filepath.Join(li.dir, fmt.Sprintf("%s.%d-%d.l", li.filenameBase, fromStep, toStep))
 		if dir.FileExist(dataPath) {
+			// width of bitmap is read from header: it's amount of files at build time (see buildFiles), the argument is for old files only
 			li.bm, err = bitmapdb.OpenFixedSizeBitmaps(dataPath, int((toStep-fromStep)/StepsInBiggestFile))
 			if err != nil {
 				return err
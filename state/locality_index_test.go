@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/binary"
 	"math"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -18,6 +19,27 @@ func TestLocality(t *testing.T) {
 	defer li.Close()
 	err := li.BuildMissedIndices(ctx, ii)
 	require.NoError(err)
+	t.Run("reopen", func(t *testing.T) {
+		entries, err := os.ReadDir(path)
+		require.NoError(err)
+		var fNames []string
+		for _, e := range entries {
+			fNames = append(fNames, e.Name())
+		}
+		reopened, _ := NewLocalityIndex(path, path, 4, "inv")
+		defer reopened.Close()
+		require.NoError(reopened.OpenList(fNames))
+		require.NotNil(reopened.bm)
+		require.Equal(li.bm.BitsPerBitmap(), reopened.bm.BitsPerBitmap())
+		require.Equal(li.bm.Amount(), reopened.bm.Amount())
+		for i := uint64(0); i < li.bm.Amount(); i++ {
+			expect, err := li.bm.At(i)
+			require.NoError(err)
+			got, err := reopened.bm.At(i)
+			require.NoError(err)
+			require.Equal(expect, got)
+		}
+	})
 	t.Run("locality iterator", func(t *testing.T) {
 		ic := ii.MakeContext()
 		defer ic.Close()